@@ -12,19 +12,22 @@ import (
 	"strings"
 	"syscall"
 
+	"github.com/ethereum/go-ethereum/common"
 	homedir "github.com/mitchellh/go-homedir"
 	"github.com/pkg/errors"
 	zerologger "github.com/rs/zerolog/log"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
+	fileauditlog "github.com/wealdtech/edcd/services/auditlog/file"
 	standardclaimdata "github.com/wealdtech/edcd/services/claimdata/standard"
 	jsonrpcdaemon "github.com/wealdtech/edcd/services/daemon/jsonrpc"
-	mockens "github.com/wealdtech/edcd/services/ens/mock"
 	standardens "github.com/wealdtech/edcd/services/ens/standard"
 	"github.com/wealdtech/edcd/services/metrics"
 	nullmetrics "github.com/wealdtech/edcd/services/metrics/null"
 	prometheusmetrics "github.com/wealdtech/edcd/services/metrics/prometheus"
+	standardsigningguard "github.com/wealdtech/edcd/services/signingguard/standard"
 	"github.com/wealdtech/edcd/util"
+	"github.com/wealdtech/edcd/util/eip712"
 )
 
 // ReleaseVersion is the release version for the code.
@@ -74,10 +77,20 @@ func main2() int {
 	setRelease(ctx, ReleaseVersion)
 	setReady(ctx, false)
 
-	if err := startServices(ctx, monitor); err != nil {
+	log.Trace().Msg("Starting ENS service")
+	ens, err := startENS(ctx, monitor)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to start ENS service")
+		return 1
+	}
+	defer ens.Close()
+
+	claimData, err := startServices(ctx, monitor, ens)
+	if err != nil {
 		log.Error().Err(err).Msg("Failed to initialise services")
 		return 1
 	}
+	defer claimData.Close()
 	setReady(ctx, true)
 
 	log.Info().Msg("All services operational")
@@ -101,6 +114,7 @@ func main2() int {
 func fetchConfig() error {
 	pflag.String("base-dir", "", "base directory for configuration files")
 	pflag.Bool("version", false, "show version and exit")
+	pflag.Bool("verify-audit-log", false, "verify the audit log and exit")
 	pflag.String("log-level", "info", "minimum level of messsages to log")
 	pflag.String("log-file", "", "redirect log output to a file")
 	pflag.String("profile-address", "", "Address on which to run Go profile server")
@@ -173,29 +187,137 @@ func startMonitor(ctx context.Context) (metrics.Service, error) {
 	return monitor, nil
 }
 
-func startServices(ctx context.Context, monitor metrics.Service) error {
-	log.Trace().Msg("Starting ENS service")
-	ens, err := standardens.New(ctx,
+// startENS starts the ENS service, which holds connections to Ethereum 1
+// nodes until it is closed.
+func startENS(ctx context.Context, monitor metrics.Service) (*standardens.Service, error) {
+	ensParams := []standardens.Parameter{
 		standardens.WithLogLevel(util.LogLevel("ens")),
 		standardens.WithMonitor(monitor),
 		standardens.WithTimeout(viper.GetDuration("claimdata.timeout")),
 		standardens.WithConnectionURL(viper.GetString("eth1client.address")),
+	}
+	if viper.IsSet("eth1client.addresses") {
+		ensParams = append(ensParams, standardens.WithConnectionURLs(viper.GetStringSlice("eth1client.addresses")))
+	}
+	if viper.IsSet("eth1client.quorum") {
+		ensParams = append(ensParams, standardens.WithQuorum(viper.GetInt("eth1client.quorum")))
+	}
+	if viper.IsSet("eth1client.max-lag") {
+		ensParams = append(ensParams, standardens.WithMaxLag(viper.GetUint64("eth1client.max-lag")))
+	}
+	if viper.IsSet("ens.network") {
+		ensParams = append(ensParams, standardens.WithNetwork(viper.GetString("ens.network")))
+	}
+	if viper.IsSet("ens.chain-id") {
+		ensParams = append(ensParams, standardens.WithChainID(viper.GetUint64("ens.chain-id")))
+	}
+	if viper.IsSet("ens.registry-address") {
+		registryAddress := viper.GetString("ens.registry-address")
+		if !common.IsHexAddress(registryAddress) {
+			return nil, fmt.Errorf("invalid ENS registry address %s", registryAddress)
+		}
+		ensParams = append(ensParams, standardens.WithRegistryAddress(common.HexToAddress(registryAddress)))
+	}
+	if viper.IsSet("eth1client.health-check-interval") {
+		ensParams = append(ensParams, standardens.WithHealthCheckInterval(viper.GetDuration("eth1client.health-check-interval")))
+	}
+	if viper.IsSet("ens.registrar-verification-interval") {
+		ensParams = append(ensParams, standardens.WithRegistrarVerificationInterval(viper.GetDuration("ens.registrar-verification-interval")))
+	}
+	// Domain controls can pin the address or code of their registrar.
+	registrarPins := make(map[string]*standardens.RegistrarPin)
+	for domain, domainControl := range viper.GetStringMap("claimdata.domain-controls") {
+		if control, isMap := domainControl.(map[string]interface{}); isMap {
+			registrarPin, err := standardens.ParseRegistrarPin(control)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid registrar pin for %s", domain)
+			}
+			if registrarPin != nil {
+				registrarPins[domain] = registrarPin
+			}
+		}
+	}
+	ensParams = append(ensParams, standardens.WithRegistrarPins(registrarPins))
+	ens, err := standardens.New(ctx, ensParams...)
+	if err != nil {
+		return nil, err
+	}
+
+	return ens, nil
+}
+
+func startServices(ctx context.Context, monitor metrics.Service, ens *standardens.Service) (*standardclaimdata.Service, error) {
+	log.Trace().Msg("Starting signing guard service")
+	domainControls := viper.GetStringMap("claimdata.domain-controls")
+	domains := make([]string, 0, len(domainControls))
+	eip712Domains := make(map[string]*eip712.Domain)
+	for domain, domainControl := range domainControls {
+		domains = append(domains, domain)
+		// Claims signed as EIP-712 typed data are checked by the guard against their domain.
+		if control, isMap := domainControl.(map[string]interface{}); isMap && control["signing-mode"] == "eip712" {
+			eip712Domain, err := eip712.ParseDomain(control["eip712"])
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid EIP-712 domain for %s", domain)
+			}
+			eip712Domains[domain] = eip712Domain
+		}
+	}
+	signingGuardPath := viper.GetString("signing-guard.path")
+	if signingGuardPath == "" {
+		signingGuardPath = "signing-guard.json"
+	}
+	signingGuard, err := standardsigningguard.New(ctx,
+		standardsigningguard.WithLogLevel(util.LogLevel("signing-guard")),
+		standardsigningguard.WithMonitor(monitor),
+		standardsigningguard.WithPath(resolvePath(signingGuardPath)),
+		standardsigningguard.WithDomains(domains),
+		standardsigningguard.WithEIP712Domains(eip712Domains),
+		standardsigningguard.WithENS(ens),
 	)
 	if err != nil {
-		return errors.Wrap(err, "failed to start ENS service")
+		return nil, errors.Wrap(err, "failed to start signing guard service")
+	}
+
+	log.Trace().Msg("Starting audit log service")
+	auditLog, err := fileauditlog.New(ctx,
+		fileauditlog.WithLogLevel(util.LogLevel("audit-log")),
+		fileauditlog.WithMonitor(monitor),
+		fileauditlog.WithPath(auditLogPath()),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start audit log service")
 	}
-	fmt.Printf("Not using ENS %v\n", ens)
 
 	log.Trace().Msg("Starting claim data service")
-	claimData, err := standardclaimdata.New(ctx,
+	claimDataParams := []standardclaimdata.Parameter{
 		standardclaimdata.WithLogLevel(util.LogLevel("claimdata")),
 		standardclaimdata.WithMonitor(monitor),
 		standardclaimdata.WithTimeout(viper.GetDuration("claimdata.timeout")),
-		standardclaimdata.WithDomainControls(viper.GetStringMap("claimdata.domain-controls")),
-		standardclaimdata.WithENS(mockens.New()),
-	)
+		standardclaimdata.WithDomainControls(domainControls),
+		standardclaimdata.WithENS(ens),
+		standardclaimdata.WithSigningGuard(signingGuard),
+		standardclaimdata.WithAuditLog(auditLog),
+		standardclaimdata.WithResolvers(viper.GetStringSlice("claimdata.dns.resolvers")),
+		standardclaimdata.WithDNSRetries(viper.GetInt("claimdata.dns.retries")),
+	}
+	if viper.GetString("claimdata.dns.transport") != "" {
+		claimDataParams = append(claimDataParams, standardclaimdata.WithDNSTransport(viper.GetString("claimdata.dns.transport")))
+	}
+	if viper.IsSet("claimdata.dns.trust-anchors") {
+		claimDataParams = append(claimDataParams, standardclaimdata.WithTrustAnchors(viper.GetStringSlice("claimdata.dns.trust-anchors")))
+	}
+	if viper.IsSet("claimdata.dns.cache.size") {
+		claimDataParams = append(claimDataParams, standardclaimdata.WithCacheSize(viper.GetInt("claimdata.dns.cache.size")))
+	}
+	if viper.IsSet("claimdata.dns.cache.min-ttl") {
+		claimDataParams = append(claimDataParams, standardclaimdata.WithCacheMinTTL(viper.GetDuration("claimdata.dns.cache.min-ttl")))
+	}
+	if viper.IsSet("claimdata.dns.cache.max-ttl") {
+		claimDataParams = append(claimDataParams, standardclaimdata.WithCacheMaxTTL(viper.GetDuration("claimdata.dns.cache.max-ttl")))
+	}
+	claimData, err := standardclaimdata.New(ctx, claimDataParams...)
 	if err != nil {
-		return errors.Wrap(err, "failed to start claim data service")
+		return nil, errors.Wrap(err, "failed to start claim data service")
 	}
 
 	log.Trace().Msg("Starting daemon service")
@@ -206,10 +328,11 @@ func startServices(ctx context.Context, monitor metrics.Service) error {
 		jsonrpcdaemon.WithListenAddress(viper.GetString("jsonrpc.listen-address")),
 	)
 	if err != nil {
-		return errors.Wrap(err, "failed to start ENS service")
+		claimData.Close()
+		return nil, errors.Wrap(err, "failed to start ENS service")
 	}
 
-	return nil
+	return claimData, nil
 }
 
 func logModules() {
@@ -249,4 +372,31 @@ func runCommands(ctx context.Context) {
 		fmt.Printf("%s\n", ReleaseVersion)
 		os.Exit(0)
 	}
+
+	if viper.GetBool("verify-audit-log") {
+		path := auditLogPath()
+		entries, breaks, err := fileauditlog.Verify(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to verify audit log: %v\n", err)
+			os.Exit(1)
+		}
+		for _, chainBreak := range breaks {
+			fmt.Printf("%s:%d: %s\n", path, chainBreak.Line, chainBreak.Reason)
+		}
+		if len(breaks) > 0 {
+			fmt.Printf("Audit log has %d entries with %d breaks\n", entries, len(breaks))
+			os.Exit(1)
+		}
+		fmt.Printf("Audit log has %d entries and is intact\n", entries)
+		os.Exit(0)
+	}
+}
+
+// auditLogPath returns the path to the audit log.
+func auditLogPath() string {
+	path := viper.GetString("audit-log.path")
+	if path == "" {
+		path = "audit-log.json"
+	}
+	return resolvePath(path)
 }
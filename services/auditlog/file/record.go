@@ -0,0 +1,52 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+)
+
+// body is the content of an audit log record that is covered by its hash.
+type body struct {
+	Time      time.Time      `json:"time"`
+	Domain    string         `json:"domain"`
+	Label     string         `json:"label"`
+	Node      common.Hash    `json:"node"`
+	Owner     common.Address `json:"owner"`
+	Registrar common.Address `json:"registrar"`
+	Hash      common.Hash    `json:"hash"`
+	Signature hexutil.Bytes  `json:"signature"`
+	Previous  common.Hash    `json:"previous"`
+}
+
+// record is a single line in the audit log.
+type record struct {
+	body
+	EntryHash common.Hash `json:"entry_hash"`
+}
+
+// entryHash calculates the hash of the body of a record.
+func (b *body) entryHash() (common.Hash, error) {
+	data, err := json.Marshal(b)
+	if err != nil {
+		return common.Hash{}, errors.Wrap(err, "failed to encode record")
+	}
+	return crypto.Keccak256Hash(data), nil
+}
@@ -0,0 +1,145 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	zerologger "github.com/rs/zerolog/log"
+	"github.com/wealdtech/edcd/services/auditlog"
+)
+
+// Service is an audit log service that writes to an append-only file,
+// with each entry chained to the previous entry by its hash.
+type Service struct {
+	mutex    sync.Mutex
+	file     *os.File
+	previous common.Hash
+}
+
+// module-wide log.
+var log zerolog.Logger
+
+// New creates a new file audit log service.
+func New(ctx context.Context, params ...Parameter) (*Service, error) {
+	parameters, err := parseAndCheckParameters(params...)
+	if err != nil {
+		return nil, errors.Wrap(err, "problem with parameters")
+	}
+
+	// Set logging.
+	log = zerologger.With().Str("service", "auditlog").Str("impl", "file").Logger()
+	if parameters.logLevel != log.GetLevel() {
+		log = log.Level(parameters.logLevel)
+	}
+
+	if err := registerMetrics(ctx, parameters.monitor); err != nil {
+		return nil, errors.New("failed to register metrics")
+	}
+
+	file, err := os.OpenFile(parameters.path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open audit log")
+	}
+	previous, err := lastEntryHash(file)
+	if err != nil {
+		file.Close()
+		return nil, errors.Wrap(err, "failed to read audit log")
+	}
+	log.Trace().Str("previous", fmt.Sprintf("%#x", previous)).Msg("Opened audit log")
+
+	s := &Service{
+		file:     file,
+		previous: previous,
+	}
+
+	return s, nil
+}
+
+// lastEntryHash returns the hash of the last entry in the audit log.
+func lastEntryHash(file *os.File) (common.Hash, error) {
+	var last []byte
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) > 0 {
+			last = append(last[:0], scanner.Bytes()...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return common.Hash{}, err
+	}
+	if last == nil {
+		// Empty log.
+		return common.Hash{}, nil
+	}
+
+	entry := &record{}
+	if err := json.Unmarshal(last, entry); err != nil {
+		return common.Hash{}, errors.Wrap(err, "invalid last entry")
+	}
+	return entry.EntryHash, nil
+}
+
+// Record records an entry in the audit log.
+func (s *Service) Record(ctx context.Context, entry *auditlog.Entry) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	r := &record{
+		body: body{
+			Time:      entry.Time.UTC(),
+			Domain:    entry.Domain,
+			Label:     entry.Label,
+			Node:      entry.Node,
+			Owner:     entry.Owner,
+			Registrar: entry.Registrar,
+			Hash:      entry.Hash,
+			Signature: entry.Signature,
+			Previous:  s.previous,
+		},
+	}
+	var err error
+	r.EntryHash, err = r.body.entryHash()
+	if err != nil {
+		requestHandled("failed")
+		return err
+	}
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		requestHandled("failed")
+		return errors.Wrap(err, "failed to encode entry")
+	}
+	if _, err := s.file.Write(append(data, '\n')); err != nil {
+		requestHandled("failed")
+		return errors.Wrap(err, "failed to write entry")
+	}
+	if err := s.file.Sync(); err != nil {
+		requestHandled("failed")
+		return errors.Wrap(err, "failed to sync entry")
+	}
+	s.previous = r.EntryHash
+	log.Trace().Str("entry_hash", fmt.Sprintf("%#x", r.EntryHash)).Msg("Recorded entry")
+
+	requestHandled("succeeded")
+	return nil
+}
@@ -0,0 +1,97 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"github.com/wealdtech/edcd/services/auditlog/file"
+	nullmetrics "github.com/wealdtech/edcd/services/metrics/null"
+)
+
+func TestService(t *testing.T) {
+	ctx := context.Background()
+
+	monitor := nullmetrics.New()
+	dir := t.TempDir()
+
+	corruptPath := filepath.Join(dir, "corrupt.json")
+	require.NoError(t, os.WriteFile(corruptPath, []byte("{\"domain\":\n"), 0600))
+
+	tests := []struct {
+		name   string
+		params []file.Parameter
+		err    string
+	}{
+		{
+			name: "MonitorMissing",
+			params: []file.Parameter{
+				file.WithLogLevel(zerolog.Disabled),
+				file.WithMonitor(nil),
+				file.WithPath(filepath.Join(dir, "audit.json")),
+			},
+			err: "problem with parameters: no monitor specified",
+		},
+		{
+			name: "PathMissing",
+			params: []file.Parameter{
+				file.WithLogLevel(zerolog.Disabled),
+				file.WithMonitor(monitor),
+			},
+			err: "problem with parameters: no path specified",
+		},
+		{
+			name: "PathInvalid",
+			params: []file.Parameter{
+				file.WithLogLevel(zerolog.Disabled),
+				file.WithMonitor(monitor),
+				file.WithPath(filepath.Join(dir, "missing", "audit.json")),
+			},
+			err: "failed to open audit log: open " + filepath.Join(dir, "missing", "audit.json") + ": no such file or directory",
+		},
+		{
+			name: "Corrupt",
+			params: []file.Parameter{
+				file.WithLogLevel(zerolog.Disabled),
+				file.WithMonitor(monitor),
+				file.WithPath(corruptPath),
+			},
+			err: "failed to read audit log: invalid last entry: unexpected end of JSON input",
+		},
+		{
+			name: "Good",
+			params: []file.Parameter{
+				file.WithLogLevel(zerolog.Disabled),
+				file.WithMonitor(monitor),
+				file.WithPath(filepath.Join(dir, "audit.json")),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := file.New(ctx, test.params...)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
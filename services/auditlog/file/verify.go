@@ -0,0 +1,78 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+// Break is a break in the chain of an audit log.
+type Break struct {
+	Line   int
+	Reason string
+}
+
+// Verify walks the chain of the audit log at the given path, returning
+// the number of entries and any breaks found.
+func Verify(path string) (int, []*Break, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "failed to open audit log")
+	}
+	defer file.Close()
+
+	breaks := make([]*Break, 0)
+	entries := 0
+	previous := common.Hash{}
+	line := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		entries++
+
+		entry := &record{}
+		if err := json.Unmarshal(scanner.Bytes(), entry); err != nil {
+			breaks = append(breaks, &Break{Line: line, Reason: fmt.Sprintf("invalid entry: %v", err)})
+			// Cannot continue the chain from this entry, so restart it from the next.
+			previous = common.Hash{}
+			continue
+		}
+
+		if entry.Previous != previous {
+			breaks = append(breaks, &Break{Line: line, Reason: fmt.Sprintf("previous hash %#x does not match %#x", entry.Previous, previous)})
+		}
+		entryHash, err := entry.body.entryHash()
+		if err != nil {
+			return 0, nil, err
+		}
+		if entryHash != entry.EntryHash {
+			breaks = append(breaks, &Break{Line: line, Reason: fmt.Sprintf("entry hash %#x does not match contents %#x", entry.EntryHash, entryHash)})
+		}
+		previous = entry.EntryHash
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, nil, errors.Wrap(err, "failed to read audit log")
+	}
+
+	return entries, breaks, nil
+}
@@ -0,0 +1,133 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"github.com/wealdtech/edcd/services/auditlog"
+	"github.com/wealdtech/edcd/services/auditlog/file"
+	nullmetrics "github.com/wealdtech/edcd/services/metrics/null"
+)
+
+// writeLog writes a log with the given number of entries, returning its lines.
+func writeLog(t *testing.T, path string, entries int) []string {
+	ctx := context.Background()
+
+	// Reopen the log for each entry to ensure that the chain is continued across restarts.
+	for i := 0; i < entries; i++ {
+		s, err := file.New(ctx,
+			file.WithLogLevel(zerolog.Disabled),
+			file.WithMonitor(nullmetrics.New()),
+			file.WithPath(path),
+		)
+		require.NoError(t, err)
+		require.NoError(t, s.Record(ctx, &auditlog.Entry{
+			Time:      time.Unix(1600000000+int64(i), 0),
+			Domain:    "test.wealdtech.eth",
+			Label:     "test",
+			Node:      common.HexToHash("0x87e957ac2ae4a1e2e5d5dc2deb3b6a03e2aa62d5e3cc81da5bbd1b7b83823169"),
+			Owner:     common.HexToAddress("0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5"),
+			Registrar: common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314"),
+			Hash:      common.HexToHash("0x0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20"),
+			Signature: []byte{0x01, byte(i)},
+		}))
+	}
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+func TestVerify(t *testing.T) {
+	dir := t.TempDir()
+
+	goodPath := filepath.Join(dir, "good.json")
+	lines := writeLog(t, goodPath, 3)
+
+	tamperedPath := filepath.Join(dir, "tampered.json")
+	tampered := append([]string{}, lines...)
+	tampered[1] = strings.Replace(tampered[1], "0x388ea662ef2c223ec0b047d41bf3c0f362142ad5", "0x1a642f0e3c3af545e7acbd38b07251b3990914f1", 1)
+	require.NotEqual(t, lines[1], tampered[1])
+	require.NoError(t, os.WriteFile(tamperedPath, []byte(strings.Join(tampered, "\n")+"\n"), 0600))
+
+	removedPath := filepath.Join(dir, "removed.json")
+	require.NoError(t, os.WriteFile(removedPath, []byte(lines[0]+"\n"+lines[2]+"\n"), 0600))
+
+	invalidPath := filepath.Join(dir, "invalid.json")
+	require.NoError(t, os.WriteFile(invalidPath, []byte(lines[0]+"\n{\n"+lines[1]+"\n"), 0600))
+
+	tests := []struct {
+		name    string
+		path    string
+		entries int
+		breaks  []int
+		err     string
+	}{
+		{
+			name: "Missing",
+			path: filepath.Join(dir, "missing.json"),
+			err:  "failed to open audit log: open " + filepath.Join(dir, "missing.json") + ": no such file or directory",
+		},
+		{
+			name:    "Good",
+			path:    goodPath,
+			entries: 3,
+			breaks:  []int{},
+		},
+		{
+			name:    "Tampered",
+			path:    tamperedPath,
+			entries: 3,
+			breaks:  []int{2},
+		},
+		{
+			name:    "Removed",
+			path:    removedPath,
+			entries: 2,
+			breaks:  []int{2},
+		},
+		{
+			name:    "Invalid",
+			path:    invalidPath,
+			entries: 3,
+			breaks:  []int{2, 3},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			entries, breaks, err := file.Verify(test.path)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, test.entries, entries)
+				lines := make([]int, len(breaks))
+				for i := range breaks {
+					lines[i] = breaks[i].Line
+				}
+				require.Equal(t, test.breaks, lines)
+			}
+		})
+	}
+}
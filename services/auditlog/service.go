@@ -0,0 +1,39 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auditlog
+
+import (
+	"context"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Entry is an entry in the audit log.
+type Entry struct {
+	Time      time.Time
+	Domain    string
+	Label     string
+	Node      [32]byte
+	Owner     common.Address
+	Registrar common.Address
+	Hash      [32]byte
+	Signature []byte
+}
+
+// Service defines the audit log service.
+type Service interface {
+	// Record records an entry in the audit log.
+	Record(ctx context.Context, entry *Entry) error
+}
@@ -17,7 +17,7 @@ import (
 	"context"
 	"errors"
 
-	"github.com/ethereum/go-ethereum/common"
+	"github.com/wealdtech/edcd/services/claimdata"
 )
 
 // Service is a mock claim data service.
@@ -29,11 +29,9 @@ func New() *Service {
 }
 
 // GetClaimData is a mock.
-func (s *Service) GetClaimData(ctx context.Context,
-	domain string,
-) ([32]byte, string, common.Address, []byte, error) {
+func (s *Service) GetClaimData(ctx context.Context, domain string) (*claimdata.ClaimData, error) {
 	if domain == "" {
-		return [32]byte{}, "", common.Address{}, nil, errors.New("no domain supplied")
+		return nil, errors.New("no domain supplied")
 	}
-	return [32]byte{}, "", common.Address{}, nil, nil
+	return &claimdata.ClaimData{}, nil
 }
@@ -19,16 +19,25 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 )
 
+// ClaimData is the data required to claim a domain.
+type ClaimData struct {
+	// NameHash is the node of the parent domain.
+	NameHash [32]byte
+	// Label is the label of the domain being claimed.
+	Label string
+	// Owner is the new owner of the domain.
+	Owner common.Address
+	// OwnerName is the ENS name that resolved to the owner, if the owner
+	// record named the owner rather than stating its address.
+	OwnerName string
+	// Signature is the signature over the claim.
+	Signature []byte
+	// Signer is the address of the key that generated the signature.
+	Signer common.Address
+}
+
 // Service defines the claim data service.
 type Service interface {
 	// GetClaimData gets the claim data for a domain.
-	GetClaimData(ctx context.Context,
-		domain string,
-	) (
-		nameHash [32]byte,
-		label string,
-		owner common.Address,
-		signature []byte,
-		err error,
-	)
+	GetClaimData(ctx context.Context, domain string) (*ClaimData, error)
 }
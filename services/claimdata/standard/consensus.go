@@ -0,0 +1,116 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package standard
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// resolverAnswer is the answer of a single resolver to an owner lookup.
+type resolverAnswer struct {
+	resolver string
+	owner    ownerTarget
+	format   string
+	err      error
+}
+
+// ownerByConsensus looks up the owner of a domain with each resolver in
+// parallel, succeeding only if the domain control's threshold of resolvers
+// agree on the owner.  The cache is not used, as each resolver must answer
+// for itself.
+func (s *Service) ownerByConsensus(ctx context.Context, domainControl *domainControl, domain string) (ownerTarget, error) {
+	resolvers := s.dns.resolvers
+	answers := make([]*resolverAnswer, len(resolvers))
+	var wg sync.WaitGroup
+	for i := range resolvers {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			answer := &resolverAnswer{resolver: resolvers[i]}
+			answer.owner, answer.format, answer.err = s.lookupOwner(ctx, domainControl, domain, s.dns.forResolver(resolvers[i]), nil)
+			answers[i] = answer
+		}(i)
+	}
+	wg.Wait()
+
+	votes := make(map[common.Address]uint)
+	firstAnswers := make(map[common.Address]*resolverAnswer)
+	for _, answer := range answers {
+		if answer.err == nil {
+			votes[answer.owner.address]++
+			if _, exists := firstAnswers[answer.owner.address]; !exists {
+				firstAnswers[answer.owner.address] = answer
+			}
+		}
+	}
+
+	// Find the owners that reached the threshold.
+	agreed := make([]common.Address, 0)
+	for owner, count := range votes {
+		if count >= domainControl.ConsensusThreshold {
+			agreed = append(agreed, owner)
+		}
+	}
+	unanimous := len(votes) == 1 && len(agreed) == 1 && votes[agreed[0]] == uint(len(answers))
+	if !unanimous {
+		logDisagreement(domain, answers)
+	}
+
+	switch len(agreed) {
+	case 0:
+		consensusReached("failed")
+		best := uint(0)
+		for _, count := range votes {
+			if count > best {
+				best = count
+			}
+		}
+		return ownerTarget{}, fmt.Errorf("owner consensus not reached for %s: %d of %d resolvers agree, %d required",
+			domain, best, len(answers), domainControl.ConsensusThreshold)
+	case 1:
+		if unanimous {
+			consensusReached("unanimous")
+		} else {
+			consensusReached("agreed")
+		}
+		answer := firstAnswers[agreed[0]]
+		ownerRecordMatched(answer.format)
+		return answer.owner, nil
+	default:
+		consensusReached("conflicted")
+		sort.Slice(agreed, func(i, j int) bool { return agreed[i].Hex() < agreed[j].Hex() })
+		return ownerTarget{}, fmt.Errorf("owner consensus not reached for %s: resolvers agree on conflicting owners %v", domain, agreed)
+	}
+}
+
+// logDisagreement logs the answer of each resolver when they do not all agree.
+func logDisagreement(domain string, answers []*resolverAnswer) {
+	for _, answer := range answers {
+		e := log.Warn().Str("domain", domain).Str("resolver", answer.resolver)
+		if answer.err != nil {
+			e = e.Err(answer.err)
+		} else {
+			e = e.Str("owner", answer.owner.address.Hex())
+			if answer.owner.name != "" {
+				e = e.Str("owner_name", answer.owner.name)
+			}
+		}
+		e.Msg("Resolvers disagree on owner")
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package standard
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+	mockauditlog "github.com/wealdtech/edcd/services/auditlog/mock"
+	mockens "github.com/wealdtech/edcd/services/ens/mock"
+	mocksigningguard "github.com/wealdtech/edcd/services/signingguard/mock"
+	"github.com/wealdtech/edcd/util/dnsstandin"
+)
+
+func TestOwnerByConsensus(t *testing.T) {
+	ownerA := "a=0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5"
+	ownerB := "a=0x3325a78425F17a7E487Eb5666b2bFd93aBb06c70"
+	a := startDNSServer(t, txtHandler(ownerA, false), txtHandler(ownerA, false))
+	b := startDNSServer(t, txtHandler(ownerB, false), txtHandler(ownerB, false))
+	emptyServer, err := dnsstandin.New(strings.NewReader(""))
+	require.NoError(t, err)
+	defer emptyServer.Close()
+	empty := emptyServer.Address()
+	failed := startDNSServer(t, rcodeHandler(dns.RcodeServerFailure), rcodeHandler(dns.RcodeServerFailure))
+
+	tests := []struct {
+		name      string
+		resolvers []string
+		threshold uint
+		owner     common.Address
+		err       string
+	}{
+		{
+			name:      "Unanimous",
+			resolvers: []string{a, a, a},
+			threshold: 3,
+			owner:     common.HexToAddress("0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5"),
+		},
+		{
+			name:      "Majority",
+			resolvers: []string{a, b, a},
+			threshold: 2,
+			owner:     common.HexToAddress("0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5"),
+		},
+		{
+			name:      "MajorityWithFailure",
+			resolvers: []string{failed, a, a},
+			threshold: 2,
+			owner:     common.HexToAddress("0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5"),
+		},
+		{
+			name:      "BelowThreshold",
+			resolvers: []string{a, b, a},
+			threshold: 3,
+			err:       "owner consensus not reached for example.com: 2 of 3 resolvers agree, 3 required",
+		},
+		{
+			name:      "BelowThresholdWithMissing",
+			resolvers: []string{a, empty, failed},
+			threshold: 2,
+			err:       "owner consensus not reached for example.com: 1 of 3 resolvers agree, 2 required",
+		},
+		{
+			name:      "NoAnswers",
+			resolvers: []string{empty, failed},
+			threshold: 1,
+			err:       "owner consensus not reached for example.com: 0 of 2 resolvers agree, 1 required",
+		},
+		{
+			name:      "Conflicting",
+			resolvers: []string{a, b},
+			threshold: 1,
+			err:       "owner consensus not reached for example.com: resolvers agree on conflicting owners [0x3325a78425F17a7E487Eb5666b2bFd93aBb06c70 0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5]",
+		},
+	}
+
+	ctx := context.Background()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := New(ctx,
+				WithTimeout(5*time.Second),
+				WithDomainControls(map[string]interface{}{
+					"com": map[string]interface{}{
+						"owner-address":       "0x1a642f0E3c3aF545E7AcBD38b07251B3990914F1",
+						"passphrase":          map[string]interface{}{"file": "testdata/passphrase"},
+						"keystore":            "testdata/keystore",
+						"consensus-threshold": int(test.threshold),
+					},
+				}),
+				WithENS(mockens.New()),
+				WithSigningGuard(mocksigningguard.New()),
+				WithAuditLog(mockauditlog.New()),
+				WithResolvers(test.resolvers),
+			)
+			require.NoError(t, err)
+
+			dc := &domainControl{Domain: "example.com", Cache: true, ConsensusThreshold: test.threshold}
+			owner, err := s.ownerForDomain(ctx, dc, "example.com")
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, test.owner, owner.address)
+			}
+		})
+	}
+}
@@ -0,0 +1,266 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package standard
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/pkg/errors"
+)
+
+// dnsMessageContentType is the media type for DNS-over-HTTPS messages.
+const dnsMessageContentType = "application/dns-message"
+
+// dnsClient carries out DNS queries against an ordered list of resolvers.
+// Resolvers are plain DNS servers, DNS-over-TLS servers given as
+// tls://host[:port] or DNS-over-HTTPS endpoints given as https:// URLs.
+type dnsClient struct {
+	resolvers  []string
+	transport  string
+	retries    int
+	timeout    time.Duration
+	tlsConfig  *tls.Config
+	httpClient *http.Client
+}
+
+// newDNSClient creates a new DNS client.  If no resolvers are supplied
+// then they are obtained from the resolver configuration file.  The
+// timeout is shared between all attempts, so that a lookup that has to
+// try every resolver still completes within it.
+func newDNSClient(resolvers []string, resolvConf string, transport string, retries int, timeout time.Duration) (*dnsClient, error) {
+	if len(resolvers) == 0 {
+		config, err := dns.ClientConfigFromFile(resolvConf)
+		if err != nil {
+			return nil, errors.Wrap(err, "no resolvers supplied and failed to read resolver configuration")
+		}
+		for _, server := range config.Servers {
+			resolvers = append(resolvers, net.JoinHostPort(server, config.Port))
+		}
+		if len(resolvers) == 0 {
+			return nil, fmt.Errorf("no resolvers supplied or found in %s", resolvConf)
+		}
+		log.Trace().Strs("resolvers", resolvers).Str("path", resolvConf).Msg("Obtained resolvers from configuration file")
+	}
+
+	client := &dnsClient{
+		resolvers: make([]string, len(resolvers)),
+		transport: transport,
+		retries:   retries,
+		timeout:   timeout / time.Duration(len(resolvers)*(retries+1)),
+	}
+	for i := range resolvers {
+		address, err := resolverAddress(resolvers[i])
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid resolver %s", resolvers[i])
+		}
+		client.resolvers[i] = address
+	}
+	client.httpClient = &http.Client{}
+
+	return client, nil
+}
+
+// forResolver returns a copy of the client that queries only the given
+// resolver, with the timeout for the full set of resolvers.
+func (c *dnsClient) forResolver(resolver string) *dnsClient {
+	return &dnsClient{
+		resolvers:  []string{resolver},
+		transport:  c.transport,
+		retries:    c.retries,
+		timeout:    c.timeout * time.Duration(len(c.resolvers)),
+		tlsConfig:  c.tlsConfig,
+		httpClient: c.httpClient,
+	}
+}
+
+// resolverAddress returns the address of the resolver, adding the default
+// port if required.
+func resolverAddress(resolver string) (string, error) {
+	switch {
+	case strings.HasPrefix(resolver, "https://"):
+		endpoint, err := url.Parse(resolver)
+		if err != nil {
+			return "", err
+		}
+		if endpoint.Host == "" {
+			return "", errors.New("no host")
+		}
+		return endpoint.String(), nil
+	case strings.HasPrefix(resolver, "tls://"):
+		host := strings.TrimPrefix(resolver, "tls://")
+		if host == "" {
+			return "", errors.New("no host")
+		}
+		return "tls://" + hostPort(host, "853"), nil
+	case strings.Contains(resolver, "://"):
+		return "", errors.New("unsupported scheme")
+	default:
+		return hostPort(resolver, "53"), nil
+	}
+}
+
+// hostPort returns the host with the default port added if it has none.
+func hostPort(host string, port string) string {
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+	return net.JoinHostPort(strings.TrimSuffix(strings.TrimPrefix(host, "["), "]"), port)
+}
+
+// exchange sends the query to each resolver in turn until one answers.
+func (c *dnsClient) exchange(ctx context.Context, m *dns.Msg) (*dns.Msg, error) {
+	var err error
+	for attempt := 0; attempt <= c.retries; attempt++ {
+		for _, resolver := range c.resolvers {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			var r *dns.Msg
+			r, err = c.exchangeWith(ctx, m, resolver)
+			if err == nil {
+				return r, nil
+			}
+			log.Debug().Str("resolver", resolver).Int("attempt", attempt).Err(err).Msg("DNS query failed")
+		}
+	}
+	return nil, err
+}
+
+// exchangeWith sends the query to a single resolver.
+func (c *dnsClient) exchangeWith(ctx context.Context, m *dns.Msg, resolver string) (*dns.Msg, error) {
+	var r *dns.Msg
+	var err error
+	switch {
+	case strings.HasPrefix(resolver, "https://"):
+		r, err = c.exchangeHTTPS(ctx, m, resolver)
+	case strings.HasPrefix(resolver, "tls://"):
+		r, err = c.exchangeTLS(ctx, m, strings.TrimPrefix(resolver, "tls://"))
+	default:
+		r, err = c.exchangePlain(ctx, m, resolver)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if r.Id != m.Id {
+		return nil, errors.New("query ID mismatch")
+	}
+	if r.Rcode == dns.RcodeServerFailure || r.Rcode == dns.RcodeRefused {
+		return nil, fmt.Errorf("resolver returned %s", dns.RcodeToString[r.Rcode])
+	}
+
+	return r, nil
+}
+
+// exchangePlain sends the query to a plain DNS resolver.
+func (c *dnsClient) exchangePlain(ctx context.Context, m *dns.Msg, resolver string) (*dns.Msg, error) {
+	client := &dns.Client{
+		Net:     c.transport,
+		Timeout: c.timeout,
+	}
+	r, _, err := client.ExchangeContext(ctx, m, resolver)
+	if err != nil {
+		return nil, err
+	}
+	if r.Truncated && client.Net == "udp" {
+		// The answer did not fit in a UDP response, so retry over TCP.
+		log.Trace().Str("resolver", resolver).Msg("Response truncated; retrying over TCP")
+		client.Net = "tcp"
+		r, _, err = client.ExchangeContext(ctx, m, resolver)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// exchangeTLS sends the query to a DNS-over-TLS resolver.
+func (c *dnsClient) exchangeTLS(ctx context.Context, m *dns.Msg, resolver string) (*dns.Msg, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if c.tlsConfig != nil {
+		tlsConfig = c.tlsConfig.Clone()
+	}
+	if tlsConfig.ServerName == "" {
+		host, _, err := net.SplitHostPort(resolver)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.ServerName = host
+	}
+	client := &dns.Client{
+		Net:       "tcp-tls",
+		Timeout:   c.timeout,
+		TLSConfig: tlsConfig,
+	}
+	r, _, err := client.ExchangeContext(ctx, m, resolver)
+	return r, err
+}
+
+// exchangeHTTPS sends the query to a DNS-over-HTTPS resolver as defined in
+// RFC 8484.
+func (c *dnsClient) exchangeHTTPS(ctx context.Context, m *dns.Msg, resolver string) (*dns.Msg, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	// RFC 8484 recommends an ID of 0 to make responses cacheable.
+	query := m.Copy()
+	query.Id = 0
+	data, err := query.Pack()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to pack query")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, resolver, bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create request")
+	}
+	req.Header.Set("Content-Type", dnsMessageContentType)
+	req.Header.Set("Accept", dnsMessageContentType)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("resolver returned HTTP status %d", resp.StatusCode)
+	}
+	if contentType := resp.Header.Get("Content-Type"); contentType != dnsMessageContentType {
+		return nil, fmt.Errorf("resolver returned content type %q", contentType)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, dns.MaxMsgSize))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read response")
+	}
+
+	r := new(dns.Msg)
+	if err := r.Unpack(body); err != nil {
+		return nil, errors.Wrap(err, "failed to unpack response")
+	}
+	if r.Id != query.Id {
+		return nil, errors.New("query ID mismatch")
+	}
+	r.Id = m.Id
+
+	return r, nil
+}
@@ -0,0 +1,370 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package standard
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+// startDNSServer starts a DNS server on UDP and TCP on the same loopback port,
+// returning its address.
+func startDNSServer(t *testing.T, udpHandler dns.HandlerFunc, tcpHandler dns.HandlerFunc) string {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	listener, err := net.Listen("tcp", pc.LocalAddr().String())
+	require.NoError(t, err)
+
+	udpServer := &dns.Server{PacketConn: pc, Handler: udpHandler}
+	tcpServer := &dns.Server{Listener: listener, Handler: tcpHandler}
+	go func() {
+		_ = udpServer.ActivateAndServe()
+	}()
+	go func() {
+		_ = tcpServer.ActivateAndServe()
+	}()
+	t.Cleanup(func() {
+		_ = udpServer.Shutdown()
+		_ = tcpServer.Shutdown()
+	})
+
+	return pc.LocalAddr().String()
+}
+
+// txtHandler returns a handler that answers with a TXT record.
+func txtHandler(txt string, truncated bool) dns.HandlerFunc {
+	return func(w dns.ResponseWriter, req *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(req)
+		m.Truncated = truncated
+		if !truncated {
+			m.Answer = append(m.Answer, &dns.TXT{
+				Hdr: dns.RR_Header{Name: req.Question[0].Name, Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: 60},
+				Txt: []string{txt},
+			})
+		}
+		_ = w.WriteMsg(m)
+	}
+}
+
+// rcodeHandler returns a handler that answers with the given response code.
+func rcodeHandler(rcode int) dns.HandlerFunc {
+	return func(w dns.ResponseWriter, req *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetRcode(req, rcode)
+		_ = w.WriteMsg(m)
+	}
+}
+
+func TestNewDNSClient(t *testing.T) {
+	tests := []struct {
+		name       string
+		resolvers  []string
+		resolvConf string
+		retries    int
+		expected   []string
+		timeout    time.Duration
+		err        string
+	}{
+		{
+			name:       "ResolvConfMissing",
+			resolvConf: "testdata/missing.conf",
+			err:        "no resolvers supplied and failed to read resolver configuration: open testdata/missing.conf: no such file or directory",
+		},
+		{
+			name:       "ResolvConfEmpty",
+			resolvConf: "testdata/resolv-empty.conf",
+			err:        "no resolvers supplied or found in testdata/resolv-empty.conf",
+		},
+		{
+			name:       "ResolvConf",
+			resolvConf: "testdata/resolv.conf",
+			expected:   []string{"192.0.2.1:53", "[2001:db8::1]:53"},
+			timeout:    15 * time.Second,
+		},
+		{
+			name:       "EncryptedResolvers",
+			resolvers:  []string{"https://dns.example.com/dns-query", "tls://dns.example.com", "tls://192.0.2.1:8853"},
+			resolvConf: "testdata/missing.conf",
+			expected:   []string{"https://dns.example.com/dns-query", "tls://dns.example.com:853", "tls://192.0.2.1:8853"},
+			timeout:    10 * time.Second,
+		},
+		{
+			name:      "HTTPSNoHost",
+			resolvers: []string{"https:///dns-query"},
+			err:       "invalid resolver https:///dns-query: no host",
+		},
+		{
+			name:      "TLSNoHost",
+			resolvers: []string{"tls://"},
+			err:       "invalid resolver tls://: no host",
+		},
+		{
+			name:      "UnsupportedScheme",
+			resolvers: []string{"quic://dns.example.com"},
+			err:       "invalid resolver quic://dns.example.com: unsupported scheme",
+		},
+		{
+			name:       "Resolvers",
+			resolvers:  []string{"192.0.2.1", "192.0.2.2:5353", "2001:db8::1", "[2001:db8::2]", "[2001:db8::3]:5353", "dns.example.com"},
+			resolvConf: "testdata/missing.conf",
+			retries:    1,
+			expected:   []string{"192.0.2.1:53", "192.0.2.2:5353", "[2001:db8::1]:53", "[2001:db8::2]:53", "[2001:db8::3]:5353", "dns.example.com:53"},
+			timeout:    2500 * time.Millisecond,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, err := newDNSClient(test.resolvers, test.resolvConf, "udp", test.retries, 30*time.Second)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, test.expected, client.resolvers)
+				require.Equal(t, test.timeout, client.timeout)
+			}
+		})
+	}
+}
+
+func TestDNSExchange(t *testing.T) {
+	ctx := context.Background()
+
+	good := startDNSServer(t, txtHandler("udp", false), txtHandler("tcp", false))
+	truncated := startDNSServer(t, txtHandler("", true), txtHandler("tcp", false))
+	servfail := startDNSServer(t, rcodeHandler(dns.RcodeServerFailure), rcodeHandler(dns.RcodeServerFailure))
+	nxdomain := startDNSServer(t, rcodeHandler(dns.RcodeNameError), rcodeHandler(dns.RcodeNameError))
+
+	// Obtain an address on which nothing is listening.
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closed := pc.LocalAddr().String()
+	require.NoError(t, pc.Close())
+
+	tests := []struct {
+		name      string
+		resolvers []string
+		transport string
+		retries   int
+		txt       string
+		rcode     int
+		err       string
+	}{
+		{
+			name:      "UDP",
+			resolvers: []string{good},
+			transport: "udp",
+			txt:       "udp",
+		},
+		{
+			name:      "TCP",
+			resolvers: []string{good},
+			transport: "tcp",
+			txt:       "tcp",
+		},
+		{
+			name:      "Truncated",
+			resolvers: []string{truncated},
+			transport: "udp",
+			txt:       "tcp",
+		},
+		{
+			name:      "ServerFailure",
+			resolvers: []string{servfail},
+			transport: "udp",
+			err:       "resolver returned SERVFAIL",
+		},
+		{
+			name:      "ServerFailureFailover",
+			resolvers: []string{servfail, good},
+			transport: "udp",
+			txt:       "udp",
+		},
+		{
+			name:      "UnreachableFailover",
+			resolvers: []string{closed, good},
+			transport: "udp",
+			txt:       "udp",
+		},
+		{
+			name:      "UnreachableRetries",
+			resolvers: []string{closed},
+			transport: "udp",
+			retries:   2,
+			err:       "read udp",
+		},
+		{
+			name:      "NXDomain",
+			resolvers: []string{nxdomain, good},
+			transport: "udp",
+			rcode:     dns.RcodeNameError,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, err := newDNSClient(test.resolvers, "", test.transport, test.retries, 5*time.Second)
+			require.NoError(t, err)
+
+			m := new(dns.Msg)
+			m.SetQuestion("test.example.com.", dns.TypeTXT)
+			r, err := client.exchange(ctx, m)
+			if test.err != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), test.err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, test.rcode, r.Rcode)
+				if test.txt != "" {
+					require.Len(t, r.Answer, 1)
+					require.Equal(t, []string{test.txt}, r.Answer[0].(*dns.TXT).Txt)
+				}
+			}
+		})
+	}
+}
+
+// dohHandler returns a DNS-over-HTTPS handler that answers with a TXT record.
+func dohHandler(txt string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != dnsMessageContentType {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		req := new(dns.Msg)
+		if err := req.Unpack(body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		m := new(dns.Msg)
+		m.SetReply(req)
+		m.Answer = append(m.Answer, &dns.TXT{
+			Hdr: dns.RR_Header{Name: req.Question[0].Name, Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: 60},
+			Txt: []string{txt},
+		})
+		data, err := m.Pack()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", dnsMessageContentType)
+		_, _ = w.Write(data)
+	}
+}
+
+// startDoTServer starts a DNS-over-TLS server with the given TLS
+// configuration, returning its address.
+func startDoTServer(t *testing.T, tlsConfig *tls.Config, handler dns.HandlerFunc) string {
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", tlsConfig)
+	require.NoError(t, err)
+	server := &dns.Server{Listener: listener, Net: "tcp-tls", Handler: handler}
+	go func() {
+		_ = server.ActivateAndServe()
+	}()
+	t.Cleanup(func() {
+		_ = server.Shutdown()
+	})
+
+	return listener.Addr().String()
+}
+
+func TestDNSExchangeEncrypted(t *testing.T) {
+	ctx := context.Background()
+
+	doh := httptest.NewTLSServer(dohHandler("https"))
+	defer doh.Close()
+	dohFailed := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer dohFailed.Close()
+	dohContentType := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+	}))
+	defer dohContentType.Close()
+
+	// The DNS-over-TLS server uses the same certificate as the DNS-over-HTTPS servers.
+	dot := startDoTServer(t, doh.TLS, txtHandler("tls", false))
+	roots := x509.NewCertPool()
+	roots.AddCert(doh.Certificate())
+
+	tests := []struct {
+		name      string
+		resolvers []string
+		txt       string
+		err       string
+	}{
+		{
+			name:      "HTTPS",
+			resolvers: []string{doh.URL + "/dns-query"},
+			txt:       "https",
+		},
+		{
+			name:      "HTTPSFailed",
+			resolvers: []string{dohFailed.URL + "/dns-query"},
+			err:       "resolver returned HTTP status 500",
+		},
+		{
+			name:      "HTTPSContentType",
+			resolvers: []string{dohContentType.URL + "/dns-query"},
+			err:       "resolver returned content type \"text/plain\"",
+		},
+		{
+			name:      "HTTPSFailover",
+			resolvers: []string{dohFailed.URL + "/dns-query", doh.URL + "/dns-query"},
+			txt:       "https",
+		},
+		{
+			name:      "TLS",
+			resolvers: []string{"tls://" + dot},
+			txt:       "tls",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, err := newDNSClient(test.resolvers, "", "udp", 0, 5*time.Second)
+			require.NoError(t, err)
+			client.httpClient = doh.Client()
+			client.tlsConfig = &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12}
+
+			m := new(dns.Msg)
+			m.SetQuestion("test.example.com.", dns.TypeTXT)
+			r, err := client.exchange(ctx, m)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, m.Id, r.Id)
+				require.Len(t, r.Answer, 1)
+				require.Equal(t, []string{test.txt}, r.Answer[0].(*dns.TXT).Txt)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package standard
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// dnsCache is a bounded cache of TXT answers, evicting the least recently
+// used answer when full.
+type dnsCache struct {
+	mutex      sync.Mutex
+	maxEntries int
+	minTTL     time.Duration
+	maxTTL     time.Duration
+	entries    map[dnsCacheKey]*list.Element
+	lru        *list.List
+	now        func() time.Time
+}
+
+// dnsCacheKey is the key for an answer in the cache.  Answers validated with
+// DNSSEC are held separately from those that are not.
+type dnsCacheKey struct {
+	name      string
+	validated bool
+}
+
+// dnsCacheEntry is an answer in the cache.
+type dnsCacheEntry struct {
+	key     dnsCacheKey
+	txts    []string
+	expires time.Time
+}
+
+// newDNSCache creates a new DNS cache.  It returns nil if the cache has no
+// capacity, which disables caching.
+func newDNSCache(maxEntries int, minTTL time.Duration, maxTTL time.Duration) *dnsCache {
+	if maxEntries == 0 {
+		return nil
+	}
+	return &dnsCache{
+		maxEntries: maxEntries,
+		minTTL:     minTTL,
+		maxTTL:     maxTTL,
+		entries:    make(map[dnsCacheKey]*list.Element),
+		lru:        list.New(),
+		now:        time.Now,
+	}
+}
+
+// get obtains an answer from the cache.
+func (c *dnsCache) get(key dnsCacheKey) ([]string, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	element, exists := c.entries[key]
+	if !exists {
+		cacheLookup("miss")
+		return nil, false
+	}
+	entry := element.Value.(*dnsCacheEntry)
+	if !c.now().Before(entry.expires) {
+		c.lru.Remove(element)
+		delete(c.entries, key)
+		cacheLookup("expired")
+		return nil, false
+	}
+	c.lru.MoveToFront(element)
+	cacheLookup("hit")
+
+	return entry.txts, true
+}
+
+// set adds an answer to the cache, if it is cacheable.
+func (c *dnsCache) set(key dnsCacheKey, txts []string, r *dns.Msg) {
+	if c == nil {
+		return
+	}
+	ttl, cacheable := responseTTL(r)
+	if !cacheable {
+		return
+	}
+	if ttl < c.minTTL {
+		ttl = c.minTTL
+	}
+	if ttl > c.maxTTL {
+		ttl = c.maxTTL
+	}
+	if ttl == 0 {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry := &dnsCacheEntry{
+		key:     key,
+		txts:    txts,
+		expires: c.now().Add(ttl),
+	}
+	if element, exists := c.entries[key]; exists {
+		element.Value = entry
+		c.lru.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.maxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*dnsCacheEntry).key)
+		cacheEvicted()
+	}
+}
+
+// responseTTL returns the time for which a response can be cached.  Positive
+// answers are cached for the lowest TTL of their records; NXDOMAIN and NODATA
+// answers are cached for the negative TTL given by the SOA record in the
+// authority section, as per RFC 2308.
+func responseTTL(r *dns.Msg) (time.Duration, bool) {
+	if r.Rcode != dns.RcodeSuccess && r.Rcode != dns.RcodeNameError {
+		return 0, false
+	}
+
+	if r.Rcode == dns.RcodeSuccess && len(r.Answer) > 0 {
+		ttl := r.Answer[0].Header().Ttl
+		for _, rr := range r.Answer[1:] {
+			if rr.Header().Ttl < ttl {
+				ttl = rr.Header().Ttl
+			}
+		}
+		return time.Duration(ttl) * time.Second, true
+	}
+
+	for _, rr := range r.Ns {
+		if soa, isSOA := rr.(*dns.SOA); isSOA {
+			ttl := soa.Hdr.Ttl
+			if soa.Minttl < ttl {
+				ttl = soa.Minttl
+			}
+			return time.Duration(ttl) * time.Second, true
+		}
+	}
+
+	// A negative answer without an SOA record cannot be cached.
+	return 0, false
+}
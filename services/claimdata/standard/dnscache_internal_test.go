@@ -0,0 +1,219 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package standard
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+	mockauditlog "github.com/wealdtech/edcd/services/auditlog/mock"
+	mockens "github.com/wealdtech/edcd/services/ens/mock"
+	mocksigningguard "github.com/wealdtech/edcd/services/signingguard/mock"
+	"github.com/wealdtech/edcd/util/dnsstandin"
+)
+
+func testTXT(name string, ttl uint32) *dns.TXT {
+	return &dns.TXT{
+		Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: ttl},
+		Txt: []string{"a=0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5"},
+	}
+}
+
+func testSOA(ttl uint32, minTTL uint32) *dns.SOA {
+	return &dns.SOA{
+		Hdr:    dns.RR_Header{Name: "example.com.", Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: ttl},
+		Ns:     "ns.example.com.",
+		Mbox:   "hostmaster.example.com.",
+		Minttl: minTTL,
+	}
+}
+
+func TestResponseTTL(t *testing.T) {
+	tests := []struct {
+		name      string
+		r         *dns.Msg
+		ttl       time.Duration
+		cacheable bool
+	}{
+		{
+			name: "ServerFailure",
+			r:    &dns.Msg{MsgHdr: dns.MsgHdr{Rcode: dns.RcodeServerFailure}},
+		},
+		{
+			name: "Answer",
+			r: &dns.Msg{
+				Answer: []dns.RR{testTXT("example.com.", 300), testTXT("example.com.", 60)},
+			},
+			ttl:       time.Minute,
+			cacheable: true,
+		},
+		{
+			name: "NoDataNoSOA",
+			r:    &dns.Msg{},
+		},
+		{
+			name: "NoData",
+			r: &dns.Msg{
+				Ns: []dns.RR{testSOA(3600, 300)},
+			},
+			ttl:       5 * time.Minute,
+			cacheable: true,
+		},
+		{
+			name: "NXDomain",
+			r: &dns.Msg{
+				MsgHdr: dns.MsgHdr{Rcode: dns.RcodeNameError},
+				Ns:     []dns.RR{testSOA(120, 300)},
+			},
+			ttl:       2 * time.Minute,
+			cacheable: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ttl, cacheable := responseTTL(test.r)
+			require.Equal(t, test.cacheable, cacheable)
+			require.Equal(t, test.ttl, ttl)
+		})
+	}
+}
+
+func TestDNSCache(t *testing.T) {
+	// Ensure a nil cache can be used.
+	var nilCache *dnsCache
+	nilCache.set(dnsCacheKey{name: "example.com."}, []string{"a"}, &dns.Msg{Answer: []dns.RR{testTXT("example.com.", 60)}})
+	_, exists := nilCache.get(dnsCacheKey{name: "example.com."})
+	require.False(t, exists)
+	require.Nil(t, newDNSCache(0, 0, time.Hour))
+
+	now := time.Now()
+	cache := newDNSCache(2, 10*time.Second, time.Hour)
+	cache.now = func() time.Time { return now }
+
+	a := dnsCacheKey{name: "a.example.com."}
+	b := dnsCacheKey{name: "b.example.com."}
+	c := dnsCacheKey{name: "c.example.com."}
+	validatedA := dnsCacheKey{name: "a.example.com.", validated: true}
+
+	// Answers are cached for their TTL, within the minimum and maximum.
+	cache.set(a, []string{"a"}, &dns.Msg{Answer: []dns.RR{testTXT("a.example.com.", 1)}})
+	cache.set(b, []string{"b"}, &dns.Msg{Answer: []dns.RR{testTXT("b.example.com.", 86400)}})
+	txts, exists := cache.get(a)
+	require.True(t, exists)
+	require.Equal(t, []string{"a"}, txts)
+	_, exists = cache.get(validatedA)
+	require.False(t, exists)
+
+	now = now.Add(10 * time.Second)
+	_, exists = cache.get(a)
+	require.False(t, exists)
+	now = now.Add(time.Hour - 10*time.Second - time.Nanosecond)
+	_, exists = cache.get(b)
+	require.True(t, exists)
+	now = now.Add(time.Nanosecond)
+	_, exists = cache.get(b)
+	require.False(t, exists)
+
+	// Negative answers are cached.
+	cache.set(a, []string{}, &dns.Msg{MsgHdr: dns.MsgHdr{Rcode: dns.RcodeNameError}, Ns: []dns.RR{testSOA(60, 60)}})
+	txts, exists = cache.get(a)
+	require.True(t, exists)
+	require.Empty(t, txts)
+
+	// Uncacheable answers are not cached.
+	cache.set(b, []string{}, &dns.Msg{})
+	_, exists = cache.get(b)
+	require.False(t, exists)
+
+	// The least recently used answer is evicted.
+	cache.set(b, []string{"b"}, &dns.Msg{Answer: []dns.RR{testTXT("b.example.com.", 60)}})
+	_, exists = cache.get(a)
+	require.True(t, exists)
+	cache.set(c, []string{"c"}, &dns.Msg{Answer: []dns.RR{testTXT("c.example.com.", 60)}})
+	_, exists = cache.get(b)
+	require.False(t, exists)
+	_, exists = cache.get(a)
+	require.True(t, exists)
+	_, exists = cache.get(c)
+	require.True(t, exists)
+}
+
+func TestTXTRecordsCache(t *testing.T) {
+	server, err := dnsstandin.New(strings.NewReader(`
+example.com. 3600 IN SOA ns.example.com. hostmaster.example.com. 1 7200 3600 1209600 60
+example.com. 60   IN TXT "a=0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5"
+`))
+	require.NoError(t, err)
+	defer server.Close()
+
+	ctx := context.Background()
+	dcs := map[string]interface{}{
+		"com": map[string]interface{}{
+			"owner-address": "0x1a642f0E3c3aF545E7AcBD38b07251B3990914F1",
+			"passphrase":    map[string]interface{}{"file": "testdata/passphrase"},
+			"keystore":      "testdata/keystore",
+		},
+	}
+	s, err := New(ctx,
+		WithTimeout(5*time.Second),
+		WithDomainControls(dcs),
+		WithENS(mockens.New()),
+		WithSigningGuard(mocksigningguard.New()),
+		WithAuditLog(mockauditlog.New()),
+		WithResolvers([]string{server.Address()}),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		cache   bool
+		domain  string
+		queries int
+	}{
+		{
+			name:    "Uncached",
+			domain:  "example.com",
+			queries: 3,
+		},
+		{
+			name:    "Cached",
+			cache:   true,
+			domain:  "example.com",
+			queries: 1,
+		},
+		{
+			name:    "NegativeCached",
+			cache:   true,
+			domain:  "missing.example.com",
+			queries: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			start := server.Queries()
+			dc := &domainControl{Domain: test.domain, Cache: test.cache}
+			for i := 0; i < 3; i++ {
+				_, err := s.txtRecords(ctx, dc, test.domain, s.dns, s.cache)
+				require.NoError(t, err)
+			}
+			require.Equal(t, test.queries, server.Queries()-start)
+		})
+	}
+}
@@ -0,0 +1,462 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package standard
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/pkg/errors"
+)
+
+// defaultTrustAnchors are the DS records for the root zone key signing keys.
+var defaultTrustAnchors = []string{
+	". IN DS 20326 8 2 E06D44B80B8F1D39A95C0B0D7C65D08458E880409BBC683457104237C7F8EC8D",
+	". IN DS 38696 8 2 683D2D0ACB8C9B712A1948B27F741219298D0A450D612C483AF444A4C0FB2B16",
+}
+
+var (
+	// errDNSSECUnsigned is returned when a response that must be validated
+	// carries no DNSSEC signatures, or sits below an insecure delegation.
+	errDNSSECUnsigned = errors.New("unsigned DNSSEC response")
+	// errDNSSECBogus is returned when a response carries DNSSEC signatures
+	// that do not validate back to a trust anchor.
+	errDNSSECBogus = errors.New("bogus DNSSEC response")
+)
+
+// dnssecValidator validates DNS responses through the chain of trust from
+// the root trust anchors.
+type dnssecValidator struct {
+	client  *dnsClient
+	anchors []*dns.DS
+	now     func() time.Time
+}
+
+// newDNSSECValidator creates a new DNSSEC validator.
+func newDNSSECValidator(client *dnsClient, trustAnchors []string) (*dnssecValidator, error) {
+	anchors := make([]*dns.DS, 0, len(trustAnchors))
+	for _, trustAnchor := range trustAnchors {
+		rr, err := dns.NewRR(trustAnchor)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid trust anchor %s", trustAnchor)
+		}
+		ds, isDS := rr.(*dns.DS)
+		if !isDS || ds.Hdr.Name != "." {
+			return nil, fmt.Errorf("trust anchor %s is not a DS record for the root zone", trustAnchor)
+		}
+		anchors = append(anchors, ds)
+	}
+	if len(anchors) == 0 {
+		return nil, errors.New("no trust anchors supplied")
+	}
+
+	return &dnssecValidator{
+		client:  client,
+		anchors: anchors,
+		now:     time.Now,
+	}, nil
+}
+
+// withClient returns a copy of the validator that uses the given client.
+func (v *dnssecValidator) withClient(client *dnsClient) *dnssecValidator {
+	if client == v.client {
+		return v
+	}
+	return &dnssecValidator{
+		client:  client,
+		anchors: v.anchors,
+		now:     v.now,
+	}
+}
+
+// newQuery creates a recursive query for the given name and type.  If dnssec
+// is set the query asks for DNSSEC records and disables checking by the
+// resolver, so that validation is carried out locally.
+func newQuery(name string, qtype uint16, dnssec bool) *dns.Msg {
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(name), qtype)
+	m.RecursionDesired = true
+	if dnssec {
+		m.CheckingDisabled = true
+		m.SetEdns0(4096, true)
+	}
+	return m
+}
+
+// validate validates all RRsets in the answer section of the response.
+func (v *dnssecValidator) validate(ctx context.Context, r *dns.Msg) error {
+	zoneKeys := make(map[string][]*dns.DNSKEY)
+	rrsets, sigs := splitRRsets(r.Answer)
+	for _, rrset := range rrsets {
+		if err := v.verifyRRset(ctx, rrset, sigs, zoneKeys); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateDenial validates the NSEC or NSEC3 records in the authority section
+// of a response that prove that there are no records of the given type at the
+// name.  Wildcards are not considered, as answers synthesised from wildcards
+// are refused regardless.
+func (v *dnssecValidator) validateDenial(ctx context.Context, r *dns.Msg, name string, qtype uint16) error {
+	desc := fmt.Sprintf("%s %s", dns.CanonicalName(name), dns.TypeToString[qtype])
+
+	zoneKeys := make(map[string][]*dns.DNSKEY)
+	nsecs := make([]*dns.NSEC, 0)
+	nsec3s := make([]*dns.NSEC3, 0)
+	rrsets, sigs := splitRRsets(r.Ns)
+	for _, rrset := range rrsets {
+		rrtype := rrset[0].Header().Rrtype
+		if rrtype != dns.TypeNSEC && rrtype != dns.TypeNSEC3 {
+			continue
+		}
+		if err := v.verifyRRset(ctx, rrset, sigs, zoneKeys); err != nil {
+			return err
+		}
+		for _, rr := range rrset {
+			switch rr := rr.(type) {
+			case *dns.NSEC:
+				nsecs = append(nsecs, rr)
+			case *dns.NSEC3:
+				nsec3s = append(nsec3s, rr)
+			}
+		}
+	}
+	if len(nsecs) == 0 && len(nsec3s) == 0 {
+		return fmt.Errorf("%w: %s: no proof of nonexistence", errDNSSECUnsigned, desc)
+	}
+
+	if nsecDenies(nsecs, name, qtype) {
+		log.Trace().Str("name", desc).Msg("Validated NSEC proof of nonexistence")
+		return nil
+	}
+	denied, optOut := nsec3Denies(nsec3s, name, qtype)
+	if denied {
+		log.Trace().Str("name", desc).Msg("Validated NSEC3 proof of nonexistence")
+		return nil
+	}
+	if optOut {
+		return fmt.Errorf("%w: %s: opt-out NSEC3", errDNSSECUnsigned, desc)
+	}
+
+	return fmt.Errorf("%w: %s: records do not prove nonexistence", errDNSSECBogus, desc)
+}
+
+// nsecDenies returns true if the NSEC records prove that there are no records
+// of the given type at the name.
+func nsecDenies(nsecs []*dns.NSEC, name string, qtype uint16) bool {
+	name = dns.CanonicalName(name)
+	for _, nsec := range nsecs {
+		owner := dns.CanonicalName(nsec.Hdr.Name)
+		next := dns.CanonicalName(nsec.NextDomain)
+		if owner == name {
+			// The name exists, so the type must not.  An NSEC at a
+			// delegation comes from the parent zone and says nothing about
+			// the records in the child zone.
+			if isDelegation(nsec.TypeBitMap) && qtype != dns.TypeDS {
+				continue
+			}
+			if !hasType(nsec.TypeBitMap, qtype) && !hasType(nsec.TypeBitMap, dns.TypeCNAME) {
+				return true
+			}
+			continue
+		}
+		// The name does not exist if it falls between the owner and the
+		// next name.  The last NSEC in a zone wraps round to the apex.
+		if canonicalCompare(owner, name) >= 0 {
+			continue
+		}
+		if canonicalCompare(name, next) < 0 || (canonicalCompare(next, owner) <= 0 && dns.IsSubDomain(next, name)) {
+			if dns.IsSubDomain(owner, name) && isDelegation(nsec.TypeBitMap) {
+				continue
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// nsec3Denies returns true if the NSEC3 records prove that there are no
+// records of the given type at the name.  It also returns true if the proof
+// relies on an opt-out NSEC3, in which case the name could exist in an
+// unsigned delegation.
+func nsec3Denies(nsec3s []*dns.NSEC3, name string, qtype uint16) (bool, bool) {
+	name = dns.CanonicalName(name)
+	for _, nsec3 := range nsec3s {
+		if !nsec3.Match(name) {
+			continue
+		}
+		if isDelegation(nsec3.TypeBitMap) && qtype != dns.TypeDS {
+			continue
+		}
+		if !hasType(nsec3.TypeBitMap, qtype) && !hasType(nsec3.TypeBitMap, dns.TypeCNAME) {
+			return true, false
+		}
+	}
+
+	// The name does not exist if its closest existing ancestor is matched and
+	// the name one label below that ancestor is covered.
+	indices := dns.Split(name)
+	for i := 1; i < len(indices); i++ {
+		closestEncloser := name[indices[i]:]
+		nextCloser := name[indices[i-1]:]
+		matched := false
+		for _, nsec3 := range nsec3s {
+			if nsec3.Match(closestEncloser) && !isDelegation(nsec3.TypeBitMap) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+		for _, nsec3 := range nsec3s {
+			if nsec3.Cover(nextCloser) {
+				if nsec3.Flags&nsec3OptOut != 0 {
+					return false, true
+				}
+				return true, false
+			}
+		}
+		return false, false
+	}
+	return false, false
+}
+
+// nsec3OptOut is the NSEC3 flag for opt-out.
+const nsec3OptOut = 0x01
+
+// isDelegation returns true if the type bitmap is for a delegation to a
+// child zone.
+func isDelegation(types []uint16) bool {
+	return hasType(types, dns.TypeNS) && !hasType(types, dns.TypeSOA)
+}
+
+// hasType returns true if the type bitmap contains the type.
+func hasType(types []uint16, rrtype uint16) bool {
+	for _, t := range types {
+		if t == rrtype {
+			return true
+		}
+	}
+	return false
+}
+
+// canonicalCompare compares two names in canonical DNS order, returning -1,
+// 0 or 1.
+func canonicalCompare(a string, b string) int {
+	aLabels := dns.SplitDomainName(dns.CanonicalName(a))
+	bLabels := dns.SplitDomainName(dns.CanonicalName(b))
+	for i := 1; i <= len(aLabels) && i <= len(bLabels); i++ {
+		if c := strings.Compare(aLabels[len(aLabels)-i], bLabels[len(bLabels)-i]); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case len(aLabels) < len(bLabels):
+		return -1
+	case len(aLabels) > len(bLabels):
+		return 1
+	default:
+		return 0
+	}
+}
+
+// verifyRRset verifies an RRset against the keys of the zone that signed it.
+func (v *dnssecValidator) verifyRRset(ctx context.Context,
+	rrset []dns.RR,
+	sigs []*dns.RRSIG,
+	zoneKeys map[string][]*dns.DNSKEY,
+) error {
+	name := rrset[0].Header().Name
+	rrtype := rrset[0].Header().Rrtype
+	desc := fmt.Sprintf("%s %s", name, dns.TypeToString[rrtype])
+
+	var err error
+	signed := false
+	for _, sig := range sigs {
+		if sig.TypeCovered != rrtype || !strings.EqualFold(sig.Hdr.Name, name) {
+			continue
+		}
+		signed = true
+		if !dns.IsSubDomain(sig.SignerName, name) {
+			err = fmt.Errorf("signer %s is not an ancestor", sig.SignerName)
+			continue
+		}
+		if int(sig.Labels) < dns.CountLabel(name) {
+			err = errors.New("wildcard expansion is not supported")
+			continue
+		}
+		keys, exists := zoneKeys[sig.SignerName]
+		if !exists {
+			keys, err = v.zoneKeys(ctx, sig.SignerName, zoneKeys)
+			if err != nil {
+				return err
+			}
+		}
+		if err = v.verifySignature(sig, rrset, keys); err == nil {
+			log.Trace().Str("rrset", desc).Str("signer", sig.SignerName).Msg("Validated RRset")
+			return nil
+		}
+	}
+	if !signed {
+		return fmt.Errorf("%w: %s", errDNSSECUnsigned, desc)
+	}
+
+	return fmt.Errorf("%w: %s: %v", errDNSSECBogus, desc, err)
+}
+
+// verifySignature verifies a single signature over an RRset with any of the
+// supplied keys.
+func (v *dnssecValidator) verifySignature(sig *dns.RRSIG, rrset []dns.RR, keys []*dns.DNSKEY) error {
+	if !sig.ValidityPeriod(v.now()) {
+		return errors.New("signature is outside its validity period")
+	}
+	err := errors.New("no key matches signature")
+	for _, key := range keys {
+		if key.KeyTag() != sig.KeyTag || key.Algorithm != sig.Algorithm {
+			continue
+		}
+		if err = sig.Verify(key, rrset); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// zoneKeys obtains the validated DNSKEYs for a zone, validating its parent
+// zones as required.
+func (v *dnssecValidator) zoneKeys(ctx context.Context, zone string, zoneKeys map[string][]*dns.DNSKEY) ([]*dns.DNSKEY, error) {
+	zone = dns.CanonicalName(zone)
+	if keys, exists := zoneKeys[zone]; exists {
+		return keys, nil
+	}
+
+	// Obtain the DS records that secure the zone.
+	var dsRRs []*dns.DS
+	if zone == "." {
+		dsRRs = v.anchors
+	} else {
+		r, err := v.client.exchange(ctx, newQuery(zone, dns.TypeDS, true))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to obtain DS records for %s", zone)
+		}
+		rrsets, sigs := splitRRsets(r.Answer)
+		for _, rrset := range rrsets {
+			if rrset[0].Header().Rrtype != dns.TypeDS || !strings.EqualFold(rrset[0].Header().Name, zone) {
+				continue
+			}
+			for _, sig := range sigs {
+				// The DS RRset must be signed by a parent zone.
+				if sig.TypeCovered == dns.TypeDS && dns.CanonicalName(sig.SignerName) == zone {
+					return nil, fmt.Errorf("%w: %s DS: signed by the zone itself", errDNSSECBogus, zone)
+				}
+			}
+			if err := v.verifyRRset(ctx, rrset, sigs, zoneKeys); err != nil {
+				return nil, err
+			}
+			for _, rr := range rrset {
+				dsRRs = append(dsRRs, rr.(*dns.DS))
+			}
+		}
+		if len(dsRRs) == 0 {
+			return nil, fmt.Errorf("%w: %s: insecure delegation", errDNSSECUnsigned, zone)
+		}
+	}
+
+	// Obtain the DNSKEYs for the zone.
+	r, err := v.client.exchange(ctx, newQuery(zone, dns.TypeDNSKEY, true))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to obtain DNSKEY records for %s", zone)
+	}
+	keyRRs := make([]dns.RR, 0)
+	keys := make([]*dns.DNSKEY, 0)
+	sigs := make([]*dns.RRSIG, 0)
+	for _, rr := range r.Answer {
+		switch rr := rr.(type) {
+		case *dns.DNSKEY:
+			if strings.EqualFold(rr.Hdr.Name, zone) {
+				keyRRs = append(keyRRs, rr)
+				keys = append(keys, rr)
+			}
+		case *dns.RRSIG:
+			if rr.TypeCovered == dns.TypeDNSKEY && dns.CanonicalName(rr.SignerName) == zone {
+				sigs = append(sigs, rr)
+			}
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%w: %s DNSKEY: no keys for secure zone", errDNSSECBogus, zone)
+	}
+
+	// The DNSKEY RRset must be signed by a key that matches a DS record.
+	entryKeys := make([]*dns.DNSKEY, 0)
+	for _, key := range keys {
+		for _, ds := range dsRRs {
+			if key.KeyTag() != ds.KeyTag || key.Algorithm != ds.Algorithm {
+				continue
+			}
+			keyDS := key.ToDS(ds.DigestType)
+			if keyDS != nil && strings.EqualFold(keyDS.Digest, ds.Digest) {
+				entryKeys = append(entryKeys, key)
+				break
+			}
+		}
+	}
+	if len(entryKeys) == 0 {
+		return nil, fmt.Errorf("%w: %s DNSKEY: no key matches DS records", errDNSSECBogus, zone)
+	}
+	if len(sigs) == 0 {
+		return nil, fmt.Errorf("%w: %s DNSKEY: not signed", errDNSSECBogus, zone)
+	}
+	err = errors.New("no signature")
+	for _, sig := range sigs {
+		if err = v.verifySignature(sig, keyRRs, entryKeys); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s DNSKEY: %v", errDNSSECBogus, zone, err)
+	}
+	log.Trace().Str("zone", zone).Int("keys", len(keys)).Msg("Validated zone keys")
+
+	zoneKeys[zone] = keys
+	return keys, nil
+}
+
+// splitRRsets splits records in to RRsets by name and type, and returns
+// them along with the signatures.
+func splitRRsets(rrs []dns.RR) ([][]dns.RR, []*dns.RRSIG) {
+	rrsets := make([][]dns.RR, 0)
+	index := make(map[string]int)
+	sigs := make([]*dns.RRSIG, 0)
+	for _, rr := range rrs {
+		if sig, isSig := rr.(*dns.RRSIG); isSig {
+			sigs = append(sigs, sig)
+			continue
+		}
+		key := fmt.Sprintf("%s/%d", dns.CanonicalName(rr.Header().Name), rr.Header().Rrtype)
+		if i, exists := index[key]; exists {
+			rrsets[i] = append(rrsets[i], rr)
+			continue
+		}
+		index[key] = len(rrsets)
+		rrsets = append(rrsets, []dns.RR{rr})
+	}
+	return rrsets, sigs
+}
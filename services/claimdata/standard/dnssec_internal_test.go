@@ -0,0 +1,504 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package standard
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+	mockauditlog "github.com/wealdtech/edcd/services/auditlog/mock"
+	mockens "github.com/wealdtech/edcd/services/ens/mock"
+	mocksigningguard "github.com/wealdtech/edcd/services/signingguard/mock"
+)
+
+// testZone is a DNSSEC-signed zone used in tests.
+type testZone struct {
+	name string
+	key  *dns.DNSKEY
+	priv crypto.Signer
+}
+
+func newTestZone(t *testing.T, name string) *testZone {
+	key := &dns.DNSKEY{
+		Hdr:       dns.RR_Header{Name: name, Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: 3600},
+		Flags:     257,
+		Protocol:  3,
+		Algorithm: dns.ECDSAP256SHA256,
+	}
+	priv, err := key.Generate(256)
+	require.NoError(t, err)
+	return &testZone{
+		name: name,
+		key:  key,
+		priv: priv.(crypto.Signer),
+	}
+}
+
+// sign signs an RRset with the zone's key.
+func (z *testZone) sign(t *testing.T, rrset []dns.RR, inception time.Time, expiration time.Time) *dns.RRSIG {
+	sig := &dns.RRSIG{
+		Hdr:        dns.RR_Header{Name: rrset[0].Header().Name, Rrtype: dns.TypeRRSIG, Class: dns.ClassINET, Ttl: 3600},
+		Algorithm:  z.key.Algorithm,
+		Inception:  uint32(inception.Unix()),
+		Expiration: uint32(expiration.Unix()),
+		KeyTag:     z.key.KeyTag(),
+		SignerName: z.name,
+	}
+	require.NoError(t, sig.Sign(z.priv, rrset))
+	return sig
+}
+
+// testChainOptions alter the signed chain served in tests.
+type testChainOptions struct {
+	unsignedTXT bool
+	tamperedTXT bool
+	expiredTXT  bool
+	noDS        bool
+	wrongDS     bool
+	wrongAnchor bool
+	missingTXT  bool
+	noDenial    bool
+	wrongName   bool
+}
+
+// startDNSSECServer starts a DNS server for a signed chain from the root to
+// example.com, returning its address and the trust anchors for the chain.
+func startDNSSECServer(t *testing.T, opts testChainOptions) (string, []string) {
+	now := time.Now()
+	inception := now.Add(-time.Hour)
+	expiration := now.Add(time.Hour)
+
+	root := newTestZone(t, ".")
+	com := newTestZone(t, "com.")
+	example := newTestZone(t, "example.com.")
+
+	records := make(map[string][]dns.RR)
+	add := func(zone *testZone, rrset []dns.RR, sigInception time.Time, sigExpiration time.Time, signed bool) {
+		key := fmt.Sprintf("%s/%d", rrset[0].Header().Name, rrset[0].Header().Rrtype)
+		records[key] = append(records[key], rrset...)
+		if signed {
+			records[key] = append(records[key], zone.sign(t, rrset, sigInception, sigExpiration))
+		}
+	}
+
+	for _, zone := range []*testZone{root, com, example} {
+		add(zone, []dns.RR{zone.key}, inception, expiration, true)
+	}
+	add(root, []dns.RR{com.key.ToDS(dns.SHA256)}, inception, expiration, true)
+	if !opts.noDS {
+		ds := example.key.ToDS(dns.SHA256)
+		if opts.wrongDS {
+			ds = newTestZone(t, "example.com.").key.ToDS(dns.SHA256)
+		}
+		add(com, []dns.RR{ds}, inception, expiration, true)
+	}
+
+	txt := &dns.TXT{
+		Hdr: dns.RR_Header{Name: "example.com.", Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: 300},
+		Txt: []string{"a=0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5"},
+	}
+	txtExpiration := expiration
+	if opts.expiredTXT {
+		txtExpiration = now.Add(-time.Minute)
+	}
+	apexTypes := []uint16{dns.TypeRRSIG, dns.TypeNSEC, dns.TypeDNSKEY}
+	if !opts.missingTXT {
+		add(example, []dns.RR{txt}, inception, txtExpiration, !opts.unsignedTXT)
+		apexTypes = []uint16{dns.TypeTXT, dns.TypeRRSIG, dns.TypeNSEC, dns.TypeDNSKEY}
+	}
+	if opts.tamperedTXT {
+		txt.Txt = []string{"a=0x0102030405060708090a0b0c0d0e0f1011121314"}
+	}
+
+	// alias.example.com is a CNAME for example.com.
+	cname := &dns.CNAME{
+		Hdr:    dns.RR_Header{Name: "alias.example.com.", Rrtype: dns.TypeCNAME, Class: dns.ClassINET, Ttl: 300},
+		Target: "example.com.",
+	}
+	add(example, []dns.RR{cname}, inception, expiration, true)
+	records["alias.example.com./16"] = append(records["alias.example.com./5"], records["example.com./16"]...)
+
+	// The NSEC chain for example.com proves that no other names exist.
+	apexNSEC := &dns.NSEC{
+		Hdr:        dns.RR_Header{Name: "example.com.", Rrtype: dns.TypeNSEC, Class: dns.ClassINET, Ttl: 300},
+		NextDomain: "alias.example.com.",
+		TypeBitMap: apexTypes,
+	}
+	aliasNSEC := &dns.NSEC{
+		Hdr:        dns.RR_Header{Name: "alias.example.com.", Rrtype: dns.TypeNSEC, Class: dns.ClassINET, Ttl: 300},
+		NextDomain: "example.com.",
+		TypeBitMap: []uint16{dns.TypeCNAME, dns.TypeRRSIG, dns.TypeNSEC},
+	}
+	apexDenial := []dns.RR{apexNSEC, example.sign(t, []dns.RR{apexNSEC}, inception, expiration)}
+	aliasDenial := []dns.RR{aliasNSEC, example.sign(t, []dns.RR{aliasNSEC}, inception, expiration)}
+
+	handler := func(w dns.ResponseWriter, req *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(req)
+		name := req.Question[0].Name
+		if opts.wrongName && req.Question[0].Qtype == dns.TypeTXT {
+			// Answer with the records of another name.
+			name = "example.com."
+		}
+		m.Answer = records[fmt.Sprintf("%s/%d", name, req.Question[0].Qtype)]
+		if len(m.Answer) == 0 && !opts.noDenial && dns.IsSubDomain("example.com.", req.Question[0].Name) {
+			m.Ns = apexDenial
+			if canonicalCompare(req.Question[0].Name, "alias.example.com.") >= 0 {
+				m.Ns = aliasDenial
+			}
+		}
+		_ = w.WriteMsg(m)
+	}
+	address := startDNSServer(t, handler, handler)
+
+	anchor := root.key.ToDS(dns.SHA256)
+	if opts.wrongAnchor {
+		anchor = newTestZone(t, ".").key.ToDS(dns.SHA256)
+	}
+
+	return address, []string{anchor.String()}
+}
+
+func TestNewDNSSECValidator(t *testing.T) {
+	tests := []struct {
+		name    string
+		anchors []string
+		err     string
+	}{
+		{
+			name: "Nil",
+			err:  "no trust anchors supplied",
+		},
+		{
+			name:    "Invalid",
+			anchors: []string{"invalid"},
+			err:     "invalid trust anchor invalid: dns: not a TTL: \"invalid\" at line: 1:7",
+		},
+		{
+			name:    "NotDS",
+			anchors: []string{". IN TXT \"anchor\""},
+			err:     "trust anchor . IN TXT \"anchor\" is not a DS record for the root zone",
+		},
+		{
+			name:    "NotRoot",
+			anchors: []string{"com. IN DS 20326 8 2 E06D44B80B8F1D39A95C0B0D7C65D08458E880409BBC683457104237C7F8EC8D"},
+			err:     "trust anchor com. IN DS 20326 8 2 E06D44B80B8F1D39A95C0B0D7C65D08458E880409BBC683457104237C7F8EC8D is not a DS record for the root zone",
+		},
+		{
+			name:    "Default",
+			anchors: defaultTrustAnchors,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			validator, err := newDNSSECValidator(&dnsClient{}, test.anchors)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+			} else {
+				require.NoError(t, err)
+				require.Len(t, validator.anchors, len(test.anchors))
+			}
+		})
+	}
+}
+
+func TestOwnerForDomainDNSSEC(t *testing.T) {
+	tests := []struct {
+		name          string
+		opts          testChainOptions
+		domain        string
+		requireDNSSEC bool
+		address       common.Address
+		unsigned      bool
+		bogus         bool
+		err           string
+	}{
+		{
+			name:          "Good",
+			requireDNSSEC: true,
+			address:       common.HexToAddress("0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5"),
+		},
+		{
+			name:          "UnsignedTXT",
+			opts:          testChainOptions{unsignedTXT: true},
+			requireDNSSEC: true,
+			unsigned:      true,
+			err:           "failed to validate owner record for example.com: unsigned DNSSEC response: example.com. TXT",
+		},
+		{
+			name:          "UnsignedTXTNotRequired",
+			opts:          testChainOptions{unsignedTXT: true},
+			requireDNSSEC: false,
+			address:       common.HexToAddress("0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5"),
+		},
+		{
+			name:          "InsecureDelegation",
+			opts:          testChainOptions{noDS: true},
+			requireDNSSEC: true,
+			unsigned:      true,
+			err:           "failed to validate owner record for example.com: unsigned DNSSEC response: example.com.: insecure delegation",
+		},
+		{
+			name:          "TamperedTXT",
+			opts:          testChainOptions{tamperedTXT: true},
+			requireDNSSEC: true,
+			bogus:         true,
+		},
+		{
+			name:          "TamperedTXTNotRequired",
+			opts:          testChainOptions{tamperedTXT: true},
+			requireDNSSEC: false,
+			address:       common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314"),
+		},
+		{
+			name:          "ExpiredTXT",
+			opts:          testChainOptions{expiredTXT: true},
+			requireDNSSEC: true,
+			bogus:         true,
+			err:           "failed to validate owner record for example.com: bogus DNSSEC response: example.com. TXT: signature is outside its validity period",
+		},
+		{
+			name:          "WrongDS",
+			opts:          testChainOptions{wrongDS: true},
+			requireDNSSEC: true,
+			bogus:         true,
+			err:           "failed to validate owner record for example.com: bogus DNSSEC response: example.com. DNSKEY: no key matches DS records",
+		},
+		{
+			name:          "WrongAnchor",
+			opts:          testChainOptions{wrongAnchor: true},
+			requireDNSSEC: true,
+			bogus:         true,
+			err:           "failed to validate owner record for example.com: bogus DNSSEC response: . DNSKEY: no key matches DS records",
+		},
+		{
+			name:          "CNAME",
+			domain:        "alias.example.com",
+			requireDNSSEC: true,
+			address:       common.HexToAddress("0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5"),
+		},
+		{
+			name:          "WrongName",
+			opts:          testChainOptions{wrongName: true},
+			domain:        "victim.com",
+			requireDNSSEC: true,
+			unsigned:      true,
+			err:           "failed to validate owner record for victim.com: unsigned DNSSEC response: victim.com. TXT: no proof of nonexistence",
+		},
+		{
+			name:          "MissingTXT",
+			opts:          testChainOptions{missingTXT: true},
+			requireDNSSEC: true,
+			err:           "no owner found for domain example.com",
+		},
+		{
+			name:          "MissingTXTUnproven",
+			opts:          testChainOptions{missingTXT: true, noDenial: true},
+			requireDNSSEC: true,
+			unsigned:      true,
+			err:           "failed to validate owner record for example.com: unsigned DNSSEC response: example.com. TXT: no proof of nonexistence",
+		},
+		{
+			name:          "MissingTXTNotRequired",
+			opts:          testChainOptions{missingTXT: true, noDenial: true},
+			requireDNSSEC: false,
+			err:           "no owner found for domain example.com",
+		},
+	}
+
+	ctx := context.Background()
+	dcs := map[string]interface{}{
+		"com": map[string]interface{}{
+			"owner-address": "0x1a642f0E3c3aF545E7AcBD38b07251B3990914F1",
+			"passphrase":    map[string]interface{}{"file": "testdata/passphrase"},
+			"keystore":      "testdata/keystore",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			address, anchors := startDNSSECServer(t, test.opts)
+			s, err := New(ctx,
+				WithTimeout(5*time.Second),
+				WithDomainControls(dcs),
+				WithENS(mockens.New()),
+				WithSigningGuard(mocksigningguard.New()),
+				WithAuditLog(mockauditlog.New()),
+				WithResolvers([]string{address}),
+				WithTrustAnchors(anchors),
+			)
+			require.NoError(t, err)
+
+			domain := test.domain
+			if domain == "" {
+				domain = "example.com"
+			}
+			res, err := s.ownerForDomain(ctx, &domainControl{Domain: domain, RequireDNSSEC: test.requireDNSSEC}, domain)
+			switch {
+			case test.unsigned || test.bogus:
+				require.Error(t, err)
+				require.Equal(t, test.unsigned, errors.Is(err, errDNSSECUnsigned))
+				require.Equal(t, test.bogus, errors.Is(err, errDNSSECBogus))
+				if test.err != "" {
+					require.EqualError(t, err, test.err)
+				}
+			case test.err != "":
+				require.EqualError(t, err, test.err)
+			default:
+				require.NoError(t, err)
+				require.Equal(t, test.address, res.address)
+			}
+		})
+	}
+}
+
+func TestNSECDenies(t *testing.T) {
+	nsec := func(owner string, next string, types ...uint16) *dns.NSEC {
+		return &dns.NSEC{
+			Hdr:        dns.RR_Header{Name: owner, Rrtype: dns.TypeNSEC, Class: dns.ClassINET, Ttl: 300},
+			NextDomain: next,
+			TypeBitMap: types,
+		}
+	}
+
+	tests := []struct {
+		name   string
+		nsecs  []*dns.NSEC
+		qname  string
+		denied bool
+	}{
+		{
+			name:   "NoData",
+			nsecs:  []*dns.NSEC{nsec("example.com.", "a.example.com.", dns.TypeSOA, dns.TypeRRSIG, dns.TypeNSEC)},
+			qname:  "example.com.",
+			denied: true,
+		},
+		{
+			name:  "NoDataTypePresent",
+			nsecs: []*dns.NSEC{nsec("example.com.", "a.example.com.", dns.TypeSOA, dns.TypeTXT, dns.TypeRRSIG, dns.TypeNSEC)},
+			qname: "example.com.",
+		},
+		{
+			name:  "NoDataCNAME",
+			nsecs: []*dns.NSEC{nsec("a.example.com.", "b.example.com.", dns.TypeCNAME, dns.TypeRRSIG, dns.TypeNSEC)},
+			qname: "a.example.com.",
+		},
+		{
+			name:  "NoDataDelegation",
+			nsecs: []*dns.NSEC{nsec("sub.example.com.", "tub.example.com.", dns.TypeNS, dns.TypeDS, dns.TypeRRSIG, dns.TypeNSEC)},
+			qname: "sub.example.com.",
+		},
+		{
+			name:   "Covered",
+			nsecs:  []*dns.NSEC{nsec("a.example.com.", "c.example.com.", dns.TypeTXT, dns.TypeRRSIG, dns.TypeNSEC)},
+			qname:  "B.example.com.",
+			denied: true,
+		},
+		{
+			name:  "NotCovered",
+			nsecs: []*dns.NSEC{nsec("a.example.com.", "c.example.com.", dns.TypeTXT, dns.TypeRRSIG, dns.TypeNSEC)},
+			qname: "d.example.com.",
+		},
+		{
+			name:   "CoveredWrap",
+			nsecs:  []*dns.NSEC{nsec("y.example.com.", "example.com.", dns.TypeTXT, dns.TypeRRSIG, dns.TypeNSEC)},
+			qname:  "z.example.com.",
+			denied: true,
+		},
+		{
+			name:  "CoveredWrapOutOfZone",
+			nsecs: []*dns.NSEC{nsec("y.example.com.", "example.com.", dns.TypeTXT, dns.TypeRRSIG, dns.TypeNSEC)},
+			qname: "victim.com.",
+		},
+		{
+			name:  "BelowDelegation",
+			nsecs: []*dns.NSEC{nsec("sub.example.com.", "tub.example.com.", dns.TypeNS, dns.TypeDS, dns.TypeRRSIG, dns.TypeNSEC)},
+			qname: "a.sub.example.com.",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.denied, nsecDenies(test.nsecs, test.qname, dns.TypeTXT))
+		})
+	}
+}
+
+func TestNSEC3Denies(t *testing.T) {
+	// A single NSEC3 for the apex matches the apex and covers every other
+	// name in the zone.
+	nsec3 := func(flags uint8, types ...uint16) *dns.NSEC3 {
+		hash := dns.HashName("example.com.", dns.SHA1, 0, "")
+		return &dns.NSEC3{
+			Hdr:        dns.RR_Header{Name: strings.ToLower(hash) + ".example.com.", Rrtype: dns.TypeNSEC3, Class: dns.ClassINET, Ttl: 300},
+			Hash:       dns.SHA1,
+			Flags:      flags,
+			NextDomain: hash,
+			TypeBitMap: types,
+		}
+	}
+
+	tests := []struct {
+		name   string
+		nsec3s []*dns.NSEC3
+		qname  string
+		denied bool
+		optOut bool
+	}{
+		{
+			name:   "NoData",
+			nsec3s: []*dns.NSEC3{nsec3(0, dns.TypeSOA, dns.TypeRRSIG, dns.TypeDNSKEY)},
+			qname:  "example.com.",
+			denied: true,
+		},
+		{
+			name:   "NoDataTypePresent",
+			nsec3s: []*dns.NSEC3{nsec3(0, dns.TypeSOA, dns.TypeTXT, dns.TypeRRSIG, dns.TypeDNSKEY)},
+			qname:  "example.com.",
+		},
+		{
+			name:   "NameError",
+			nsec3s: []*dns.NSEC3{nsec3(0, dns.TypeSOA, dns.TypeTXT, dns.TypeRRSIG, dns.TypeDNSKEY)},
+			qname:  "_ens.missing.example.com.",
+			denied: true,
+		},
+		{
+			name:   "NameErrorOptOut",
+			nsec3s: []*dns.NSEC3{nsec3(nsec3OptOut, dns.TypeSOA, dns.TypeTXT, dns.TypeRRSIG, dns.TypeDNSKEY)},
+			qname:  "missing.example.com.",
+			optOut: true,
+		},
+		{
+			name:   "OutOfZone",
+			nsec3s: []*dns.NSEC3{nsec3(0, dns.TypeSOA, dns.TypeRRSIG, dns.TypeDNSKEY)},
+			qname:  "victim.com.",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			denied, optOut := nsec3Denies(test.nsec3s, test.qname, dns.TypeTXT)
+			require.Equal(t, test.denied, denied)
+			require.Equal(t, test.optOut, optOut)
+		})
+	}
+}
@@ -16,17 +16,76 @@ package standard
 import (
 	"encoding/hex"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/pkg/errors"
+	"github.com/wealdtech/edcd/services/signer"
+	"github.com/wealdtech/edcd/util/eip712"
 )
 
 // domainControl contains information about control of a domain.
 type domainControl struct {
-	Domain     string
-	Owner      common.Address
-	Passphrase string
+	Domain      string
+	Keys        []*signingKey
+	SigningMode string
+	EIP712      *eip712.Domain
+	// RequireDNSSEC requires the owner record to be validated with DNSSEC.
+	RequireDNSSEC bool
+	// OwnerRecords are the formats of owner record accepted.  Records in all
+	// formats must agree on the owner.  If not set the default formats are
+	// accepted.
+	OwnerRecords []ownerRecordFormat
+	// Lookup is the strategy for the name at which owner records are found.
+	Lookup string
+	// Cache allows owner records to be served from the DNS answer cache.
+	Cache bool
+	// ConsensusThreshold is the number of resolvers that must agree on the
+	// owner.  If 0 the first resolver to answer is used.
+	ConsensusThreshold uint
+}
+
+const (
+	// signingModeRegistrar signs the hash provided by the registrar contract.
+	signingModeRegistrar = "registrar"
+	// signingModeEIP712 signs EIP-712 typed data built locally.
+	signingModeEIP712 = "eip712"
+)
+
+const (
+	// lookupParent looks up owner records at the parent domain.
+	lookupParent = "parent"
+	// lookupFQDN looks up owner records at the requested name.
+	lookupFQDN = "fqdn"
+	// lookupENS looks up owner records at _ens.<requested name>.  Owner
+	// record formats that use a subdomain cannot be configured with this
+	// lookup, and default formats that use one are skipped.
+	lookupENS = "ens"
+)
+
+// signingKey contains information about a key that can sign claims for a domain.
+type signingKey struct {
+	Owner        common.Address
+	NotBefore    time.Time
+	NotAfter     time.Time
+	Current      bool
+	Passphrase   secretProvider
+	Keystore     string
+	SignerConfig *signerConfig
+	Signer       signer.Service
+}
+
+// signerConfig contains the configuration for the signer of a domain.
+type signerConfig struct {
+	Type       string
+	Endpoint   string
+	Library    string
+	Slot       *uint
+	TokenLabel string
+	KeyLabel   string
+	PIN        secretProvider
 }
 
 func parseDomainControls(dcs map[string]interface{}) (map[string]*domainControl, error) {
@@ -38,30 +97,393 @@ func parseDomainControls(dcs map[string]interface{}) (map[string]*domainControl,
 			return nil, fmt.Errorf("invalid configuration for %s", domain)
 		}
 
-		ownerAddress, exists := control["owner-address"].(string)
-		if !exists {
-			return nil, fmt.Errorf("owner-address missing for %s", domain)
+		keys := make([]*signingKey, 0)
+		if keysConfig, exists := control["keys"]; exists {
+			keysList, isList := keysConfig.([]interface{})
+			if !isList || len(keysList) == 0 {
+				return nil, fmt.Errorf("invalid keys for %s", domain)
+			}
+			for i := range keysList {
+				keyConfig, isMap := toStringMap(keysList[i])
+				if !isMap {
+					return nil, fmt.Errorf("invalid configuration for %s key %d", domain, i)
+				}
+				key, err := parseSigningKey(fmt.Sprintf("%s key %d", domain, i), keyConfig)
+				if err != nil {
+					return nil, err
+				}
+				keys = append(keys, key)
+			}
+		} else {
+			// A single key configured directly in the domain control.
+			key, err := parseSigningKey(domain, control)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, key)
 		}
-		owner, err := hex.DecodeString(strings.TrimPrefix(ownerAddress, "0x"))
-		if err != nil {
-			return nil, errors.Wrapf(err, "owner-address invalid for %s", domain)
+
+		currentKeys := 0
+		for _, key := range keys {
+			if key.Current {
+				currentKeys++
+			}
 		}
-		if len(owner) != 20 {
-			return nil, fmt.Errorf("incorrect owner-address length for %s", domain)
+		if currentKeys > 1 {
+			return nil, fmt.Errorf("multiple current keys for %s", domain)
 		}
-		address := common.BytesToAddress(owner)
 
-		passphrase, exists := control["passphrase"].(string)
-		if !exists {
-			return nil, fmt.Errorf("passphrase missing for %s", domain)
+		signingMode := signingModeRegistrar
+		if mode, exists := control["signing-mode"]; exists {
+			signingMode, exists = mode.(string)
+			if !exists {
+				return nil, fmt.Errorf("invalid signing-mode for %s", domain)
+			}
+		}
+		var eip712Domain *eip712.Domain
+		switch signingMode {
+		case signingModeRegistrar:
+		case signingModeEIP712:
+			if _, exists := control["eip712"]; !exists {
+				return nil, fmt.Errorf("eip712 missing for %s", domain)
+			}
+			var err error
+			eip712Domain, err = eip712.ParseDomain(control["eip712"])
+			if err != nil {
+				return nil, errors.Wrapf(err, "eip712 invalid for %s", domain)
+			}
+		default:
+			return nil, fmt.Errorf("unknown signing-mode %s for %s", signingMode, domain)
+		}
+
+		requireDNSSEC := false
+		if input, exists := control["require-dnssec"]; exists {
+			requireDNSSEC, exists = input.(bool)
+			if !exists {
+				return nil, fmt.Errorf("require-dnssec invalid for %s", domain)
+			}
+		}
+
+		var ownerRecords []ownerRecordFormat
+		if input, exists := control["owner-records"]; exists {
+			var err error
+			ownerRecords, err = parseOwnerRecordFormats(input)
+			if err != nil {
+				return nil, errors.Wrapf(err, "owner-records invalid for %s", domain)
+			}
+		}
+
+		lookup := lookupParent
+		if input, exists := control["lookup"]; exists {
+			lookup, exists = input.(string)
+			if !exists {
+				return nil, fmt.Errorf("invalid lookup for %s", domain)
+			}
+		}
+		switch lookup {
+		case lookupParent, lookupFQDN, lookupENS:
+		default:
+			return nil, fmt.Errorf("unknown lookup %s for %s", lookup, domain)
+		}
+		if lookup == lookupENS {
+			// The ens lookup already targets the _ens subdomain, so formats
+			// on a subdomain would never be found.
+			for _, format := range ownerRecords {
+				if format.subdomain() != "" {
+					return nil, fmt.Errorf("owner record format %s uses a subdomain so cannot be used with lookup %s for %s", format.name(), lookup, domain)
+				}
+			}
+		}
+
+		cache := true
+		if input, exists := control["cache"]; exists {
+			cache, exists = input.(bool)
+			if !exists {
+				return nil, fmt.Errorf("cache invalid for %s", domain)
+			}
+		}
+
+		var consensusThreshold uint
+		if input, exists := control["consensus-threshold"]; exists {
+			consensusThreshold, exists = parseUint(input)
+			if !exists {
+				return nil, fmt.Errorf("consensus-threshold invalid for %s", domain)
+			}
+		}
+
+		if signingMode == signingModeRegistrar {
+			// Clef only signs typed data or prefixed messages, so cannot
+			// sign the bare hash provided by the registrar.
+			for _, key := range keys {
+				if key.SignerConfig.Type == "clef" {
+					return nil, fmt.Errorf("clef signer requires signing-mode eip712 for %s", domain)
+				}
+			}
 		}
 
 		domainControls[domain] = &domainControl{
-			Domain:     domain,
-			Owner:      address,
-			Passphrase: passphrase,
+			Domain:             domain,
+			Keys:               keys,
+			SigningMode:        signingMode,
+			EIP712:             eip712Domain,
+			RequireDNSSEC:      requireDNSSEC,
+			OwnerRecords:       ownerRecords,
+			Lookup:             lookup,
+			Cache:              cache,
+			ConsensusThreshold: consensusThreshold,
 		}
 	}
 
 	return domainControls, nil
 }
+
+// ownerName returns the name at which the owner records for the given label
+// are found.
+func (dc *domainControl) ownerName(label string) string {
+	switch dc.Lookup {
+	case lookupFQDN:
+		return fmt.Sprintf("%s.%s", label, dc.Domain)
+	case lookupENS:
+		return fmt.Sprintf("_ens.%s.%s", label, dc.Domain)
+	default:
+		return dc.Domain
+	}
+}
+
+// parseSigningKey parses the configuration for a signing key.
+// name is used to identify the key in errors.
+func parseSigningKey(name string, control map[string]interface{}) (*signingKey, error) {
+	ownerAddress, exists := control["owner-address"].(string)
+	if !exists {
+		return nil, fmt.Errorf("owner-address missing for %s", name)
+	}
+	owner, err := hex.DecodeString(strings.TrimPrefix(ownerAddress, "0x"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "owner-address invalid for %s", name)
+	}
+	if len(owner) != 20 {
+		return nil, fmt.Errorf("incorrect owner-address length for %s", name)
+	}
+
+	notBefore, err := parseTime(control["not-before"])
+	if err != nil {
+		return nil, errors.Wrapf(err, "not-before invalid for %s", name)
+	}
+	notAfter, err := parseTime(control["not-after"])
+	if err != nil {
+		return nil, errors.Wrapf(err, "not-after invalid for %s", name)
+	}
+	if !notBefore.IsZero() && !notAfter.IsZero() && !notAfter.After(notBefore) {
+		return nil, fmt.Errorf("not-after must be after not-before for %s", name)
+	}
+
+	current := false
+	if input, exists := control["current"]; exists {
+		current, exists = input.(bool)
+		if !exists {
+			return nil, fmt.Errorf("current invalid for %s", name)
+		}
+	}
+
+	signerConfig, err := parseSignerConfig(control["signer"])
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid signer for %s", name)
+	}
+
+	var passphrase secretProvider
+	var keystore string
+	if signerConfig.Type == "keystore" {
+		if _, exists := control["passphrase"]; !exists {
+			return nil, fmt.Errorf("passphrase missing for %s", name)
+		}
+		passphrase, err = parseSecretProvider(control["passphrase"])
+		if err != nil {
+			return nil, errors.Wrapf(err, "passphrase invalid for %s", name)
+		}
+
+		keystore, exists = control["keystore"].(string)
+		if !exists {
+			return nil, fmt.Errorf("keystore missing for %s", name)
+		}
+	}
+
+	return &signingKey{
+		Owner:        common.BytesToAddress(owner),
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		Current:      current,
+		Passphrase:   passphrase,
+		Keystore:     keystore,
+		SignerConfig: signerConfig,
+	}, nil
+}
+
+// parseTime parses an optional RFC 3339 time from configuration.
+func parseTime(input interface{}) (time.Time, error) {
+	switch v := input.(type) {
+	case nil:
+		return time.Time{}, nil
+	case time.Time:
+		return v, nil
+	case string:
+		return time.Parse(time.RFC3339, v)
+	default:
+		return time.Time{}, errors.New("invalid time")
+	}
+}
+
+// toStringMap converts a configuration map to one keyed by strings.
+// Maps inside lists are not normalised by the configuration loader, so
+// may arrive with either string or interface keys.
+func toStringMap(input interface{}) (map[string]interface{}, bool) {
+	switch v := input.(type) {
+	case map[string]interface{}:
+		return v, true
+	case map[interface{}]interface{}:
+		res := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			keyStr, isString := key.(string)
+			if !isString {
+				return nil, false
+			}
+			res[keyStr] = value
+		}
+		return res, true
+	default:
+		return nil, false
+	}
+}
+
+// validAt returns true if the key is valid at the given time.
+func (k *signingKey) validAt(t time.Time) bool {
+	if !k.NotBefore.IsZero() && t.Before(k.NotBefore) {
+		return false
+	}
+	if !k.NotAfter.IsZero() && !t.Before(k.NotAfter) {
+		return false
+	}
+	return true
+}
+
+// signingKey selects the key to sign claims for the domain at the given time.
+// Of the keys valid at the time the current key is preferred, followed by the
+// key that most recently became valid.
+func (dc *domainControl) signingKey(t time.Time) (*signingKey, error) {
+	var selected *signingKey
+	for _, key := range dc.Keys {
+		if !key.validAt(t) {
+			continue
+		}
+		if key.Current {
+			return key, nil
+		}
+		if selected == nil || key.NotBefore.After(selected.NotBefore) {
+			selected = key
+		}
+	}
+	if selected == nil {
+		return nil, fmt.Errorf("no valid signing key for %s", dc.Domain)
+	}
+	return selected, nil
+}
+
+// parseSignerConfig parses the signer configuration for a domain control.
+// If no configuration is supplied the signer defaults to a local keystore.
+func parseSignerConfig(input interface{}) (*signerConfig, error) {
+	config := &signerConfig{
+		Type: "keystore",
+	}
+	if input == nil {
+		return config, nil
+	}
+
+	sc, isMap := toStringMap(input)
+	if !isMap {
+		return nil, errors.New("invalid configuration")
+	}
+	if signerType, exists := sc["type"].(string); exists {
+		config.Type = signerType
+	}
+	config.Endpoint, _ = sc["endpoint"].(string)
+
+	switch config.Type {
+	case "keystore":
+	case "clef":
+		if config.Endpoint == "" {
+			return nil, errors.New("endpoint missing")
+		}
+	case "pkcs11":
+		var exists bool
+		config.Library, exists = sc["library"].(string)
+		if !exists {
+			return nil, errors.New("library missing")
+		}
+		config.KeyLabel, exists = sc["key-label"].(string)
+		if !exists {
+			return nil, errors.New("key-label missing")
+		}
+		if _, exists := sc["pin"]; !exists {
+			return nil, errors.New("pin missing")
+		}
+		var err error
+		config.PIN, err = parseSecretProvider(sc["pin"])
+		if err != nil {
+			return nil, errors.Wrap(err, "pin invalid")
+		}
+		// Slot IDs are assigned by the token, so the token must be selected
+		// explicitly by either its slot or its label.
+		slot, slotExists := sc["slot"]
+		tokenLabel, tokenLabelExists := sc["token-label"]
+		switch {
+		case slotExists && tokenLabelExists:
+			return nil, errors.New("only one of slot or token-label may be supplied")
+		case slotExists:
+			config.Slot, err = parseSlot(slot)
+			if err != nil {
+				return nil, err
+			}
+		case tokenLabelExists:
+			config.TokenLabel, _ = tokenLabel.(string)
+			if config.TokenLabel == "" {
+				return nil, errors.New("token-label invalid")
+			}
+		default:
+			return nil, errors.New("slot or token-label missing")
+		}
+	default:
+		return nil, fmt.Errorf("unknown type %s", config.Type)
+	}
+
+	return config, nil
+}
+
+// parseSlot parses a PKCS#11 slot from configuration.
+func parseSlot(input interface{}) (*uint, error) {
+	slot, valid := parseUint(input)
+	if !valid {
+		return nil, errors.New("slot invalid")
+	}
+	return &slot, nil
+}
+
+// parseUint parses an unsigned integer from configuration.
+func parseUint(input interface{}) (uint, bool) {
+	switch v := input.(type) {
+	case int:
+		if v >= 0 {
+			return uint(v), true
+		}
+	case uint:
+		return v, true
+	case float64:
+		if v >= 0 && v == float64(uint(v)) {
+			return uint(v), true
+		}
+	case string:
+		value, err := strconv.ParseUint(v, 10, 32)
+		if err == nil {
+			return uint(value), true
+		}
+	}
+	return 0, false
+}
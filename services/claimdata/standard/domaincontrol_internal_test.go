@@ -14,13 +14,18 @@
 package standard
 
 import (
+	"math/big"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/stretchr/testify/require"
+	"github.com/wealdtech/edcd/util/eip712"
 )
 
 func TestParseDomainControls(t *testing.T) {
+	slot := uint(1234)
+
 	tests := []struct {
 		name     string
 		dcs      map[string]interface{}
@@ -63,6 +68,158 @@ func TestParseDomainControls(t *testing.T) {
 			},
 			err: "incorrect owner-address length for wealdtech.eth",
 		},
+		{
+			name: "SignerInvalid",
+			dcs: map[string]interface{}{
+				"wealdtech.eth": map[string]interface{}{
+					"owner-address": "0x000102030405060708090a0b0c0d0e0f10111213",
+					"signer":        "invalid",
+				},
+			},
+			err: "invalid signer for wealdtech.eth: invalid configuration",
+		},
+		{
+			name: "SignerTypeUnknown",
+			dcs: map[string]interface{}{
+				"wealdtech.eth": map[string]interface{}{
+					"owner-address": "0x000102030405060708090a0b0c0d0e0f10111213",
+					"signer": map[string]interface{}{
+						"type": "unknown",
+					},
+				},
+			},
+			err: "invalid signer for wealdtech.eth: unknown type unknown",
+		},
+		{
+			name: "ClefEndpointMissing",
+			dcs: map[string]interface{}{
+				"wealdtech.eth": map[string]interface{}{
+					"owner-address": "0x000102030405060708090a0b0c0d0e0f10111213",
+					"signer": map[string]interface{}{
+						"type": "clef",
+					},
+				},
+			},
+			err: "invalid signer for wealdtech.eth: endpoint missing",
+		},
+		{
+			name: "PKCS11LibraryMissing",
+			dcs: map[string]interface{}{
+				"wealdtech.eth": map[string]interface{}{
+					"owner-address": "0x000102030405060708090a0b0c0d0e0f10111213",
+					"signer": map[string]interface{}{
+						"type": "pkcs11",
+					},
+				},
+			},
+			err: "invalid signer for wealdtech.eth: library missing",
+		},
+		{
+			name: "PKCS11KeyLabelMissing",
+			dcs: map[string]interface{}{
+				"wealdtech.eth": map[string]interface{}{
+					"owner-address": "0x000102030405060708090a0b0c0d0e0f10111213",
+					"signer": map[string]interface{}{
+						"type":    "pkcs11",
+						"library": "/usr/lib/softhsm/libsofthsm2.so",
+					},
+				},
+			},
+			err: "invalid signer for wealdtech.eth: key-label missing",
+		},
+		{
+			name: "PKCS11PINMissing",
+			dcs: map[string]interface{}{
+				"wealdtech.eth": map[string]interface{}{
+					"owner-address": "0x000102030405060708090a0b0c0d0e0f10111213",
+					"signer": map[string]interface{}{
+						"type":      "pkcs11",
+						"library":   "/usr/lib/softhsm/libsofthsm2.so",
+						"key-label": "edcd",
+					},
+				},
+			},
+			err: "invalid signer for wealdtech.eth: pin missing",
+		},
+		{
+			name: "PKCS11PINPlaintext",
+			dcs: map[string]interface{}{
+				"wealdtech.eth": map[string]interface{}{
+					"owner-address": "0x000102030405060708090a0b0c0d0e0f10111213",
+					"signer": map[string]interface{}{
+						"type":      "pkcs11",
+						"library":   "/usr/lib/softhsm/libsofthsm2.so",
+						"key-label": "edcd",
+						"pin":       "1234",
+					},
+				},
+			},
+			err: "invalid signer for wealdtech.eth: pin invalid: plaintext secrets are not supported; use file, env or credential",
+		},
+		{
+			name: "PKCS11SlotMissing",
+			dcs: map[string]interface{}{
+				"wealdtech.eth": map[string]interface{}{
+					"owner-address": "0x000102030405060708090a0b0c0d0e0f10111213",
+					"signer": map[string]interface{}{
+						"type":      "pkcs11",
+						"library":   "/usr/lib/softhsm/libsofthsm2.so",
+						"key-label": "edcd",
+						"pin":       map[string]interface{}{"env": "EDCD_PIN"},
+					},
+				},
+			},
+			err: "invalid signer for wealdtech.eth: slot or token-label missing",
+		},
+		{
+			name: "PKCS11SlotAndTokenLabel",
+			dcs: map[string]interface{}{
+				"wealdtech.eth": map[string]interface{}{
+					"owner-address": "0x000102030405060708090a0b0c0d0e0f10111213",
+					"signer": map[string]interface{}{
+						"type":        "pkcs11",
+						"library":     "/usr/lib/softhsm/libsofthsm2.so",
+						"key-label":   "edcd",
+						"pin":         map[string]interface{}{"env": "EDCD_PIN"},
+						"slot":        "1234",
+						"token-label": "edcd",
+					},
+				},
+			},
+			err: "invalid signer for wealdtech.eth: only one of slot or token-label may be supplied",
+		},
+		{
+			name: "PKCS11TokenLabelInvalid",
+			dcs: map[string]interface{}{
+				"wealdtech.eth": map[string]interface{}{
+					"owner-address": "0x000102030405060708090a0b0c0d0e0f10111213",
+					"signer": map[string]interface{}{
+						"type":        "pkcs11",
+						"library":     "/usr/lib/softhsm/libsofthsm2.so",
+						"key-label":   "edcd",
+						"pin":         map[string]interface{}{"env": "EDCD_PIN"},
+						"token-label": true,
+					},
+				},
+			},
+			err: "invalid signer for wealdtech.eth: token-label invalid",
+		},
+		{
+			name: "PKCS11SlotInvalid",
+			dcs: map[string]interface{}{
+				"wealdtech.eth": map[string]interface{}{
+					"owner-address": "0x000102030405060708090a0b0c0d0e0f10111213",
+					"signer": map[string]interface{}{
+						"type":      "pkcs11",
+						"library":   "/usr/lib/softhsm/libsofthsm2.so",
+						"key-label": "edcd",
+						"pin":       map[string]interface{}{"env": "EDCD_PIN"},
+						"slot":      -1,
+					},
+				},
+			},
+			err: "invalid signer for wealdtech.eth: slot invalid",
+		},
 		{
 			name: "PassphraseMissing",
 			dcs: map[string]interface{}{
@@ -73,18 +230,641 @@ func TestParseDomainControls(t *testing.T) {
 			err: "passphrase missing for wealdtech.eth",
 		},
 		{
-			name: "Good",
+			name: "PassphrasePlaintext",
 			dcs: map[string]interface{}{
 				"wealdtech.eth": map[string]interface{}{
 					"owner-address": "0x000102030405060708090a0b0c0d0e0f10111213",
 					"passphrase":    "a secret",
 				},
 			},
+			err: "passphrase invalid for wealdtech.eth: plaintext secrets are not supported; use file, env or credential",
+		},
+		{
+			name: "KeystoreMissing",
+			dcs: map[string]interface{}{
+				"wealdtech.eth": map[string]interface{}{
+					"owner-address": "0x000102030405060708090a0b0c0d0e0f10111213",
+					"passphrase":    map[string]interface{}{"file": "testdata/passphrase"},
+				},
+			},
+			err: "keystore missing for wealdtech.eth",
+		},
+		{
+			name: "Good",
+			dcs: map[string]interface{}{
+				"wealdtech.eth": map[string]interface{}{
+					"owner-address": "0x000102030405060708090a0b0c0d0e0f10111213",
+					"passphrase":    map[string]interface{}{"file": "testdata/passphrase"},
+					"keystore":      "/path/to/keystore",
+				},
+			},
 			expected: map[string]*domainControl{
 				"wealdtech.eth": {
-					Domain:     "wealdtech.eth",
-					Owner:      common.HexToAddress("000102030405060708090a0b0c0d0e0f10111213"),
-					Passphrase: "a secret",
+					Domain:      "wealdtech.eth",
+					SigningMode: "registrar",
+					Lookup:      "parent",
+					Cache:       true,
+					Keys: []*signingKey{
+						{
+							Owner:      common.HexToAddress("000102030405060708090a0b0c0d0e0f10111213"),
+							Passphrase: &fileSecret{path: "testdata/passphrase"},
+							Keystore:   "/path/to/keystore",
+							SignerConfig: &signerConfig{
+								Type: "keystore",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "ClefRegistrar",
+			dcs: map[string]interface{}{
+				"wealdtech.eth": map[string]interface{}{
+					"owner-address": "0x000102030405060708090a0b0c0d0e0f10111213",
+					"signer":        map[string]interface{}{"type": "clef", "endpoint": "http://localhost:8550/"},
+				},
+			},
+			err: "clef signer requires signing-mode eip712 for wealdtech.eth",
+		},
+		{
+			name: "GoodClef",
+			dcs: map[string]interface{}{
+				"wealdtech.eth": map[string]interface{}{
+					"owner-address": "0x000102030405060708090a0b0c0d0e0f10111213",
+					"signer": map[string]interface{}{
+						"type":     "clef",
+						"endpoint": "http://localhost:8550/",
+					},
+					"signing-mode": "eip712",
+					"eip712": map[string]interface{}{
+						"name":               "ENS DNS claim",
+						"version":            "1",
+						"chain-id":           5,
+						"verifying-contract": "0x0102030405060708090a0b0c0d0e0f1011121314",
+					},
+				},
+			},
+			expected: map[string]*domainControl{
+				"wealdtech.eth": {
+					Domain:      "wealdtech.eth",
+					SigningMode: "eip712",
+					Lookup:      "parent",
+					Cache:       true,
+					EIP712: &eip712.Domain{
+						Name:              "ENS DNS claim",
+						Version:           "1",
+						ChainID:           big.NewInt(5),
+						VerifyingContract: common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314"),
+					},
+					Keys: []*signingKey{
+						{
+							Owner: common.HexToAddress("000102030405060708090a0b0c0d0e0f10111213"),
+							SignerConfig: &signerConfig{
+								Type:     "clef",
+								Endpoint: "http://localhost:8550/",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "GoodPKCS11",
+			dcs: map[string]interface{}{
+				"wealdtech.eth": map[string]interface{}{
+					"owner-address": "0x000102030405060708090a0b0c0d0e0f10111213",
+					"signer": map[string]interface{}{
+						"type":      "pkcs11",
+						"library":   "/usr/lib/softhsm/libsofthsm2.so",
+						"key-label": "edcd",
+						"pin":       map[string]interface{}{"env": "EDCD_PIN"},
+						"slot":      "1234",
+					},
+				},
+			},
+			expected: map[string]*domainControl{
+				"wealdtech.eth": {
+					Domain:      "wealdtech.eth",
+					SigningMode: "registrar",
+					Lookup:      "parent",
+					Cache:       true,
+					Keys: []*signingKey{
+						{
+							Owner: common.HexToAddress("000102030405060708090a0b0c0d0e0f10111213"),
+							SignerConfig: &signerConfig{
+								Type:     "pkcs11",
+								Library:  "/usr/lib/softhsm/libsofthsm2.so",
+								Slot:     &slot,
+								KeyLabel: "edcd",
+								PIN:      &envSecret{name: "EDCD_PIN"},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "SigningModeInvalid",
+			dcs: map[string]interface{}{
+				"wealdtech.eth": map[string]interface{}{
+					"owner-address": "0x000102030405060708090a0b0c0d0e0f10111213",
+					"signer":        map[string]interface{}{"type": "clef", "endpoint": "http://localhost:8550/"},
+					"signing-mode":  true,
+				},
+			},
+			err: "invalid signing-mode for wealdtech.eth",
+		},
+		{
+			name: "SigningModeUnknown",
+			dcs: map[string]interface{}{
+				"wealdtech.eth": map[string]interface{}{
+					"owner-address": "0x000102030405060708090a0b0c0d0e0f10111213",
+					"signer":        map[string]interface{}{"type": "clef", "endpoint": "http://localhost:8550/"},
+					"signing-mode":  "unknown",
+				},
+			},
+			err: "unknown signing-mode unknown for wealdtech.eth",
+		},
+		{
+			name: "EIP712Missing",
+			dcs: map[string]interface{}{
+				"wealdtech.eth": map[string]interface{}{
+					"owner-address": "0x000102030405060708090a0b0c0d0e0f10111213",
+					"signer":        map[string]interface{}{"type": "clef", "endpoint": "http://localhost:8550/"},
+					"signing-mode":  "eip712",
+				},
+			},
+			err: "eip712 missing for wealdtech.eth",
+		},
+		{
+			name: "EIP712Invalid",
+			dcs: map[string]interface{}{
+				"wealdtech.eth": map[string]interface{}{
+					"owner-address": "0x000102030405060708090a0b0c0d0e0f10111213",
+					"signer":        map[string]interface{}{"type": "clef", "endpoint": "http://localhost:8550/"},
+					"signing-mode":  "eip712",
+					"eip712":        map[string]interface{}{},
+				},
+			},
+			err: "eip712 invalid for wealdtech.eth: name missing",
+		},
+		{
+			name: "GoodEIP712",
+			dcs: map[string]interface{}{
+				"wealdtech.eth": map[string]interface{}{
+					"owner-address": "0x000102030405060708090a0b0c0d0e0f10111213",
+					"signer":        map[string]interface{}{"type": "clef", "endpoint": "http://localhost:8550/"},
+					"signing-mode":  "eip712",
+					"eip712": map[string]interface{}{
+						"name":               "ENS DNS claim",
+						"version":            "1",
+						"chain-id":           5,
+						"verifying-contract": "0x0102030405060708090a0b0c0d0e0f1011121314",
+					},
+				},
+			},
+			expected: map[string]*domainControl{
+				"wealdtech.eth": {
+					Domain:      "wealdtech.eth",
+					SigningMode: "eip712",
+					Lookup:      "parent",
+					Cache:       true,
+					EIP712: &eip712.Domain{
+						Name:              "ENS DNS claim",
+						Version:           "1",
+						ChainID:           big.NewInt(5),
+						VerifyingContract: common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314"),
+					},
+					Keys: []*signingKey{
+						{
+							Owner: common.HexToAddress("000102030405060708090a0b0c0d0e0f10111213"),
+							SignerConfig: &signerConfig{
+								Type:     "clef",
+								Endpoint: "http://localhost:8550/",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "RequireDNSSECInvalid",
+			dcs: map[string]interface{}{
+				"wealdtech.eth": map[string]interface{}{
+					"owner-address":  "0x000102030405060708090a0b0c0d0e0f10111213",
+					"signer":         map[string]interface{}{"type": "clef", "endpoint": "http://localhost:8550/"},
+					"require-dnssec": "yes",
+				},
+			},
+			err: "require-dnssec invalid for wealdtech.eth",
+		},
+		{
+			name: "GoodRequireDNSSEC",
+			dcs: map[string]interface{}{
+				"wealdtech.eth": map[string]interface{}{
+					"owner-address":  "0x000102030405060708090a0b0c0d0e0f10111213",
+					"passphrase":     map[string]interface{}{"file": "testdata/passphrase"},
+					"keystore":       "/path/to/keystore",
+					"require-dnssec": true,
+				},
+			},
+			expected: map[string]*domainControl{
+				"wealdtech.eth": {
+					Domain:        "wealdtech.eth",
+					SigningMode:   "registrar",
+					Lookup:        "parent",
+					Cache:         true,
+					RequireDNSSEC: true,
+					Keys: []*signingKey{
+						{
+							Owner:      common.HexToAddress("000102030405060708090a0b0c0d0e0f10111213"),
+							Passphrase: &fileSecret{path: "testdata/passphrase"},
+							Keystore:   "/path/to/keystore",
+							SignerConfig: &signerConfig{
+								Type: "keystore",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "OwnerRecordsInvalid",
+			dcs: map[string]interface{}{
+				"wealdtech.eth": map[string]interface{}{
+					"owner-address": "0x000102030405060708090a0b0c0d0e0f10111213",
+					"signer":        map[string]interface{}{"type": "clef", "endpoint": "http://localhost:8550/"},
+					"owner-records": []interface{}{"unknown"},
+				},
+			},
+			err: "owner-records invalid for wealdtech.eth: entry 0 format unknown unknown",
+		},
+		{
+			name: "GoodOwnerRecords",
+			dcs: map[string]interface{}{
+				"wealdtech.eth": map[string]interface{}{
+					"owner-address": "0x000102030405060708090a0b0c0d0e0f10111213",
+					"passphrase":    map[string]interface{}{"file": "testdata/passphrase"},
+					"keystore":      "/path/to/keystore",
+					"owner-records": []interface{}{"ens1", map[string]interface{}{"format": "prefix", "prefix": "owner="}},
+				},
+			},
+			expected: map[string]*domainControl{
+				"wealdtech.eth": {
+					Domain:      "wealdtech.eth",
+					SigningMode: "registrar",
+					Lookup:      "parent",
+					Cache:       true,
+					OwnerRecords: []ownerRecordFormat{
+						&ens1Format{},
+						&templateFormat{formatName: "prefix", prefix: "owner="},
+					},
+					Keys: []*signingKey{
+						{
+							Owner:      common.HexToAddress("000102030405060708090a0b0c0d0e0f10111213"),
+							Passphrase: &fileSecret{path: "testdata/passphrase"},
+							Keystore:   "/path/to/keystore",
+							SignerConfig: &signerConfig{
+								Type: "keystore",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "LookupInvalid",
+			dcs: map[string]interface{}{
+				"wealdtech.eth": map[string]interface{}{
+					"owner-address": "0x000102030405060708090a0b0c0d0e0f10111213",
+					"signer":        map[string]interface{}{"type": "clef", "endpoint": "http://localhost:8550/"},
+					"lookup":        1,
+				},
+			},
+			err: "invalid lookup for wealdtech.eth",
+		},
+		{
+			name: "LookupUnknown",
+			dcs: map[string]interface{}{
+				"wealdtech.eth": map[string]interface{}{
+					"owner-address": "0x000102030405060708090a0b0c0d0e0f10111213",
+					"signer":        map[string]interface{}{"type": "clef", "endpoint": "http://localhost:8550/"},
+					"lookup":        "label",
+				},
+			},
+			err: "unknown lookup label for wealdtech.eth",
+		},
+		{
+			name: "LookupENSSubdomainFormat",
+			dcs: map[string]interface{}{
+				"wealdtech.eth": map[string]interface{}{
+					"owner-address": "0x000102030405060708090a0b0c0d0e0f10111213",
+					"passphrase":    map[string]interface{}{"file": "testdata/passphrase"},
+					"keystore":      "/path/to/keystore",
+					"owner-records": []interface{}{"ens"},
+					"lookup":        "ens",
+				},
+			},
+			err: "owner record format ens uses a subdomain so cannot be used with lookup ens for wealdtech.eth",
+		},
+		{
+			name: "LookupENSSubdomainPrefix",
+			dcs: map[string]interface{}{
+				"wealdtech.eth": map[string]interface{}{
+					"owner-address": "0x000102030405060708090a0b0c0d0e0f10111213",
+					"passphrase":    map[string]interface{}{"file": "testdata/passphrase"},
+					"keystore":      "/path/to/keystore",
+					"owner-records": []interface{}{
+						"address",
+						map[string]interface{}{"format": "prefix", "prefix": "owner=", "subdomain": "_owner"},
+					},
+					"lookup": "ens",
+				},
+			},
+			err: "owner record format prefix uses a subdomain so cannot be used with lookup ens for wealdtech.eth",
+		},
+		{
+			name: "GoodLookup",
+			dcs: map[string]interface{}{
+				"wealdtech.eth": map[string]interface{}{
+					"owner-address": "0x000102030405060708090a0b0c0d0e0f10111213",
+					"passphrase":    map[string]interface{}{"file": "testdata/passphrase"},
+					"keystore":      "/path/to/keystore",
+					"lookup":        "fqdn",
+				},
+			},
+			expected: map[string]*domainControl{
+				"wealdtech.eth": {
+					Domain:      "wealdtech.eth",
+					SigningMode: "registrar",
+					Lookup:      "fqdn",
+					Cache:       true,
+					Keys: []*signingKey{
+						{
+							Owner:      common.HexToAddress("000102030405060708090a0b0c0d0e0f10111213"),
+							Passphrase: &fileSecret{path: "testdata/passphrase"},
+							Keystore:   "/path/to/keystore",
+							SignerConfig: &signerConfig{
+								Type: "keystore",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "CacheInvalid",
+			dcs: map[string]interface{}{
+				"wealdtech.eth": map[string]interface{}{
+					"owner-address": "0x000102030405060708090a0b0c0d0e0f10111213",
+					"signer":        map[string]interface{}{"type": "clef", "endpoint": "http://localhost:8550/"},
+					"cache":         "no",
+				},
+			},
+			err: "cache invalid for wealdtech.eth",
+		},
+		{
+			name: "GoodCacheDisabled",
+			dcs: map[string]interface{}{
+				"wealdtech.eth": map[string]interface{}{
+					"owner-address": "0x000102030405060708090a0b0c0d0e0f10111213",
+					"passphrase":    map[string]interface{}{"file": "testdata/passphrase"},
+					"keystore":      "/path/to/keystore",
+					"cache":         false,
+				},
+			},
+			expected: map[string]*domainControl{
+				"wealdtech.eth": {
+					Domain:      "wealdtech.eth",
+					SigningMode: "registrar",
+					Lookup:      "parent",
+					Keys: []*signingKey{
+						{
+							Owner:      common.HexToAddress("000102030405060708090a0b0c0d0e0f10111213"),
+							Passphrase: &fileSecret{path: "testdata/passphrase"},
+							Keystore:   "/path/to/keystore",
+							SignerConfig: &signerConfig{
+								Type: "keystore",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "ConsensusThresholdInvalid",
+			dcs: map[string]interface{}{
+				"wealdtech.eth": map[string]interface{}{
+					"owner-address":       "0x000102030405060708090a0b0c0d0e0f10111213",
+					"signer":              map[string]interface{}{"type": "clef", "endpoint": "http://localhost:8550/"},
+					"consensus-threshold": -1,
+				},
+			},
+			err: "consensus-threshold invalid for wealdtech.eth",
+		},
+		{
+			name: "GoodConsensusThreshold",
+			dcs: map[string]interface{}{
+				"wealdtech.eth": map[string]interface{}{
+					"owner-address":       "0x000102030405060708090a0b0c0d0e0f10111213",
+					"passphrase":          map[string]interface{}{"file": "testdata/passphrase"},
+					"keystore":            "/path/to/keystore",
+					"consensus-threshold": 2,
+				},
+			},
+			expected: map[string]*domainControl{
+				"wealdtech.eth": {
+					Domain:             "wealdtech.eth",
+					SigningMode:        "registrar",
+					Lookup:             "parent",
+					Cache:              true,
+					ConsensusThreshold: 2,
+					Keys: []*signingKey{
+						{
+							Owner:      common.HexToAddress("000102030405060708090a0b0c0d0e0f10111213"),
+							Passphrase: &fileSecret{path: "testdata/passphrase"},
+							Keystore:   "/path/to/keystore",
+							SignerConfig: &signerConfig{
+								Type: "keystore",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "KeysInvalid",
+			dcs: map[string]interface{}{
+				"wealdtech.eth": map[string]interface{}{
+					"keys": "invalid",
+				},
+			},
+			err: "invalid keys for wealdtech.eth",
+		},
+		{
+			name: "KeysEmpty",
+			dcs: map[string]interface{}{
+				"wealdtech.eth": map[string]interface{}{
+					"keys": []interface{}{},
+				},
+			},
+			err: "invalid keys for wealdtech.eth",
+		},
+		{
+			name: "KeyInvalid",
+			dcs: map[string]interface{}{
+				"wealdtech.eth": map[string]interface{}{
+					"keys": []interface{}{"invalid"},
+				},
+			},
+			err: "invalid configuration for wealdtech.eth key 0",
+		},
+		{
+			name: "KeyOwnerAddressMissing",
+			dcs: map[string]interface{}{
+				"wealdtech.eth": map[string]interface{}{
+					"keys": []interface{}{
+						map[string]interface{}{},
+					},
+				},
+			},
+			err: "owner-address missing for wealdtech.eth key 0",
+		},
+		{
+			name: "KeyNotBeforeInvalid",
+			dcs: map[string]interface{}{
+				"wealdtech.eth": map[string]interface{}{
+					"keys": []interface{}{
+						map[string]interface{}{
+							"owner-address": "0x000102030405060708090a0b0c0d0e0f10111213",
+							"not-before":    "invalid",
+						},
+					},
+				},
+			},
+			err: `not-before invalid for wealdtech.eth key 0: parsing time "invalid" as "2006-01-02T15:04:05Z07:00": cannot parse "invalid" as "2006"`,
+		},
+		{
+			name: "KeyNotAfterInvalid",
+			dcs: map[string]interface{}{
+				"wealdtech.eth": map[string]interface{}{
+					"keys": []interface{}{
+						map[string]interface{}{
+							"owner-address": "0x000102030405060708090a0b0c0d0e0f10111213",
+							"not-after":     1,
+						},
+					},
+				},
+			},
+			err: "not-after invalid for wealdtech.eth key 0: invalid time",
+		},
+		{
+			name: "KeyWindowInvalid",
+			dcs: map[string]interface{}{
+				"wealdtech.eth": map[string]interface{}{
+					"keys": []interface{}{
+						map[string]interface{}{
+							"owner-address": "0x000102030405060708090a0b0c0d0e0f10111213",
+							"not-before":    "2021-06-01T00:00:00Z",
+							"not-after":     "2021-01-01T00:00:00Z",
+						},
+					},
+				},
+			},
+			err: "not-after must be after not-before for wealdtech.eth key 0",
+		},
+		{
+			name: "KeyCurrentInvalid",
+			dcs: map[string]interface{}{
+				"wealdtech.eth": map[string]interface{}{
+					"keys": []interface{}{
+						map[string]interface{}{
+							"owner-address": "0x000102030405060708090a0b0c0d0e0f10111213",
+							"current":       "yes",
+						},
+					},
+				},
+			},
+			err: "current invalid for wealdtech.eth key 0",
+		},
+		{
+			name: "KeysMultipleCurrent",
+			dcs: map[string]interface{}{
+				"wealdtech.eth": map[string]interface{}{
+					"keys": []interface{}{
+						map[string]interface{}{
+							"owner-address": "0x000102030405060708090a0b0c0d0e0f10111213",
+							"signer":        map[string]interface{}{"type": "clef", "endpoint": "http://localhost:8550/"},
+							"current":       true,
+						},
+						map[string]interface{}{
+							"owner-address": "0x1415161718191a1b1c1d1e1f2021222324252627",
+							"signer":        map[string]interface{}{"type": "clef", "endpoint": "http://localhost:8550/"},
+							"current":       true,
+						},
+					},
+				},
+			},
+			err: "multiple current keys for wealdtech.eth",
+		},
+		{
+			name: "GoodKeys",
+			dcs: map[string]interface{}{
+				"wealdtech.eth": map[string]interface{}{
+					// The configuration loader leaves maps inside lists, and
+					// any maps inside them, keyed by interface.
+					"keys": []interface{}{
+						map[interface{}]interface{}{
+							"owner-address": "0x000102030405060708090a0b0c0d0e0f10111213",
+							"passphrase":    map[interface{}]interface{}{"file": "testdata/passphrase"},
+							"keystore":      "/path/to/keystore",
+							"not-after":     "2021-06-01T00:00:00Z",
+						},
+						map[interface{}]interface{}{
+							"owner-address": "0x1415161718191a1b1c1d1e1f2021222324252627",
+							"signer": map[interface{}]interface{}{
+								"type":        "pkcs11",
+								"library":     "/usr/lib/softhsm/libsofthsm2.so",
+								"token-label": "edcd",
+								"key-label":   "edcd",
+								"pin":         map[interface{}]interface{}{"env": "EDCD_PIN"},
+							},
+							"not-before": "2021-05-01T00:00:00Z",
+							"current":    true,
+						},
+					},
+				},
+			},
+			expected: map[string]*domainControl{
+				"wealdtech.eth": {
+					Domain:      "wealdtech.eth",
+					SigningMode: "registrar",
+					Lookup:      "parent",
+					Cache:       true,
+					Keys: []*signingKey{
+						{
+							Owner:      common.HexToAddress("000102030405060708090a0b0c0d0e0f10111213"),
+							NotAfter:   time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+							Passphrase: &fileSecret{path: "testdata/passphrase"},
+							Keystore:   "/path/to/keystore",
+							SignerConfig: &signerConfig{
+								Type: "keystore",
+							},
+						},
+						{
+							Owner:     common.HexToAddress("1415161718191a1b1c1d1e1f2021222324252627"),
+							NotBefore: time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC),
+							Current:   true,
+							SignerConfig: &signerConfig{
+								Type:       "pkcs11",
+								Library:    "/usr/lib/softhsm/libsofthsm2.so",
+								TokenLabel: "edcd",
+								KeyLabel:   "edcd",
+								PIN:        &envSecret{name: "EDCD_PIN"},
+							},
+						},
+					},
 				},
 			},
 		},
@@ -102,3 +882,121 @@ func TestParseDomainControls(t *testing.T) {
 		})
 	}
 }
+
+func TestSigningKey(t *testing.T) {
+	oldKey := &signingKey{
+		Owner:    common.HexToAddress("0x000102030405060708090a0b0c0d0e0f10111213"),
+		NotAfter: time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+	}
+	newKey := &signingKey{
+		Owner:     common.HexToAddress("0x1415161718191a1b1c1d1e1f2021222324252627"),
+		NotBefore: time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC),
+	}
+	currentKey := &signingKey{
+		Owner:     common.HexToAddress("0x28292a2b2c2d2e2f303132333435363738393a3b"),
+		NotBefore: time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:  time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC),
+		Current:   true,
+	}
+
+	tests := []struct {
+		name     string
+		keys     []*signingKey
+		time     time.Time
+		expected *signingKey
+		err      string
+	}{
+		{
+			name: "None",
+			time: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+			err:  "no valid signing key for wealdtech.eth",
+		},
+		{
+			name:     "Single",
+			keys:     []*signingKey{oldKey},
+			time:     time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+			expected: oldKey,
+		},
+		{
+			name: "Expired",
+			keys: []*signingKey{oldKey},
+			time: time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+			err:  "no valid signing key for wealdtech.eth",
+		},
+		{
+			name:     "BeforeOverlap",
+			keys:     []*signingKey{oldKey, newKey},
+			time:     time.Date(2021, 4, 30, 0, 0, 0, 0, time.UTC),
+			expected: oldKey,
+		},
+		{
+			name:     "Overlap",
+			keys:     []*signingKey{oldKey, newKey},
+			time:     time.Date(2021, 5, 15, 0, 0, 0, 0, time.UTC),
+			expected: newKey,
+		},
+		{
+			name:     "Current",
+			keys:     []*signingKey{oldKey, currentKey, newKey},
+			time:     time.Date(2021, 5, 15, 0, 0, 0, 0, time.UTC),
+			expected: currentKey,
+		},
+		{
+			name:     "CurrentExpired",
+			keys:     []*signingKey{oldKey, currentKey, newKey},
+			time:     time.Date(2021, 8, 1, 0, 0, 0, 0, time.UTC),
+			expected: newKey,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dc := &domainControl{
+				Domain: "wealdtech.eth",
+				Keys:   test.keys,
+			}
+			res, err := dc.signingKey(test.time)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, test.expected, res)
+			}
+		})
+	}
+}
+
+func TestOwnerName(t *testing.T) {
+	tests := []struct {
+		name     string
+		lookup   string
+		expected string
+	}{
+		{
+			name:     "Default",
+			expected: "example.com",
+		},
+		{
+			name:     "Parent",
+			lookup:   "parent",
+			expected: "example.com",
+		},
+		{
+			name:     "FQDN",
+			lookup:   "fqdn",
+			expected: "alice.example.com",
+		},
+		{
+			name:     "ENS",
+			lookup:   "ens",
+			expected: "_ens.alice.example.com",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dc := &domainControl{Domain: "example.com", Lookup: test.lookup}
+			require.Equal(t, test.expected, dc.ownerName("alice"))
+		})
+	}
+}
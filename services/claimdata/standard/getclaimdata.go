@@ -17,53 +17,130 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/miekg/dns"
 	"github.com/pkg/errors"
+	"github.com/wealdtech/edcd/services/auditlog"
+	"github.com/wealdtech/edcd/services/claimdata"
+	"github.com/wealdtech/edcd/util/eip712"
 	"github.com/wealdtech/go-ens/v3"
 )
 
 // GetClaimData gets the claim data for a domain.
-func (s *Service) GetClaimData(ctx context.Context,
-	domain string,
-) (
-	[32]byte,
-	string,
-	common.Address,
-	[]byte,
-	error,
-) {
+func (s *Service) GetClaimData(ctx context.Context, domain string) (*claimdata.ClaimData, error) {
 	log := log.With().Str("domain", domain).Logger()
 
 	domainControl, label, err := s.managedDomain(ctx, domain)
 	if err != nil {
-		return [32]byte{}, "", common.Address{}, nil, err
+		return nil, err
 	}
-	log.Trace().Str("parent_domain", domainControl.Domain).Str("parent_owner", fmt.Sprintf("%#x", domainControl.Owner)).Msg("Obtained parent domain")
+	log.Trace().Str("parent_domain", domainControl.Domain).Msg("Obtained parent domain")
+
+	key, err := domainControl.signingKey(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	log.Trace().Str("signer", fmt.Sprintf("%#x", key.Owner)).Msg("Selected signing key")
 
 	nameHash, err := ens.NameHash(domainControl.Domain)
 	if err != nil {
-		return [32]byte{}, "", common.Address{}, nil, err
+		return nil, err
 	}
 
-	owner, err := s.ownerForDomain(ctx, domainControl.Domain)
+	ownerName := domainControl.ownerName(label)
+	target, err := s.ownerForDomain(ctx, domainControl, ownerName)
 	if err != nil {
-		return [32]byte{}, "", common.Address{}, nil, err
+		return nil, err
 	}
-	log.Trace().Str("owner", fmt.Sprintf("%#x", owner)).Msg("Obtained domain owner")
+	owner := target.address
+	log.Trace().Str("owner_record", ownerName).Str("owner_name", target.name).Str("owner", fmt.Sprintf("%#x", owner)).Msg("Obtained domain owner")
 
-	hash, err := s.ens.SignatureHash(ctx, domain, domainControl.Domain, owner)
+	signatureHash, typedData, err := s.claimHash(ctx, domain, domainControl, nameHash, label, owner)
 	if err != nil {
-		return [32]byte{}, "", common.Address{}, nil, err
+		return nil, err
 	}
-	log.Trace().Str("hash", fmt.Sprintf("%#x", hash)).Msg("Obtained signature hash")
+	log.Trace().Str("hash", fmt.Sprintf("%#x", signatureHash)).Msg("Obtained signature hash")
 
-	// TODO sign the hash.
-	var sig []byte
+	if err := s.signingGuard.Authorize(ctx, domainControl.Domain, label, owner, signatureHash); err != nil {
+		return nil, errors.Wrap(err, "signing not authorized")
+	}
+
+	sig, err := signClaim(ctx, key, signatureHash, typedData)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to sign hash")
+	}
 	log.Trace().Str("signature", fmt.Sprintf("%#x", sig)).Msg("Signed hash")
 
-	return nameHash, label, owner, sig, nil
+	// Ensure that the claim will be accepted before handing it out.
+	if err := verifyClaim(domain, domainControl, key, nameHash, label, signatureHash, sig); err != nil {
+		log.Error().Err(err).Msg("Claim failed verification")
+		return nil, err
+	}
+
+	// Record the signature before handing it out.
+	registrar, err := s.registrarAddress(ctx, domainControl)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to obtain registrar address")
+	}
+	if err := s.auditLog.Record(ctx, &auditlog.Entry{
+		Time:      time.Now(),
+		Domain:    domain,
+		Label:     label,
+		Node:      nameHash,
+		Owner:     owner,
+		Registrar: registrar,
+		Hash:      signatureHash,
+		Signature: sig,
+	}); err != nil {
+		log.Error().Err(err).Msg("Failed to record signature in audit log")
+		return nil, errors.Wrap(err, "failed to record signature")
+	}
+
+	return &claimdata.ClaimData{
+		NameHash:  nameHash,
+		Label:     label,
+		Owner:     owner,
+		OwnerName: target.name,
+		Signature: sig,
+		Signer:    key.Owner,
+	}, nil
+}
+
+// claimHash returns the hash to sign for a claim, along with its typed
+// data if the claim is signed as EIP-712 typed data.
+func (s *Service) claimHash(ctx context.Context,
+	domain string,
+	domainControl *domainControl,
+	nameHash [32]byte,
+	label string,
+	owner common.Address,
+) (
+	[32]byte,
+	*eip712.TypedData,
+	error,
+) {
+	if domainControl.SigningMode == signingModeEIP712 {
+		return eip712.ClaimHash(domainControl.EIP712, nameHash, label, owner),
+			eip712.ClaimTypedData(domainControl.EIP712, nameHash, label, owner),
+			nil
+	}
+
+	signatureHash, err := s.ens.SignatureHash(ctx, domain, domainControl.Domain, owner)
+	if err != nil {
+		return [32]byte{}, nil, err
+	}
+
+	return signatureHash, nil, nil
+}
+
+// registrarAddress returns the address of the registrar that verifies claims.
+func (s *Service) registrarAddress(ctx context.Context, domainControl *domainControl) (common.Address, error) {
+	if domainControl.SigningMode == signingModeEIP712 {
+		return domainControl.EIP712.VerifyingContract, nil
+	}
+	return s.ens.RegistrarAddress(ctx, domainControl.Domain)
 }
 
 // managedDomain finds the managed domain given a fully-qualified domain name.
@@ -96,62 +173,206 @@ func normalizeDomain(domain string) string {
 	return domain
 }
 
-func (s *Service) ownerForDomain(ctx context.Context, domain string) (common.Address, error) {
-	c := new(dns.Client)
-	c.Net = "udp"
-	//				c.DialTimeout = *timeoutDial
-	//	c.ReadTimeout = *timeoutRead
-	//	c.WriteTimeout = *timeoutWrite
-	m := &dns.Msg{
-		MsgHdr: dns.MsgHdr{
-			Authoritative:     false,
-			AuthenticatedData: false,
-			CheckingDisabled:  false,
-			RecursionDesired:  true,
-			Opcode:            dns.OpcodeQuery,
-		},
-		Question: make([]dns.Question, 1),
-	}
-	m.Rcode = dns.RcodeSuccess
-
-	//	o := &dns.OPT{
-	//		Hdr: dns.RR_Header{
-	//			Name:   ".",
-	//			Rrtype: dns.TypeOPT,
-	//		},
-	//	}
-	//	o.SetDo()
-	//	o.SetUDPSize(dns.DefaultMsgSize)
-	//	m.Extra = append(m.Extra, o)
-
-	m.Question[0] = dns.Question{Name: dns.Fqdn(domain), Qtype: dns.TypeTXT, Qclass: dns.ClassINET}
-	m.Id = dns.Id()
-
-	r, _, err := c.Exchange(m, "127.0.0.53:53")
+// ownerForDomain obtains the owner of a domain from the TXT records at the
+// given name, validating them with DNSSEC if the domain control requires it.
+// Records that match any accepted format provide the owner, and must all agree
+// on it.  If the records name the owner by its ENS name, the returned target
+// holds both the name and its resolved address.
+func (s *Service) ownerForDomain(ctx context.Context, domainControl *domainControl, domain string) (ownerTarget, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	if domainControl.ConsensusThreshold > 0 {
+		return s.ownerByConsensus(ctx, domainControl, domain)
+	}
+
+	owner, format, err := s.lookupOwner(ctx, domainControl, domain, s.dns, s.cache)
 	if err != nil {
-		return common.Address{}, err
+		return ownerTarget{}, err
 	}
-	if r.Id != m.Id {
-		return common.Address{}, errors.New("query ID mismatch")
+	ownerRecordMatched(format)
+
+	return owner, nil
+}
+
+// lookupOwner looks up the owner of a domain using the given DNS client and
+// cache, returning the owner and the format of the first record that provided
+// it.  ENS names in records are resolved to their addresses.  All matching
+// records, across all accepted formats, must agree on the owner.
+func (s *Service) lookupOwner(ctx context.Context,
+	domainControl *domainControl,
+	domain string,
+	client *dnsClient,
+	cache *dnsCache,
+) (
+	ownerTarget,
+	string,
+	error,
+) {
+	formats := domainControl.OwnerRecords
+	if len(formats) == 0 {
+		formats = defaultFormats()
 	}
 
-	for _, rr := range r.Answer {
-		txtRR, isTxtRR := rr.(*dns.TXT)
-		if !isTxtRR {
-			continue
+	records := make(map[string][]string)
+	candidates := make([]ownerTarget, 0)
+	matchedFormat := ""
+	for _, format := range formats {
+		name := domain
+		if format.subdomain() != "" {
+			if domainControl.Lookup == lookupENS {
+				// The domain is already the _ens subdomain of the
+				// requested name, so default formats on a subdomain do
+				// not apply.  Configured formats on a subdomain are
+				// rejected when the domain control is parsed.
+				continue
+			}
+			name = fmt.Sprintf("%s.%s", format.subdomain(), domain)
+		}
+		txts, exists := records[name]
+		if !exists {
+			var err error
+			txts, err = s.txtRecords(ctx, domainControl, name, client, cache)
+			if err != nil {
+				return ownerTarget{}, "", err
+			}
+			records[name] = txts
 		}
-		for _, txt := range txtRR.Txt {
-			// Only continue if this is an address record.
-			if !strings.HasPrefix(txt, "a=0x") {
+
+		for _, txt := range txts {
+			target, matched, err := format.parse(txt)
+			if !matched {
 				continue
 			}
-			address := common.HexToAddress(txt[4:])
-			if address.String() == "0x0000000000000000000000000000000000000000" {
-				return common.Address{}, fmt.Errorf("invalid record %s", txt)
+			if err != nil {
+				return ownerTarget{}, "", err
+			}
+			log.Trace().Str("name", name).Str("format", format.name()).Str("record", txt).Msg("Matched owner record")
+			if target.name != "" {
+				target.address, err = s.resolveOwnerName(ctx, target.name)
+				if err != nil {
+					return ownerTarget{}, "", err
+				}
 			}
-			return address, nil
+			if matchedFormat == "" {
+				matchedFormat = format.name()
+			}
+			if !containsOwner(candidates, target) {
+				candidates = append(candidates, target)
+			}
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		return ownerTarget{}, "", fmt.Errorf("no owner found for domain %s", domain)
+	case 1:
+		return candidates[0], matchedFormat, nil
+	default:
+		descriptions := make([]string, len(candidates))
+		for i := range candidates {
+			descriptions[i] = candidates[i].String()
 		}
+		return ownerTarget{}, "", fmt.Errorf("ambiguous owner for domain %s: candidates %s", domain, strings.Join(descriptions, ", "))
 	}
+}
 
-	return common.Address{}, fmt.Errorf("no owner found for domain %s", domain)
+// resolveOwnerName resolves the ENS name of an owner to its address.
+func (s *Service) resolveOwnerName(ctx context.Context, name string) (common.Address, error) {
+	address, err := s.ens.Resolve(ctx, name)
+	if err != nil {
+		return common.Address{}, errors.Wrapf(err, "failed to resolve owner name %s", name)
+	}
+	if address == (common.Address{}) {
+		return common.Address{}, fmt.Errorf("failed to resolve owner name %s: no address", name)
+	}
+	log.Trace().Str("owner_name", name).Str("owner", address.Hex()).Msg("Resolved owner name")
+	return address, nil
+}
+
+// containsOwner returns true if the list contains a target with the same
+// address.  A name and the address it resolves to are the same owner.
+func containsOwner(targets []ownerTarget, target ownerTarget) bool {
+	for i := range targets {
+		if targets[i].address == target.address {
+			return true
+		}
+	}
+	return false
+}
+
+// txtRecords obtains the TXT strings for a name, validating them with
+// DNSSEC if the domain control requires it.  Answers are served from and
+// added to the cache if the domain control allows it.
+func (s *Service) txtRecords(ctx context.Context,
+	domainControl *domainControl,
+	name string,
+	client *dnsClient,
+	cache *dnsCache,
+) (
+	[]string,
+	error,
+) {
+	cacheKey := dnsCacheKey{name: dns.CanonicalName(name), validated: domainControl.RequireDNSSEC}
+	if domainControl.Cache {
+		if txts, exists := cache.get(cacheKey); exists {
+			log.Trace().Str("name", name).Msg("Obtained TXT records from cache")
+			return txts, nil
+		}
+	}
+
+	m := newQuery(name, dns.TypeTXT, domainControl.RequireDNSSEC)
+	r, err := client.exchange(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	txts, target := answerTXT(r.Answer, name)
+	if domainControl.RequireDNSSEC {
+		validator := s.dnssec.withClient(client)
+		if err := validator.validate(ctx, r); err != nil {
+			return nil, errors.Wrapf(err, "failed to validate owner record for %s", name)
+		}
+		// An empty answer must be proven, or it could hide a record.
+		if len(txts) == 0 {
+			if err := validator.validateDenial(ctx, r, target, dns.TypeTXT); err != nil {
+				return nil, errors.Wrapf(err, "failed to validate owner record for %s", name)
+			}
+		}
+	}
+	if domainControl.Cache {
+		cache.set(cacheKey, txts, r)
+	}
+
+	return txts, nil
+}
+
+// maxCNAMEs is the maximum length of a chain of CNAMEs followed in an answer.
+const maxCNAMEs = 8
+
+// answerTXT obtains the TXT strings for a name from the answer section of a
+// response, following any CNAMEs from the name.  Records for other names are
+// ignored.  It also returns the name at the end of the chain of CNAMEs.
+func answerTXT(rrs []dns.RR, name string) ([]string, string) {
+	target := dns.CanonicalName(name)
+	for i := 0; i < maxCNAMEs; i++ {
+		next := ""
+		for _, rr := range rrs {
+			if cname, isCNAME := rr.(*dns.CNAME); isCNAME && dns.CanonicalName(cname.Hdr.Name) == target {
+				next = dns.CanonicalName(cname.Target)
+				break
+			}
+		}
+		if next == "" {
+			break
+		}
+		target = next
+	}
+
+	txts := make([]string, 0)
+	for _, rr := range rrs {
+		if txtRR, isTxtRR := rr.(*dns.TXT); isTxtRR && dns.CanonicalName(txtRR.Hdr.Name) == target {
+			txts = append(txts, txtRR.Txt...)
+		}
+	}
+	return txts, target
 }
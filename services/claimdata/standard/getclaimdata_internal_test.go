@@ -15,11 +15,16 @@ package standard
 
 import (
 	"context"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/stretchr/testify/require"
+	mockauditlog "github.com/wealdtech/edcd/services/auditlog/mock"
 	mockens "github.com/wealdtech/edcd/services/ens/mock"
+	mocksigningguard "github.com/wealdtech/edcd/services/signingguard/mock"
+	"github.com/wealdtech/edcd/util/dnsstandin"
 )
 
 func TestManagedDomain(t *testing.T) {
@@ -68,21 +73,26 @@ func TestManagedDomain(t *testing.T) {
 	ctx := context.Background()
 	dcs := map[string]interface{}{
 		"com": map[string]interface{}{
-			"owner-address": "0x000102030405060708090a0b0c0d0e0f10111213",
-			"passphrase":    "a secret",
+			"owner-address": "0x1a642f0E3c3aF545E7AcBD38b07251B3990914F1",
+			"passphrase":    map[string]interface{}{"file": "testdata/passphrase"},
+			"keystore":      "testdata/keystore",
 		},
 		"example.com": map[string]interface{}{
-			"owner-address": "0x0102030405060708090a0b0c0d0e0f1011121314",
-			"passphrase":    "a secret",
+			"owner-address": "0x5050A4F4b3f9338C3472dcC01A87C76A144b3c9c",
+			"passphrase":    map[string]interface{}{"file": "testdata/passphrase"},
+			"keystore":      "testdata/keystore",
 		},
 		"example.net": map[string]interface{}{
-			"owner-address": "0x02030405060708090a0b0c0d0e0f101112131415",
-			"passphrase":    "a secret",
+			"owner-address": "0x3325a78425F17a7E487Eb5666b2bFd93aBb06c70",
+			"passphrase":    map[string]interface{}{"file": "testdata/passphrase"},
+			"keystore":      "testdata/keystore",
 		},
 	}
 	s, err := New(ctx,
 		WithDomainControls(dcs),
 		WithENS(mockens.New()),
+		WithSigningGuard(mocksigningguard.New()),
+		WithAuditLog(mockauditlog.New()),
 	)
 	require.NoError(t, err)
 
@@ -101,10 +111,11 @@ func TestManagedDomain(t *testing.T) {
 
 func TestOwnerForDomain(t *testing.T) {
 	tests := []struct {
-		name    string
-		domain  string
-		address common.Address
-		err     string
+		name          string
+		domain        string
+		requireDNSSEC bool
+		address       common.Address
+		err           string
 	}{
 		{
 			name:    "Present",
@@ -121,29 +132,55 @@ func TestOwnerForDomain(t *testing.T) {
 			domain: "example.com",
 			err:    "no owner found for domain example.com",
 		},
+		{
+			name:   "NoRecords",
+			domain: "missing.example.com",
+			err:    "no owner found for domain missing.example.com",
+		},
+		{
+			name:          "PresentDNSSEC",
+			domain:        "owner.example.com",
+			requireDNSSEC: true,
+			address:       common.HexToAddress("0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5"),
+		},
+		{
+			name:          "NoRecordsDNSSEC",
+			domain:        "missing.example.com",
+			requireDNSSEC: true,
+			err:           "no owner found for domain missing.example.com",
+		},
 	}
 
+	server, err := dnsstandin.NewFromFile("testdata/zone.db", dnsstandin.WithDNSSEC())
+	require.NoError(t, err)
+	defer server.Close()
+
 	ctx := context.Background()
 	dcs := map[string]interface{}{
 		"com": map[string]interface{}{
-			"owner-address": "0x000102030405060708090a0b0c0d0e0f10111213",
-			"passphrase":    "a secret",
+			"owner-address": "0x1a642f0E3c3aF545E7AcBD38b07251B3990914F1",
+			"passphrase":    map[string]interface{}{"file": "testdata/passphrase"},
+			"keystore":      "testdata/keystore",
 		},
 	}
 	s, err := New(ctx,
 		WithDomainControls(dcs),
 		WithENS(mockens.New()),
+		WithSigningGuard(mocksigningguard.New()),
+		WithAuditLog(mockauditlog.New()),
+		WithResolvers([]string{server.Address()}),
+		WithTrustAnchors(server.TrustAnchors()),
 	)
 	require.NoError(t, err)
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			res, err := s.ownerForDomain(ctx, test.domain)
+			res, err := s.ownerForDomain(ctx, &domainControl{Domain: test.domain, RequireDNSSEC: test.requireDNSSEC}, test.domain)
 			if test.err != "" {
 				require.EqualError(t, err, test.err)
 			} else {
 				require.NoError(t, err)
-				require.Equal(t, test.address, res)
+				require.Equal(t, test.address, res.address)
 			}
 		})
 	}
@@ -188,3 +225,118 @@ func TestNormalizeDomain(t *testing.T) {
 		})
 	}
 }
+
+func TestGetClaimDataLookup(t *testing.T) {
+	server, err := dnsstandin.New(strings.NewReader(`
+example.com.       300 IN TXT "a=0x0102030405060708090a0b0c0d0e0f1011121314"
+alice.example.com. 300 IN TXT "a=0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5"
+bob.example.com.   300 IN TXT "a=0x3325a78425F17a7E487Eb5666b2bFd93aBb06c70"
+dave.example.com.  300 IN TXT "a=dave.eth"
+_ens.erin.example.net.      300 IN TXT "a=0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5"
+_ens._ens.erin.example.net. 300 IN TXT "a=0x3325a78425F17a7E487Eb5666b2bFd93aBb06c70"
+`))
+	require.NoError(t, err)
+	defer server.Close()
+
+	eip712Domain := map[string]interface{}{
+		"name":               "ENS DNS claim",
+		"version":            "1",
+		"chain-id":           5,
+		"verifying-contract": "0x0102030405060708090a0b0c0d0e0f1011121314",
+	}
+	ctx := context.Background()
+	dcs := map[string]interface{}{
+		"example.com": map[string]interface{}{
+			"owner-address": "0x1a642f0E3c3aF545E7AcBD38b07251B3990914F1",
+			"passphrase":    map[string]interface{}{"file": "testdata/passphrase"},
+			"keystore":      "testdata/keystore",
+			"signing-mode":  "eip712",
+			"eip712":        eip712Domain,
+			"lookup":        "fqdn",
+		},
+		"example.org": map[string]interface{}{
+			"owner-address": "0x1a642f0E3c3aF545E7AcBD38b07251B3990914F1",
+			"passphrase":    map[string]interface{}{"file": "testdata/passphrase"},
+			"keystore":      "testdata/keystore",
+			"signing-mode":  "eip712",
+			"eip712":        eip712Domain,
+		},
+		"example.net": map[string]interface{}{
+			"owner-address": "0x1a642f0E3c3aF545E7AcBD38b07251B3990914F1",
+			"passphrase":    map[string]interface{}{"file": "testdata/passphrase"},
+			"keystore":      "testdata/keystore",
+			"signing-mode":  "eip712",
+			"eip712":        eip712Domain,
+			"lookup":        "ens",
+		},
+	}
+	s, err := New(ctx,
+		WithTimeout(5*time.Second),
+		WithDomainControls(dcs),
+		WithENS(mockens.NewWithNames(map[string]common.Address{
+			"dave.eth": common.HexToAddress("0x3325a78425F17a7E487Eb5666b2bFd93aBb06c70"),
+		})),
+		WithSigningGuard(mocksigningguard.New()),
+		WithAuditLog(mockauditlog.New()),
+		WithResolvers([]string{server.Address()}),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		domain    string
+		owner     common.Address
+		ownerName string
+		err       string
+	}{
+		{
+			name:   "Alice",
+			domain: "alice.example.com",
+			owner:  common.HexToAddress("0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5"),
+		},
+		{
+			name:   "Bob",
+			domain: "bob.example.com",
+			owner:  common.HexToAddress("0x3325a78425F17a7E487Eb5666b2bFd93aBb06c70"),
+		},
+		{
+			name:   "Carol",
+			domain: "carol.example.com",
+			err:    "no owner found for domain carol.example.com",
+		},
+		{
+			name:      "Dave",
+			domain:    "dave.example.com",
+			owner:     common.HexToAddress("0x3325a78425F17a7E487Eb5666b2bFd93aBb06c70"),
+			ownerName: "dave.eth",
+		},
+		{
+			name:   "Parent",
+			domain: "alice.example.org",
+			err:    "no owner found for domain example.org",
+		},
+		{
+			name:   "ENS",
+			domain: "erin.example.net",
+			owner:  common.HexToAddress("0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5"),
+		},
+		{
+			name:   "ENSMissing",
+			domain: "frank.example.net",
+			err:    "no owner found for domain _ens.frank.example.net",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := s.GetClaimData(ctx, test.domain)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, test.owner, res.Owner)
+				require.Equal(t, test.ownerName, res.OwnerName)
+			}
+		})
+	}
+}
@@ -15,14 +15,22 @@ package standard_test
 
 import (
 	"context"
+	"math/big"
 	"testing"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/require"
+	mockauditlog "github.com/wealdtech/edcd/services/auditlog/mock"
 	"github.com/wealdtech/edcd/services/claimdata/standard"
 	mockens "github.com/wealdtech/edcd/services/ens/mock"
 	nullmetrics "github.com/wealdtech/edcd/services/metrics/null"
+	"github.com/wealdtech/edcd/services/signer/clef/standin"
+	mocksigningguard "github.com/wealdtech/edcd/services/signingguard/mock"
+	"github.com/wealdtech/edcd/util/dnsstandin"
+	"github.com/wealdtech/edcd/util/eip712"
 )
 
 func TestGetClaimData(t *testing.T) {
@@ -47,15 +55,30 @@ func TestGetClaimData(t *testing.T) {
 			domain: "test.wealdtech.eth",
 			err:    "no owner found for domain wealdtech.eth",
 		},
+		{
+			name:   "Registrar",
+			domain: "owner.example.com",
+		},
 	}
 
 	monitor := nullmetrics.New()
 	domainControls := map[string]interface{}{
 		"wealdtech.eth": map[string]interface{}{
-			"owner-address": "0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5",
-			"passphrase":    "a secret",
+			"owner-address": "0x1a642f0E3c3aF545E7AcBD38b07251B3990914F1",
+			"passphrase":    map[string]interface{}{"file": "testdata/passphrase"},
+			"keystore":      "testdata/keystore",
+		},
+		"example.com": map[string]interface{}{
+			"owner-address": "0x1a642f0E3c3aF545E7AcBD38b07251B3990914F1",
+			"passphrase":    map[string]interface{}{"file": "testdata/passphrase"},
+			"keystore":      "testdata/keystore",
+			"lookup":        "fqdn",
 		},
 	}
+	server, err := dnsstandin.NewFromFile("testdata/zone.db")
+	require.NoError(t, err)
+	defer server.Close()
+
 	ens := mockens.New()
 	s, err := standard.New(ctx,
 		standard.WithLogLevel(zerolog.Disabled),
@@ -63,12 +86,15 @@ func TestGetClaimData(t *testing.T) {
 		standard.WithTimeout(10*time.Second),
 		standard.WithDomainControls(domainControls),
 		standard.WithENS(ens),
+		standard.WithSigningGuard(mocksigningguard.New()),
+		standard.WithAuditLog(mockauditlog.New()),
+		standard.WithResolvers([]string{server.Address()}),
 	)
 	require.NoError(t, err)
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			_ /* nameHash */, _ /* label */, _ /* address */, _ /* signature */, err := s.GetClaimData(ctx, test.domain)
+			_, err := s.GetClaimData(ctx, test.domain)
 			if test.err != "" {
 				require.EqualError(t, err, test.err)
 			} else {
@@ -77,3 +103,92 @@ func TestGetClaimData(t *testing.T) {
 		})
 	}
 }
+
+func TestGetClaimDataClef(t *testing.T) {
+	ctx := context.Background()
+
+	key, err := crypto.HexToECDSA("0101010101010101010101010101010101010101010101010101010101010101")
+	require.NoError(t, err)
+	address := crypto.PubkeyToAddress(key.PublicKey)
+	clefServer, err := standin.New(key)
+	require.NoError(t, err)
+	defer clefServer.Close()
+
+	server, err := dnsstandin.NewFromFile("testdata/zone.db")
+	require.NoError(t, err)
+	defer server.Close()
+
+	eip712Config := map[string]interface{}{
+		"name":               "ENS DNS claim",
+		"version":            "1",
+		"chain-id":           5,
+		"verifying-contract": "0x0102030405060708090a0b0c0d0e0f1011121314",
+	}
+	eip712Domain := &eip712.Domain{
+		Name:              "ENS DNS claim",
+		Version:           "1",
+		ChainID:           big.NewInt(5),
+		VerifyingContract: common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314"),
+	}
+
+	tests := []struct {
+		name        string
+		signingMode string
+		err         string
+	}{
+		{
+			// Clef cannot sign the bare registrar hash.
+			name:        "Registrar",
+			signingMode: "registrar",
+			err:         "invalid domain controls: clef signer requires signing-mode eip712 for example.com",
+		},
+		{
+			name:        "EIP712",
+			signingMode: "eip712",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := standard.New(ctx,
+				standard.WithLogLevel(zerolog.Disabled),
+				standard.WithTimeout(10*time.Second),
+				standard.WithDomainControls(map[string]interface{}{
+					"example.com": map[string]interface{}{
+						"owner-address": address.Hex(),
+						"signer": map[string]interface{}{
+							"type":     "clef",
+							"endpoint": clefServer.Endpoint(),
+						},
+						"signing-mode": test.signingMode,
+						"eip712":       eip712Config,
+						"lookup":       "fqdn",
+					},
+				}),
+				standard.WithENS(mockens.New()),
+				standard.WithSigningGuard(mocksigningguard.New()),
+				standard.WithAuditLog(mockauditlog.New()),
+				standard.WithResolvers([]string{server.Address()}),
+			)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+
+			res, err := s.GetClaimData(ctx, "owner.example.com")
+			require.NoError(t, err)
+			require.Equal(t, address, res.Signer)
+			require.Equal(t, common.HexToAddress("0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5"), res.Owner)
+
+			// The signature recovers to the signer over the claim hash.
+			hash := eip712.ClaimHash(eip712Domain, res.NameHash, res.Label, res.Owner)
+			sig := make([]byte, 65)
+			copy(sig, res.Signature)
+			sig[64] -= 27
+			pubKey, err := crypto.SigToPub(hash[:], sig)
+			require.NoError(t, err)
+			require.Equal(t, address, crypto.PubkeyToAddress(*pubKey))
+		})
+	}
+}
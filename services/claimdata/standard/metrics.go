@@ -24,6 +24,11 @@ import (
 var metricsNamespace = "edcd"
 
 var requests *prometheus.GaugeVec
+var verificationFailures *prometheus.GaugeVec
+var ownerRecords *prometheus.GaugeVec
+var cacheLookups *prometheus.GaugeVec
+var cacheEvictions prometheus.Gauge
+var consensusResults *prometheus.GaugeVec
 
 func registerMetrics(ctx context.Context, monitor metrics.Service) error {
 	if requests != nil {
@@ -53,6 +58,64 @@ func registerPrometheusMetrics(ctx context.Context) error {
 		return errors.Wrap(err, "failed to register requests_total")
 	}
 
+	verificationFailures = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "claimdata",
+		Name:      "verification_failures_total",
+		Help:      "Claims that failed self-verification",
+	},
+		[]string{"reason"},
+	)
+	if err := prometheus.Register(verificationFailures); err != nil {
+		return errors.Wrap(err, "failed to register verification_failures_total")
+	}
+
+	ownerRecords = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "claimdata",
+		Name:      "owner_records_total",
+		Help:      "Owner records matched, by format",
+	},
+		[]string{"format"},
+	)
+	if err := prometheus.Register(ownerRecords); err != nil {
+		return errors.Wrap(err, "failed to register owner_records_total")
+	}
+
+	cacheLookups = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "claimdata",
+		Name:      "dns_cache_lookups_total",
+		Help:      "Lookups in the DNS answer cache",
+	},
+		[]string{"result"},
+	)
+	if err := prometheus.Register(cacheLookups); err != nil {
+		return errors.Wrap(err, "failed to register dns_cache_lookups_total")
+	}
+
+	cacheEvictions = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "claimdata",
+		Name:      "dns_cache_evictions_total",
+		Help:      "Answers evicted from the DNS answer cache",
+	})
+	if err := prometheus.Register(cacheEvictions); err != nil {
+		return errors.Wrap(err, "failed to register dns_cache_evictions_total")
+	}
+
+	consensusResults = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "claimdata",
+		Name:      "owner_consensus_total",
+		Help:      "Owner lookups across multiple resolvers, by result",
+	},
+		[]string{"result"},
+	)
+	if err := prometheus.Register(consensusResults); err != nil {
+		return errors.Wrap(err, "failed to register owner_consensus_total")
+	}
+
 	return nil
 }
 
@@ -61,3 +124,33 @@ func requestHandled(result string) {
 		requests.WithLabelValues(result).Inc()
 	}
 }
+
+func verificationFailed(reason string) {
+	if verificationFailures != nil {
+		verificationFailures.WithLabelValues(reason).Inc()
+	}
+}
+
+func ownerRecordMatched(format string) {
+	if ownerRecords != nil {
+		ownerRecords.WithLabelValues(format).Inc()
+	}
+}
+
+func cacheLookup(result string) {
+	if cacheLookups != nil {
+		cacheLookups.WithLabelValues(result).Inc()
+	}
+}
+
+func cacheEvicted() {
+	if cacheEvictions != nil {
+		cacheEvictions.Inc()
+	}
+}
+
+func consensusReached(result string) {
+	if consensusResults != nil {
+		consensusResults.WithLabelValues(result).Inc()
+	}
+}
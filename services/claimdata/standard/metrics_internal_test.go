@@ -27,6 +27,11 @@ func TestRegisterMetrics(t *testing.T) {
 
 	// Ensure metrics handler can be called without failing.
 	requestHandled("success")
+	verificationFailed("signature")
+	ownerRecordMatched("address")
+	cacheLookup("hit")
+	cacheEvicted()
+	consensusReached("agreed")
 
 	// Ensure metrics can be registered without monitor.
 	require.NoError(t, registerMetrics(ctx, nil))
@@ -50,4 +55,9 @@ func TestRegisterMetrics(t *testing.T) {
 
 	// Ensure metrics handler can be called without failing.
 	requestHandled("success")
+	verificationFailed("signature")
+	ownerRecordMatched("address")
+	cacheLookup("hit")
+	cacheEvicted()
+	consensusReached("agreed")
 }
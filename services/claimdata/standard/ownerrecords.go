@@ -0,0 +1,303 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package standard
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	ens "github.com/wealdtech/go-ens/v3"
+)
+
+// ownerTarget is the owner stated by a record: either an address, or an
+// ENS name whose address is the owner.
+type ownerTarget struct {
+	address common.Address
+	name    string
+}
+
+// String returns the target in human-readable form.
+func (t ownerTarget) String() string {
+	if t.name != "" {
+		return fmt.Sprintf("%s (%s)", t.name, t.address.Hex())
+	}
+	return t.address.Hex()
+}
+
+// ownerRecordFormat is a format of TXT record that states the owner of a domain.
+type ownerRecordFormat interface {
+	// name returns the name of the format, for logs and metrics.
+	name() string
+	// subdomain returns the label below the domain at which the record is
+	// found, or an empty string if it is found at the domain itself.
+	subdomain() string
+	// parse parses a TXT string, returning the owner and true if the string
+	// is in this format.
+	parse(txt string) (ownerTarget, bool, error)
+}
+
+// ownerRecordFormatFunc creates an owner record format from its configuration.
+type ownerRecordFormatFunc func(config map[string]interface{}) (ownerRecordFormat, error)
+
+// ownerRecordFormats is the registry of owner record formats.  The built-in
+// address and ens formats accept "a=" followed by an address with a 0x prefix
+// or an ENS name.
+var ownerRecordFormats = map[string]ownerRecordFormatFunc{
+	"address": func(map[string]interface{}) (ownerRecordFormat, error) {
+		return &templateFormat{formatName: "address", prefix: "a="}, nil
+	},
+	"ens": func(map[string]interface{}) (ownerRecordFormat, error) {
+		return &templateFormat{formatName: "ens", label: "_ens", prefix: "a="}, nil
+	},
+	"ens1":     func(map[string]interface{}) (ownerRecordFormat, error) { return &ens1Format{}, nil },
+	"prefix":   newPrefixFormat,
+	"template": newTemplateFormat,
+}
+
+// defaultOwnerRecordFormats are the formats used if a domain control does not
+// configure its own.
+var defaultOwnerRecordFormats = []string{"address", "ens", "ens1"}
+
+// addressPlaceholder is the placeholder for the address in a template.
+const addressPlaceholder = "{address}"
+
+// templateFormat is a record with the owner address or ENS name between a
+// prefix and a suffix.
+type templateFormat struct {
+	formatName string
+	label      string
+	prefix     string
+	suffix     string
+}
+
+func (f *templateFormat) name() string {
+	return f.formatName
+}
+
+func (f *templateFormat) subdomain() string {
+	return f.label
+}
+
+func (f *templateFormat) parse(txt string) (ownerTarget, bool, error) {
+	if !strings.HasPrefix(txt, f.prefix) || !strings.HasSuffix(txt, f.suffix) || len(txt) < len(f.prefix)+len(f.suffix) {
+		return ownerTarget{}, false, nil
+	}
+	value := txt[len(f.prefix) : len(txt)-len(f.suffix)]
+	if !looksLikeOwner(value) {
+		// Other records, such as verification strings, can share the prefix.
+		return ownerTarget{}, false, nil
+	}
+	target, err := parseOwnerTarget(value)
+	if err != nil {
+		return ownerTarget{}, true, errors.Wrapf(err, "invalid record %s", txt)
+	}
+	return target, true, nil
+}
+
+// looksLikeOwner returns true if the value of a record appears to state an
+// owner: an address, either with a 0x prefix or as 40 hexadecimal characters,
+// or an ENS name.  Values that do not are not treated as owner records.
+func looksLikeOwner(value string) bool {
+	if strings.HasPrefix(value, "0x") || strings.HasPrefix(value, "0X") {
+		return true
+	}
+	if len(value) == 2*common.AddressLength && strings.Trim(value, "0123456789abcdefABCDEF") == "" {
+		return true
+	}
+	if !strings.Contains(value, ".") {
+		return false
+	}
+	for _, c := range value {
+		if c < unicode.MaxASCII && !(unicode.IsLetter(c) || unicode.IsDigit(c) || c == '-' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// newPrefixFormat creates a format for records with a custom prefix
+// followed by the owner address.
+func newPrefixFormat(config map[string]interface{}) (ownerRecordFormat, error) {
+	prefix, isString := config["prefix"].(string)
+	if !isString || prefix == "" {
+		return nil, errors.New("prefix missing")
+	}
+	label, err := formatLabel(config)
+	if err != nil {
+		return nil, err
+	}
+	return &templateFormat{formatName: "prefix", label: label, prefix: prefix}, nil
+}
+
+// newTemplateFormat creates a format for records that match a custom
+// template containing the {address} placeholder.
+func newTemplateFormat(config map[string]interface{}) (ownerRecordFormat, error) {
+	template, isString := config["template"].(string)
+	if !isString || template == "" {
+		return nil, errors.New("template missing")
+	}
+	if strings.Count(template, addressPlaceholder) != 1 {
+		return nil, fmt.Errorf("template must contain %s exactly once", addressPlaceholder)
+	}
+	label, err := formatLabel(config)
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.SplitN(template, addressPlaceholder, 2)
+	return &templateFormat{formatName: "template", label: label, prefix: parts[0], suffix: parts[1]}, nil
+}
+
+// formatLabel obtains the optional subdomain label for a custom format.
+func formatLabel(config map[string]interface{}) (string, error) {
+	input, exists := config["subdomain"]
+	if !exists {
+		return "", nil
+	}
+	label, isString := input.(string)
+	if !isString || label == "" || strings.Contains(label, ".") {
+		return "", errors.New("subdomain invalid")
+	}
+	return label, nil
+}
+
+// ens1Format is the ENS DNS integration record "ENS1 <resolver> <address>",
+// where the address can also be an ENS name.
+type ens1Format struct{}
+
+func (f *ens1Format) name() string {
+	return "ens1"
+}
+
+func (f *ens1Format) subdomain() string {
+	return ""
+}
+
+func (f *ens1Format) parse(txt string) (ownerTarget, bool, error) {
+	fields := strings.Fields(txt)
+	if len(fields) == 0 || fields[0] != "ENS1" {
+		return ownerTarget{}, false, nil
+	}
+	if len(fields) != 3 {
+		return ownerTarget{}, true, fmt.Errorf("invalid record %s: expected resolver and address", txt)
+	}
+	target, err := parseOwnerTarget(fields[2])
+	if err != nil {
+		return ownerTarget{}, true, errors.Wrapf(err, "invalid record %s", txt)
+	}
+	return target, true, nil
+}
+
+// parseOwnerTarget parses the owner in a record.  Input containing a period
+// is an ENS name, anything else must be an address.
+func parseOwnerTarget(input string) (ownerTarget, error) {
+	if strings.Contains(input, ".") {
+		name, err := parseOwnerName(input)
+		if err != nil {
+			return ownerTarget{}, err
+		}
+		return ownerTarget{name: name}, nil
+	}
+	address, err := parseOwnerAddress(input)
+	if err != nil {
+		return ownerTarget{}, err
+	}
+	return ownerTarget{address: address}, nil
+}
+
+// parseOwnerName parses an ENS name, returning it in normalized form.
+func parseOwnerName(input string) (string, error) {
+	if strings.HasPrefix(input, ".") || strings.HasSuffix(input, ".") || strings.Contains(input, "..") {
+		return "", errors.New("name has an empty label")
+	}
+	if strings.IndexFunc(input, unicode.IsSpace) != -1 {
+		return "", errors.New("name contains whitespace")
+	}
+	name, err := ens.Normalize(input)
+	if err != nil {
+		return "", errors.Wrap(err, "name invalid")
+	}
+	return name, nil
+}
+
+// parseOwnerAddress strictly parses an owner address, with or without a 0x
+// prefix.  It must be exactly 40 hexadecimal characters, and if it is mixed
+// case it must match its EIP-55 checksum.
+func parseOwnerAddress(input string) (common.Address, error) {
+	hex := strings.TrimPrefix(strings.TrimPrefix(input, "0x"), "0X")
+	if len(hex) != 2*common.AddressLength {
+		return common.Address{}, errors.New("address must be 40 hexadecimal characters")
+	}
+	for _, c := range hex {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return common.Address{}, errors.New("address must be 40 hexadecimal characters")
+		}
+	}
+	address := common.HexToAddress(hex)
+	if hex != strings.ToLower(hex) && hex != strings.ToUpper(hex) && hex != address.Hex()[2:] {
+		return common.Address{}, errors.New("address does not match its EIP-55 checksum")
+	}
+	if address == (common.Address{}) {
+		return common.Address{}, errors.New("address cannot be zero")
+	}
+	return address, nil
+}
+
+// parseOwnerRecordFormats parses the owner record formats for a domain
+// control.  Each entry is either the name of a format or a map with the
+// name of the format under "format" along with its configuration.
+func parseOwnerRecordFormats(input interface{}) ([]ownerRecordFormat, error) {
+	entries, isList := input.([]interface{})
+	if !isList || len(entries) == 0 {
+		return nil, errors.New("invalid list")
+	}
+	formats := make([]ownerRecordFormat, 0, len(entries))
+	for i, entry := range entries {
+		config, isMap := toStringMap(entry)
+		if !isMap {
+			formatName, isString := entry.(string)
+			if !isString {
+				return nil, fmt.Errorf("entry %d invalid", i)
+			}
+			config = map[string]interface{}{"format": formatName}
+		}
+		formatName, isString := config["format"].(string)
+		if !isString {
+			return nil, fmt.Errorf("entry %d format missing", i)
+		}
+		formatFunc, exists := ownerRecordFormats[formatName]
+		if !exists {
+			return nil, fmt.Errorf("entry %d format %s unknown", i, formatName)
+		}
+		format, err := formatFunc(config)
+		if err != nil {
+			return nil, errors.Wrapf(err, "entry %d format %s invalid", i, formatName)
+		}
+		formats = append(formats, format)
+	}
+	return formats, nil
+}
+
+// defaultFormats returns the default owner record formats.
+func defaultFormats() []ownerRecordFormat {
+	formats := make([]ownerRecordFormat, 0, len(defaultOwnerRecordFormats))
+	for _, formatName := range defaultOwnerRecordFormats {
+		// Built-in formats take no configuration so cannot fail.
+		format, _ := ownerRecordFormats[formatName](nil)
+		formats = append(formats, format)
+	}
+	return formats
+}
@@ -0,0 +1,538 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package standard
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+	mockauditlog "github.com/wealdtech/edcd/services/auditlog/mock"
+	mockens "github.com/wealdtech/edcd/services/ens/mock"
+	mocksigningguard "github.com/wealdtech/edcd/services/signingguard/mock"
+	"github.com/wealdtech/edcd/util/dnsstandin"
+)
+
+func TestParseOwnerRecordFormats(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    interface{}
+		expected []ownerRecordFormat
+		err      string
+	}{
+		{
+			name:  "Invalid",
+			input: "address",
+			err:   "invalid list",
+		},
+		{
+			name:  "Empty",
+			input: []interface{}{},
+			err:   "invalid list",
+		},
+		{
+			name:  "EntryInvalid",
+			input: []interface{}{1},
+			err:   "entry 0 invalid",
+		},
+		{
+			name:  "FormatMissing",
+			input: []interface{}{map[string]interface{}{"prefix": "owner="}},
+			err:   "entry 0 format missing",
+		},
+		{
+			name:  "FormatUnknown",
+			input: []interface{}{"address", "unknown"},
+			err:   "entry 1 format unknown unknown",
+		},
+		{
+			name:  "PrefixMissing",
+			input: []interface{}{"prefix"},
+			err:   "entry 0 format prefix invalid: prefix missing",
+		},
+		{
+			name:  "TemplateMissing",
+			input: []interface{}{map[string]interface{}{"format": "template"}},
+			err:   "entry 0 format template invalid: template missing",
+		},
+		{
+			name:  "TemplateNoPlaceholder",
+			input: []interface{}{map[string]interface{}{"format": "template", "template": "owner="}},
+			err:   "entry 0 format template invalid: template must contain {address} exactly once",
+		},
+		{
+			name:  "TemplateMultiplePlaceholders",
+			input: []interface{}{map[string]interface{}{"format": "template", "template": "{address}{address}"}},
+			err:   "entry 0 format template invalid: template must contain {address} exactly once",
+		},
+		{
+			name:  "SubdomainInvalid",
+			input: []interface{}{map[string]interface{}{"format": "prefix", "prefix": "owner=", "subdomain": "a.b"}},
+			err:   "entry 0 format prefix invalid: subdomain invalid",
+		},
+		{
+			name: "Good",
+			input: []interface{}{
+				"address",
+				"ens",
+				"ens1",
+				map[string]interface{}{"format": "prefix", "prefix": "owner=", "subdomain": "_owner"},
+				map[interface{}]interface{}{"format": "template", "template": "owner=({address})"},
+			},
+			expected: []ownerRecordFormat{
+				&templateFormat{formatName: "address", prefix: "a="},
+				&templateFormat{formatName: "ens", label: "_ens", prefix: "a="},
+				&ens1Format{},
+				&templateFormat{formatName: "prefix", label: "_owner", prefix: "owner="},
+				&templateFormat{formatName: "template", prefix: "owner=(", suffix: ")"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			formats, err := parseOwnerRecordFormats(test.input)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, test.expected, formats)
+			}
+		})
+	}
+}
+
+func TestOwnerRecordFormatParse(t *testing.T) {
+	address := &templateFormat{formatName: "address", prefix: "a="}
+	template := &templateFormat{formatName: "template", prefix: "owner=(", suffix: ")"}
+	ens1 := &ens1Format{}
+
+	tests := []struct {
+		name    string
+		format  ownerRecordFormat
+		txt     string
+		matched bool
+		target  ownerTarget
+		err     string
+	}{
+		{
+			name:   "AddressNoMatch",
+			format: address,
+			txt:    "v=spf1 -all",
+		},
+		{
+			name:    "AddressZero",
+			format:  address,
+			txt:     "a=0x0000000000000000000000000000000000000000",
+			matched: true,
+			err:     "invalid record a=0x0000000000000000000000000000000000000000: address cannot be zero",
+		},
+		{
+			name:    "AddressTrailing",
+			format:  address,
+			txt:     "a=0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5 ",
+			matched: true,
+			err:     "invalid record a=0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5 : address must be 40 hexadecimal characters",
+		},
+		{
+			name:    "AddressChecksum",
+			format:  address,
+			txt:     "a=0x388ea662EF2c223eC0B047D41Bf3c0f362142ad5",
+			matched: true,
+			err:     "invalid record a=0x388ea662EF2c223eC0B047D41Bf3c0f362142ad5: address does not match its EIP-55 checksum",
+		},
+		{
+			name:    "Address",
+			format:  address,
+			txt:     "a=0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5",
+			matched: true,
+			target:  ownerTarget{address: common.HexToAddress("0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5")},
+		},
+		{
+			name:    "AddressNoPrefix",
+			format:  address,
+			txt:     "a=388Ea662EF2c223eC0B047D41Bf3c0f362142ad5",
+			matched: true,
+			target:  ownerTarget{address: common.HexToAddress("0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5")},
+		},
+		{
+			name:    "NameEmptyLabel",
+			format:  address,
+			txt:     "a=alice..eth",
+			matched: true,
+			err:     "invalid record a=alice..eth: name has an empty label",
+		},
+		{
+			name:    "NameTrailingPeriod",
+			format:  address,
+			txt:     "a=alice.eth.",
+			matched: true,
+			err:     "invalid record a=alice.eth.: name has an empty label",
+		},
+		{
+			name:   "NameWhitespace",
+			format: address,
+			txt:    "a=alice .eth",
+		},
+		{
+			name:   "AddressNotOwner",
+			format: address,
+			txt:    "a=verification-code-1234",
+		},
+		{
+			name:   "AddressSPF",
+			format: address,
+			txt:    "a=v=spf1 include:_spf.example.com -all",
+		},
+		{
+			name:    "Name",
+			format:  address,
+			txt:     "a=alice.eth",
+			matched: true,
+			target:  ownerTarget{name: "alice.eth"},
+		},
+		{
+			name:    "NameNormalized",
+			format:  address,
+			txt:     "a=Alice.ETH",
+			matched: true,
+			target:  ownerTarget{name: "alice.eth"},
+		},
+		{
+			name:   "TemplateNoSuffix",
+			format: template,
+			txt:    "owner=(0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5",
+		},
+		{
+			name:   "TemplateOverlap",
+			format: &templateFormat{formatName: "template", prefix: "ab", suffix: "ba"},
+			txt:    "aba",
+		},
+		{
+			name:    "Template",
+			format:  template,
+			txt:     "owner=(0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5)",
+			matched: true,
+			target:  ownerTarget{address: common.HexToAddress("0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5")},
+		},
+		{
+			name:    "TemplateName",
+			format:  template,
+			txt:     "owner=(alice.eth)",
+			matched: true,
+			target:  ownerTarget{name: "alice.eth"},
+		},
+		{
+			name:   "ENS1NoMatch",
+			format: ens1,
+			txt:    "a=0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5",
+		},
+		{
+			name:    "ENS1MissingAddress",
+			format:  ens1,
+			txt:     "ENS1 0x0102030405060708090a0b0c0d0e0f1011121314",
+			matched: true,
+			err:     "invalid record ENS1 0x0102030405060708090a0b0c0d0e0f1011121314: expected resolver and address",
+		},
+		{
+			name:    "ENS1InvalidAddress",
+			format:  ens1,
+			txt:     "ENS1 0x0102030405060708090a0b0c0d0e0f1011121314 0x1234",
+			matched: true,
+			err:     "invalid record ENS1 0x0102030405060708090a0b0c0d0e0f1011121314 0x1234: address must be 40 hexadecimal characters",
+		},
+		{
+			name:    "ENS1",
+			format:  ens1,
+			txt:     "ENS1 0x0102030405060708090a0b0c0d0e0f1011121314 0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5",
+			matched: true,
+			target:  ownerTarget{address: common.HexToAddress("0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5")},
+		},
+		{
+			name:    "ENS1Name",
+			format:  ens1,
+			txt:     "ENS1 0x0102030405060708090a0b0c0d0e0f1011121314 alice.eth",
+			matched: true,
+			target:  ownerTarget{name: "alice.eth"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			target, matched, err := test.format.parse(test.txt)
+			require.Equal(t, test.matched, matched)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, test.target, target)
+			}
+		})
+	}
+}
+
+func TestParseOwnerAddress(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		address common.Address
+		err     string
+	}{
+		{
+			name:  "Empty",
+			input: "",
+			err:   "address must be 40 hexadecimal characters",
+		},
+		{
+			name:  "Short",
+			input: "0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad",
+			err:   "address must be 40 hexadecimal characters",
+		},
+		{
+			name:  "Long",
+			input: "0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad500",
+			err:   "address must be 40 hexadecimal characters",
+		},
+		{
+			name:  "NotHex",
+			input: "0x388Ea662EF2c223eC0B047D41Bf3c0f362142adg",
+			err:   "address must be 40 hexadecimal characters",
+		},
+		{
+			name:  "DoublePrefix",
+			input: "0x0x388Ea662EF2c223eC0B047D41Bf3c0f36214",
+			err:   "address must be 40 hexadecimal characters",
+		},
+		{
+			name:  "BadChecksum",
+			input: "0x388EA662EF2c223eC0B047D41Bf3c0f362142ad5",
+			err:   "address does not match its EIP-55 checksum",
+		},
+		{
+			name:  "Zero",
+			input: "0000000000000000000000000000000000000000",
+			err:   "address cannot be zero",
+		},
+		{
+			name:    "Checksummed",
+			input:   "0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5",
+			address: common.HexToAddress("0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5"),
+		},
+		{
+			name:    "NoPrefix",
+			input:   "388Ea662EF2c223eC0B047D41Bf3c0f362142ad5",
+			address: common.HexToAddress("0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5"),
+		},
+		{
+			name:    "Lower",
+			input:   "0x388ea662ef2c223ec0b047d41bf3c0f362142ad5",
+			address: common.HexToAddress("0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5"),
+		},
+		{
+			name:    "Upper",
+			input:   "0X388EA662EF2C223EC0B047D41BF3C0F362142AD5",
+			address: common.HexToAddress("0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			address, err := parseOwnerAddress(test.input)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, test.address, address)
+			}
+		})
+	}
+}
+
+func TestOwnerForDomainFormats(t *testing.T) {
+	server, err := dnsstandin.New(strings.NewReader(`
+address.com.   300 IN TXT "v=spf1 -all"
+address.com.   300 IN TXT "a=0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5"
+unrelated.com. 300 IN TXT "a=verification-code-1234"
+unrelated.com. 300 IN TXT "a=v=spf1 include:_spf.example.com -all"
+unrelated.com. 300 IN TXT "a=0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5"
+_ens.ens.com.  300 IN TXT "a=0x0102030405060708090a0b0c0d0e0f1011121314"
+ens1.com.      300 IN TXT "ENS1 0x0102030405060708090a0b0c0d0e0f1011121314 0x3325a78425F17a7E487Eb5666b2bFd93aBb06c70"
+both.com.      300 IN TXT "ENS1 0x0102030405060708090a0b0c0d0e0f1011121314 0x3325a78425F17a7E487Eb5666b2bFd93aBb06c70"
+_ens.both.com. 300 IN TXT "a=0x3325a78425F17a7E487Eb5666b2bFd93aBb06c70"
+conflict.com.  300 IN TXT "a=0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5"
+_ens.conflict.com. 300 IN TXT "a=0x0102030405060708090a0b0c0d0e0f1011121314"
+conflict.com.  300 IN TXT "ENS1 0x0102030405060708090a0b0c0d0e0f1011121314 0x3325a78425F17a7E487Eb5666b2bFd93aBb06c70"
+custom.com.    300 IN TXT "owner=(0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5)"
+ambiguous.com. 300 IN TXT "a=0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5"
+ambiguous.com. 300 IN TXT "a=0x3325a78425F17a7E487Eb5666b2bFd93aBb06c70"
+duplicate.com. 300 IN TXT "a=0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5"
+duplicate.com. 300 IN TXT "a=0x388ea662ef2c223ec0b047d41bf3c0f362142ad5"
+name.com.      300 IN TXT "a=alice.eth"
+namedup.com.   300 IN TXT "a=alice.eth"
+namedup.com.   300 IN TXT "a=0x3325a78425F17a7E487Eb5666b2bFd93aBb06c70"
+nameamb.com.   300 IN TXT "a=alice.eth"
+nameamb.com.   300 IN TXT "a=0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5"
+unresolved.com. 300 IN TXT "a=bob.eth"
+`))
+	require.NoError(t, err)
+	defer server.Close()
+
+	ctx := context.Background()
+	dcs := map[string]interface{}{
+		"com": map[string]interface{}{
+			"owner-address": "0x1a642f0E3c3aF545E7AcBD38b07251B3990914F1",
+			"passphrase":    map[string]interface{}{"file": "testdata/passphrase"},
+			"keystore":      "testdata/keystore",
+		},
+	}
+	s, err := New(ctx,
+		WithTimeout(5*time.Second),
+		WithDomainControls(dcs),
+		WithENS(mockens.NewWithNames(map[string]common.Address{
+			"alice.eth": common.HexToAddress("0x3325a78425F17a7E487Eb5666b2bFd93aBb06c70"),
+		})),
+		WithSigningGuard(mocksigningguard.New()),
+		WithAuditLog(mockauditlog.New()),
+		WithResolvers([]string{server.Address()}),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		domain    string
+		formats   []interface{}
+		address   common.Address
+		ownerName string
+		err       string
+	}{
+		{
+			name:    "Address",
+			domain:  "address.com",
+			address: common.HexToAddress("0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5"),
+		},
+		{
+			name:    "Unrelated",
+			domain:  "unrelated.com",
+			address: common.HexToAddress("0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5"),
+		},
+		{
+			name:    "ENS",
+			domain:  "ens.com",
+			address: common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314"),
+		},
+		{
+			name:    "ENS1",
+			domain:  "ens1.com",
+			address: common.HexToAddress("0x3325a78425F17a7E487Eb5666b2bFd93aBb06c70"),
+		},
+		{
+			name:    "Both",
+			domain:  "both.com",
+			address: common.HexToAddress("0x3325a78425F17a7E487Eb5666b2bFd93aBb06c70"),
+		},
+		{
+			name:    "BothConfigured",
+			domain:  "both.com",
+			formats: []interface{}{"ens1", "ens"},
+			address: common.HexToAddress("0x3325a78425F17a7E487Eb5666b2bFd93aBb06c70"),
+		},
+		{
+			name:   "Conflict",
+			domain: "conflict.com",
+			err:    "ambiguous owner for domain conflict.com: candidates 0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5, 0x0102030405060708090a0B0c0d0e0f1011121314, 0x3325a78425F17a7E487Eb5666b2bFd93aBb06c70",
+		},
+		{
+			name:    "ConflictConfigured",
+			domain:  "conflict.com",
+			formats: []interface{}{"ens1", "ens"},
+			err:     "ambiguous owner for domain conflict.com: candidates 0x3325a78425F17a7E487Eb5666b2bFd93aBb06c70, 0x0102030405060708090a0B0c0d0e0f1011121314",
+		},
+		{
+			name:    "ConflictNotAccepted",
+			domain:  "conflict.com",
+			formats: []interface{}{"ens1"},
+			address: common.HexToAddress("0x3325a78425F17a7E487Eb5666b2bFd93aBb06c70"),
+		},
+		{
+			name:    "FormatNotAccepted",
+			domain:  "ens.com",
+			formats: []interface{}{"address", "ens1"},
+			err:     "no owner found for domain ens.com",
+		},
+		{
+			name:   "CustomNotAccepted",
+			domain: "custom.com",
+			err:    "no owner found for domain custom.com",
+		},
+		{
+			name:   "Ambiguous",
+			domain: "ambiguous.com",
+			err:    "ambiguous owner for domain ambiguous.com: candidates 0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5, 0x3325a78425F17a7E487Eb5666b2bFd93aBb06c70",
+		},
+		{
+			name:    "Duplicate",
+			domain:  "duplicate.com",
+			address: common.HexToAddress("0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5"),
+		},
+		{
+			name:      "Name",
+			domain:    "name.com",
+			address:   common.HexToAddress("0x3325a78425F17a7E487Eb5666b2bFd93aBb06c70"),
+			ownerName: "alice.eth",
+		},
+		{
+			name:      "NameDuplicate",
+			domain:    "namedup.com",
+			address:   common.HexToAddress("0x3325a78425F17a7E487Eb5666b2bFd93aBb06c70"),
+			ownerName: "alice.eth",
+		},
+		{
+			name:   "NameAmbiguous",
+			domain: "nameamb.com",
+			err:    "ambiguous owner for domain nameamb.com: candidates alice.eth (0x3325a78425F17a7E487Eb5666b2bFd93aBb06c70), 0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5",
+		},
+		{
+			name:   "NameUnresolved",
+			domain: "unresolved.com",
+			err:    "failed to resolve owner name bob.eth: unregistered name",
+		},
+		{
+			name:    "Custom",
+			domain:  "custom.com",
+			formats: []interface{}{map[string]interface{}{"format": "template", "template": "owner=({address})"}},
+			address: common.HexToAddress("0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dc := &domainControl{Domain: test.domain}
+			if test.formats != nil {
+				dc.OwnerRecords, err = parseOwnerRecordFormats(test.formats)
+				require.NoError(t, err)
+			}
+			res, err := s.ownerForDomain(ctx, dc, test.domain)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, test.address, res.address)
+				require.Equal(t, test.ownerName, res.name)
+			}
+		})
+	}
+}
@@ -15,12 +15,15 @@ package standard
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/rs/zerolog"
+	"github.com/wealdtech/edcd/services/auditlog"
 	"github.com/wealdtech/edcd/services/ens"
 	"github.com/wealdtech/edcd/services/metrics"
 	nullmetrics "github.com/wealdtech/edcd/services/metrics/null"
+	"github.com/wealdtech/edcd/services/signingguard"
 )
 
 type parameters struct {
@@ -29,6 +32,16 @@ type parameters struct {
 	timeout        time.Duration
 	domainControls map[string]interface{}
 	ens            ens.Service
+	signingGuard   signingguard.Service
+	auditLog       auditlog.Service
+	resolvers      []string
+	resolvConf     string
+	dnsTransport   string
+	dnsRetries     int
+	trustAnchors   []string
+	cacheSize      int
+	cacheMinTTL    time.Duration
+	cacheMaxTTL    time.Duration
 }
 
 // Parameter is the interface for service parameters.
@@ -77,12 +90,93 @@ func WithENS(ens ens.Service) Parameter {
 	})
 }
 
+// WithSigningGuard sets the signing guard service for this module.
+func WithSigningGuard(signingGuard signingguard.Service) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.signingGuard = signingGuard
+	})
+}
+
+// WithAuditLog sets the audit log service for this module.
+func WithAuditLog(auditLog auditlog.Service) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.auditLog = auditLog
+	})
+}
+
+// WithResolvers sets the DNS resolvers for this module, in order of preference.
+// Resolvers can be plain DNS servers, DNS-over-TLS servers as tls://host[:port]
+// or DNS-over-HTTPS endpoints as https:// URLs.
+func WithResolvers(resolvers []string) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.resolvers = resolvers
+	})
+}
+
+// WithResolvConf sets the path to the resolver configuration used if no
+// resolvers are supplied.
+func WithResolvConf(path string) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.resolvConf = path
+	})
+}
+
+// WithDNSTransport sets the transport for DNS queries to plain DNS servers.
+func WithDNSTransport(transport string) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.dnsTransport = transport
+	})
+}
+
+// WithDNSRetries sets the number of times to retry the list of resolvers
+// if none of them answers.
+func WithDNSRetries(retries int) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.dnsRetries = retries
+	})
+}
+
+// WithTrustAnchors sets the DS records for the root zone used as trust
+// anchors when validating owner records with DNSSEC.
+func WithTrustAnchors(anchors []string) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.trustAnchors = anchors
+	})
+}
+
+// WithCacheSize sets the maximum number of DNS answers held in the cache.
+// A size of 0 disables the cache.
+func WithCacheSize(size int) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.cacheSize = size
+	})
+}
+
+// WithCacheMinTTL sets the minimum time for which DNS answers are cached.
+func WithCacheMinTTL(ttl time.Duration) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.cacheMinTTL = ttl
+	})
+}
+
+// WithCacheMaxTTL sets the maximum time for which DNS answers are cached.
+func WithCacheMaxTTL(ttl time.Duration) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.cacheMaxTTL = ttl
+	})
+}
+
 // parseAndCheckParameters parses and checks parameters to ensure that mandatory parameters are present and correct.
 func parseAndCheckParameters(params ...Parameter) (*parameters, error) {
 	parameters := parameters{
-		logLevel: zerolog.GlobalLevel(),
-		monitor:  nullmetrics.New(),
-		timeout:  30 * time.Second,
+		logLevel:     zerolog.GlobalLevel(),
+		monitor:      nullmetrics.New(),
+		timeout:      30 * time.Second,
+		resolvConf:   "/etc/resolv.conf",
+		dnsTransport: "udp",
+		trustAnchors: defaultTrustAnchors,
+		cacheSize:    1024,
+		cacheMaxTTL:  time.Hour,
 	}
 	for _, p := range params {
 		if params != nil {
@@ -102,6 +196,27 @@ func parseAndCheckParameters(params ...Parameter) (*parameters, error) {
 	if parameters.ens == nil {
 		return nil, errors.New("no ENS service specified")
 	}
+	if parameters.signingGuard == nil {
+		return nil, errors.New("no signing guard service specified")
+	}
+	if parameters.auditLog == nil {
+		return nil, errors.New("no audit log service specified")
+	}
+	if parameters.dnsTransport != "udp" && parameters.dnsTransport != "tcp" {
+		return nil, fmt.Errorf("unsupported DNS transport %s", parameters.dnsTransport)
+	}
+	if parameters.dnsRetries < 0 {
+		return nil, errors.New("DNS retries cannot be negative")
+	}
+	if parameters.cacheSize < 0 {
+		return nil, errors.New("cache size cannot be negative")
+	}
+	if parameters.cacheMinTTL < 0 {
+		return nil, errors.New("cache minimum TTL cannot be negative")
+	}
+	if parameters.cacheMaxTTL < parameters.cacheMinTTL {
+		return nil, errors.New("cache maximum TTL cannot be less than minimum TTL")
+	}
 
 	return &parameters, nil
 }
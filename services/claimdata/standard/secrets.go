@@ -0,0 +1,136 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package standard
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// secretProvider provides a secret when it is required.
+// Secrets are read on demand rather than when configuration is parsed,
+// and the provider's description never contains the secret itself.
+type secretProvider interface {
+	// Secret returns the secret.
+	Secret() (string, error)
+	// String returns a description of the source of the secret.
+	String() string
+}
+
+// fileSecret is a secret held in a file.
+type fileSecret struct {
+	path string
+}
+
+// Secret returns the secret.
+func (s *fileSecret) Secret() (string, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read secret file")
+	}
+	return trimSecret(data), nil
+}
+
+// String returns a description of the source of the secret.
+func (s *fileSecret) String() string {
+	return fmt.Sprintf("file %s", s.path)
+}
+
+// envSecret is a secret held in an environment variable.
+type envSecret struct {
+	name string
+}
+
+// Secret returns the secret.
+func (s *envSecret) Secret() (string, error) {
+	secret, exists := os.LookupEnv(s.name)
+	if !exists {
+		return "", fmt.Errorf("environment variable %s not set", s.name)
+	}
+	return secret, nil
+}
+
+// String returns a description of the source of the secret.
+func (s *envSecret) String() string {
+	return fmt.Sprintf("environment variable %s", s.name)
+}
+
+// credentialSecret is a secret held in the systemd credentials directory.
+type credentialSecret struct {
+	name string
+}
+
+// Secret returns the secret.
+func (s *credentialSecret) Secret() (string, error) {
+	dir, exists := os.LookupEnv("CREDENTIALS_DIRECTORY")
+	if !exists {
+		return "", errors.New("no credentials directory available")
+	}
+	data, err := os.ReadFile(filepath.Join(dir, s.name))
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read credential")
+	}
+	return trimSecret(data), nil
+}
+
+// String returns a description of the source of the secret.
+func (s *credentialSecret) String() string {
+	return fmt.Sprintf("credential %s", s.name)
+}
+
+// trimSecret removes the trailing newline that editors add to files.
+func trimSecret(data []byte) string {
+	return strings.TrimSuffix(strings.TrimSuffix(string(data), "\n"), "\r")
+}
+
+// parseSecretProvider parses the configuration for a secret.
+func parseSecretProvider(input interface{}) (secretProvider, error) {
+	if _, isString := input.(string); isString {
+		return nil, errors.New("plaintext secrets are not supported; use file, env or credential")
+	}
+	config, isMap := toStringMap(input)
+	if !isMap {
+		return nil, errors.New("invalid configuration")
+	}
+	if len(config) != 1 {
+		return nil, errors.New("exactly one of file, env or credential must be supplied")
+	}
+
+	for source, value := range config {
+		location, isString := value.(string)
+		if !isString || location == "" {
+			return nil, fmt.Errorf("invalid %s", source)
+		}
+		switch source {
+		case "file":
+			return &fileSecret{path: location}, nil
+		case "env":
+			return &envSecret{name: location}, nil
+		case "credential":
+			if strings.ContainsRune(location, filepath.Separator) {
+				return nil, errors.New("credential must be a name, not a path")
+			}
+			return &credentialSecret{name: location}, nil
+		default:
+			return nil, fmt.Errorf("unknown secret source %s", source)
+		}
+	}
+
+	// Unreachable.
+	return nil, errors.New("no secret source")
+}
@@ -0,0 +1,182 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package standard
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSecretProvider(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       interface{}
+		description string
+		err         string
+	}{
+		{
+			name: "Nil",
+			err:  "invalid configuration",
+		},
+		{
+			name:  "Plaintext",
+			input: "a secret",
+			err:   "plaintext secrets are not supported; use file, env or credential",
+		},
+		{
+			name:  "Empty",
+			input: map[string]interface{}{},
+			err:   "exactly one of file, env or credential must be supplied",
+		},
+		{
+			name: "Multiple",
+			input: map[string]interface{}{
+				"file": "testdata/passphrase",
+				"env":  "EDCD_TEST_PASSPHRASE",
+			},
+			err: "exactly one of file, env or credential must be supplied",
+		},
+		{
+			name: "SourceUnknown",
+			input: map[string]interface{}{
+				"vault": "secret/edcd",
+			},
+			err: "unknown secret source vault",
+		},
+		{
+			name: "LocationInvalid",
+			input: map[string]interface{}{
+				"file": true,
+			},
+			err: "invalid file",
+		},
+		{
+			name: "CredentialPath",
+			input: map[string]interface{}{
+				"credential": "../passphrase",
+			},
+			err: "credential must be a name, not a path",
+		},
+		{
+			name: "File",
+			input: map[string]interface{}{
+				"file": "testdata/passphrase",
+			},
+			description: "file testdata/passphrase",
+		},
+		{
+			name: "FileInterfaceKeys",
+			input: map[interface{}]interface{}{
+				"file": "testdata/passphrase",
+			},
+			description: "file testdata/passphrase",
+		},
+		{
+			name: "Env",
+			input: map[string]interface{}{
+				"env": "EDCD_TEST_PASSPHRASE",
+			},
+			description: "environment variable EDCD_TEST_PASSPHRASE",
+		},
+		{
+			name: "Credential",
+			input: map[string]interface{}{
+				"credential": "passphrase",
+			},
+			description: "credential passphrase",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := parseSecretProvider(test.input)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, test.description, res.String())
+			}
+		})
+	}
+}
+
+func TestSecrets(t *testing.T) {
+	require.NoError(t, os.Setenv("EDCD_TEST_PASSPHRASE", "a secret"))
+	defer os.Unsetenv("EDCD_TEST_PASSPHRASE")
+
+	tests := []struct {
+		name           string
+		provider       secretProvider
+		credentialsDir string
+		secret         string
+		err            string
+	}{
+		{
+			name:     "FileMissing",
+			provider: &fileSecret{path: "testdata/missing"},
+			err:      "failed to read secret file: open testdata/missing: no such file or directory",
+		},
+		{
+			name:     "File",
+			provider: &fileSecret{path: "testdata/passphrase"},
+			secret:   "a secret",
+		},
+		{
+			name:     "EnvMissing",
+			provider: &envSecret{name: "EDCD_TEST_MISSING"},
+			err:      "environment variable EDCD_TEST_MISSING not set",
+		},
+		{
+			name:     "Env",
+			provider: &envSecret{name: "EDCD_TEST_PASSPHRASE"},
+			secret:   "a secret",
+		},
+		{
+			name:     "CredentialsDirectoryMissing",
+			provider: &credentialSecret{name: "passphrase"},
+			err:      "no credentials directory available",
+		},
+		{
+			name:           "CredentialMissing",
+			provider:       &credentialSecret{name: "missing"},
+			credentialsDir: "testdata",
+			err:            "failed to read credential: open " + filepath.Join("testdata", "missing") + ": no such file or directory",
+		},
+		{
+			name:           "Credential",
+			provider:       &credentialSecret{name: "passphrase"},
+			credentialsDir: "testdata",
+			secret:         "a secret",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.credentialsDir != "" {
+				require.NoError(t, os.Setenv("CREDENTIALS_DIRECTORY", test.credentialsDir))
+				defer os.Unsetenv("CREDENTIALS_DIRECTORY")
+			}
+			secret, err := test.provider.Secret()
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, test.secret, secret)
+			}
+		})
+	}
+}
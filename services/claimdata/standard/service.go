@@ -15,12 +15,16 @@ package standard
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 	zerologger "github.com/rs/zerolog/log"
+	"github.com/wealdtech/edcd/services/auditlog"
 	"github.com/wealdtech/edcd/services/ens"
+	"github.com/wealdtech/edcd/services/signer"
+	"github.com/wealdtech/edcd/services/signingguard"
 )
 
 // Service is the ENS service.
@@ -28,6 +32,11 @@ type Service struct {
 	timeout        time.Duration
 	domainControls map[string]*domainControl
 	ens            ens.Service
+	signingGuard   signingguard.Service
+	auditLog       auditlog.Service
+	dns            *dnsClient
+	dnssec         *dnssecValidator
+	cache          *dnsCache
 }
 
 // module-wide log.
@@ -56,11 +65,59 @@ func New(ctx context.Context, params ...Parameter) (*Service, error) {
 		return nil, errors.Wrap(err, "invalid domain controls")
 	}
 
+	dnsClient, err := newDNSClient(parameters.resolvers, parameters.resolvConf, parameters.dnsTransport, parameters.dnsRetries, parameters.timeout)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create DNS client")
+	}
+	for _, domainControl := range domainControls {
+		if domainControl.ConsensusThreshold > uint(len(dnsClient.resolvers)) {
+			return nil, fmt.Errorf("consensus threshold %d for %s exceeds %d resolvers",
+				domainControl.ConsensusThreshold, domainControl.Domain, len(dnsClient.resolvers))
+		}
+	}
+	dnssecValidator, err := newDNSSECValidator(dnsClient, parameters.trustAnchors)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create DNSSEC validator")
+	}
+
+	// Create signers for domain controls, failing if any key cannot be loaded.
+	// Signers are created last, so that later failures cannot leave them open.
+	for _, domainControl := range domainControls {
+		for _, key := range domainControl.Keys {
+			key.Signer, err = newSigner(ctx, parameters, key)
+			if err != nil {
+				closeSigners(domainControls)
+				return nil, errors.Wrapf(err, "failed to create signer for %s", domainControl.Domain)
+			}
+		}
+	}
+
 	s := &Service{
 		timeout:        parameters.timeout,
 		domainControls: domainControls,
 		ens:            parameters.ens,
+		signingGuard:   parameters.signingGuard,
+		auditLog:       parameters.auditLog,
+		dns:            dnsClient,
+		dnssec:         dnssecValidator,
+		cache:          newDNSCache(parameters.cacheSize, parameters.cacheMinTTL, parameters.cacheMaxTTL),
 	}
 
 	return s, nil
 }
+
+// Close closes the signers for the domain controls.
+func (s *Service) Close() {
+	closeSigners(s.domainControls)
+}
+
+// closeSigners closes the signers that hold resources.
+func closeSigners(domainControls map[string]*domainControl) {
+	for _, domainControl := range domainControls {
+		for _, key := range domainControl.Keys {
+			if closer, isCloser := key.Signer.(signer.Closer); isCloser {
+				closer.Close()
+			}
+		}
+	}
+}
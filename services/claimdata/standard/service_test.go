@@ -20,9 +20,12 @@ import (
 
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/require"
+	mockauditlog "github.com/wealdtech/edcd/services/auditlog/mock"
 	"github.com/wealdtech/edcd/services/claimdata/standard"
 	mockens "github.com/wealdtech/edcd/services/ens/mock"
 	nullmetrics "github.com/wealdtech/edcd/services/metrics/null"
+	"github.com/wealdtech/edcd/services/signer/clef/standin"
+	mocksigningguard "github.com/wealdtech/edcd/services/signingguard/mock"
 )
 
 func TestService(t *testing.T) {
@@ -31,12 +34,17 @@ func TestService(t *testing.T) {
 	monitor := nullmetrics.New()
 	domainControls := map[string]interface{}{
 		"wealdtech.eth": map[string]interface{}{
-			"owner-address": "0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5",
-			"passphrase":    "a secret",
+			"owner-address": "0x1a642f0E3c3aF545E7AcBD38b07251B3990914F1",
+			"passphrase":    map[string]interface{}{"file": "testdata/passphrase"},
+			"keystore":      "testdata/keystore",
 		},
 	}
 	ens := mockens.New()
 
+	clefServer, err := standin.New()
+	require.NoError(t, err)
+	defer clefServer.Close()
+
 	tests := []struct {
 		name   string
 		params []standard.Parameter
@@ -50,6 +58,8 @@ func TestService(t *testing.T) {
 				standard.WithTimeout(10 * time.Second),
 				standard.WithDomainControls(domainControls),
 				standard.WithENS(ens),
+				standard.WithSigningGuard(mocksigningguard.New()),
+				standard.WithAuditLog(mockauditlog.New()),
 			},
 			err: "problem with parameters: no monitor specified",
 		},
@@ -61,6 +71,8 @@ func TestService(t *testing.T) {
 				standard.WithTimeout(0),
 				standard.WithDomainControls(domainControls),
 				standard.WithENS(ens),
+				standard.WithSigningGuard(mocksigningguard.New()),
+				standard.WithAuditLog(mockauditlog.New()),
 			},
 			err: "problem with parameters: no timeout specified",
 		},
@@ -71,6 +83,8 @@ func TestService(t *testing.T) {
 				standard.WithMonitor(monitor),
 				standard.WithTimeout(10 * time.Second),
 				standard.WithENS(ens),
+				standard.WithSigningGuard(mocksigningguard.New()),
+				standard.WithAuditLog(mockauditlog.New()),
 			},
 			err: "problem with parameters: no domain controls specified",
 		},
@@ -84,9 +98,49 @@ func TestService(t *testing.T) {
 					"wealdtech.eth": "bad",
 				}),
 				standard.WithENS(ens),
+				standard.WithSigningGuard(mocksigningguard.New()),
+				standard.WithAuditLog(mockauditlog.New()),
 			},
 			err: "invalid domain controls: invalid configuration for wealdtech.eth",
 		},
+		{
+			name: "KeyMissing",
+			params: []standard.Parameter{
+				standard.WithLogLevel(zerolog.Disabled),
+				standard.WithMonitor(monitor),
+				standard.WithTimeout(10 * time.Second),
+				standard.WithDomainControls(map[string]interface{}{
+					"wealdtech.eth": map[string]interface{}{
+						"owner-address": "0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5",
+						"passphrase":    map[string]interface{}{"file": "testdata/passphrase"},
+						"keystore":      "testdata/keystore",
+					},
+				}),
+				standard.WithENS(ens),
+				standard.WithSigningGuard(mocksigningguard.New()),
+				standard.WithAuditLog(mockauditlog.New()),
+			},
+			err: "failed to create signer for wealdtech.eth: failed to load key: no key for 0x388ea662ef2c223ec0b047d41bf3c0f362142ad5 in testdata/keystore",
+		},
+		{
+			name: "PassphraseIncorrect",
+			params: []standard.Parameter{
+				standard.WithLogLevel(zerolog.Disabled),
+				standard.WithMonitor(monitor),
+				standard.WithTimeout(10 * time.Second),
+				standard.WithDomainControls(map[string]interface{}{
+					"wealdtech.eth": map[string]interface{}{
+						"owner-address": "0x1a642f0E3c3aF545E7AcBD38b07251B3990914F1",
+						"passphrase":    map[string]interface{}{"file": "testdata/wrongpassphrase"},
+						"keystore":      "testdata/keystore",
+					},
+				}),
+				standard.WithENS(ens),
+				standard.WithSigningGuard(mocksigningguard.New()),
+				standard.WithAuditLog(mockauditlog.New()),
+			},
+			err: "failed to create signer for wealdtech.eth: failed to load key: failed to decrypt key for 0x1a642f0e3c3af545e7acbd38b07251b3990914f1: could not decrypt key with given password",
+		},
 		{
 			name: "ENSMissing",
 			params: []standard.Parameter{
@@ -97,6 +151,176 @@ func TestService(t *testing.T) {
 			},
 			err: "problem with parameters: no ENS service specified",
 		},
+		{
+			name: "SigningGuardMissing",
+			params: []standard.Parameter{
+				standard.WithLogLevel(zerolog.Disabled),
+				standard.WithMonitor(monitor),
+				standard.WithTimeout(10 * time.Second),
+				standard.WithDomainControls(domainControls),
+				standard.WithENS(ens),
+			},
+			err: "problem with parameters: no signing guard service specified",
+		},
+		{
+			name: "AuditLogMissing",
+			params: []standard.Parameter{
+				standard.WithLogLevel(zerolog.Disabled),
+				standard.WithMonitor(monitor),
+				standard.WithTimeout(10 * time.Second),
+				standard.WithDomainControls(domainControls),
+				standard.WithENS(ens),
+				standard.WithSigningGuard(mocksigningguard.New()),
+			},
+			err: "problem with parameters: no audit log service specified",
+		},
+		{
+			name: "GoodClef",
+			params: []standard.Parameter{
+				standard.WithLogLevel(zerolog.Disabled),
+				standard.WithMonitor(monitor),
+				standard.WithTimeout(10 * time.Second),
+				standard.WithDomainControls(map[string]interface{}{
+					"wealdtech.eth": map[string]interface{}{
+						"owner-address": "0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5",
+						"signer": map[string]interface{}{
+							"type":     "clef",
+							"endpoint": clefServer.Endpoint(),
+						},
+						"signing-mode": "eip712",
+						"eip712": map[string]interface{}{
+							"name":               "ENS DNS claim",
+							"version":            "1",
+							"chain-id":           5,
+							"verifying-contract": "0x0102030405060708090a0b0c0d0e0f1011121314",
+						},
+					},
+				}),
+				standard.WithENS(ens),
+				standard.WithSigningGuard(mocksigningguard.New()),
+				standard.WithAuditLog(mockauditlog.New()),
+			},
+		},
+		{
+			name: "DNSTransportInvalid",
+			params: []standard.Parameter{
+				standard.WithLogLevel(zerolog.Disabled),
+				standard.WithMonitor(monitor),
+				standard.WithTimeout(10 * time.Second),
+				standard.WithDomainControls(domainControls),
+				standard.WithENS(ens),
+				standard.WithSigningGuard(mocksigningguard.New()),
+				standard.WithAuditLog(mockauditlog.New()),
+				standard.WithDNSTransport("sctp"),
+			},
+			err: "problem with parameters: unsupported DNS transport sctp",
+		},
+		{
+			name: "DNSRetriesNegative",
+			params: []standard.Parameter{
+				standard.WithLogLevel(zerolog.Disabled),
+				standard.WithMonitor(monitor),
+				standard.WithTimeout(10 * time.Second),
+				standard.WithDomainControls(domainControls),
+				standard.WithENS(ens),
+				standard.WithSigningGuard(mocksigningguard.New()),
+				standard.WithAuditLog(mockauditlog.New()),
+				standard.WithDNSRetries(-1),
+			},
+			err: "problem with parameters: DNS retries cannot be negative",
+		},
+		{
+			name: "ResolvConfMissing",
+			params: []standard.Parameter{
+				standard.WithLogLevel(zerolog.Disabled),
+				standard.WithMonitor(monitor),
+				standard.WithTimeout(10 * time.Second),
+				standard.WithDomainControls(domainControls),
+				standard.WithENS(ens),
+				standard.WithSigningGuard(mocksigningguard.New()),
+				standard.WithAuditLog(mockauditlog.New()),
+				standard.WithResolvConf("testdata/missing.conf"),
+			},
+			err: "failed to create DNS client: no resolvers supplied and failed to read resolver configuration: open testdata/missing.conf: no such file or directory",
+		},
+		{
+			name: "TrustAnchorsInvalid",
+			params: []standard.Parameter{
+				standard.WithLogLevel(zerolog.Disabled),
+				standard.WithMonitor(monitor),
+				standard.WithTimeout(10 * time.Second),
+				standard.WithDomainControls(domainControls),
+				standard.WithENS(ens),
+				standard.WithSigningGuard(mocksigningguard.New()),
+				standard.WithAuditLog(mockauditlog.New()),
+				standard.WithTrustAnchors([]string{". IN TXT \"anchor\""}),
+			},
+			err: "failed to create DNSSEC validator: trust anchor . IN TXT \"anchor\" is not a DS record for the root zone",
+		},
+		{
+			name: "CacheSizeNegative",
+			params: []standard.Parameter{
+				standard.WithLogLevel(zerolog.Disabled),
+				standard.WithMonitor(monitor),
+				standard.WithTimeout(10 * time.Second),
+				standard.WithDomainControls(domainControls),
+				standard.WithENS(ens),
+				standard.WithSigningGuard(mocksigningguard.New()),
+				standard.WithAuditLog(mockauditlog.New()),
+				standard.WithCacheSize(-1),
+			},
+			err: "problem with parameters: cache size cannot be negative",
+		},
+		{
+			name: "CacheMinTTLNegative",
+			params: []standard.Parameter{
+				standard.WithLogLevel(zerolog.Disabled),
+				standard.WithMonitor(monitor),
+				standard.WithTimeout(10 * time.Second),
+				standard.WithDomainControls(domainControls),
+				standard.WithENS(ens),
+				standard.WithSigningGuard(mocksigningguard.New()),
+				standard.WithAuditLog(mockauditlog.New()),
+				standard.WithCacheMinTTL(-time.Second),
+			},
+			err: "problem with parameters: cache minimum TTL cannot be negative",
+		},
+		{
+			name: "CacheMaxTTLLow",
+			params: []standard.Parameter{
+				standard.WithLogLevel(zerolog.Disabled),
+				standard.WithMonitor(monitor),
+				standard.WithTimeout(10 * time.Second),
+				standard.WithDomainControls(domainControls),
+				standard.WithENS(ens),
+				standard.WithSigningGuard(mocksigningguard.New()),
+				standard.WithAuditLog(mockauditlog.New()),
+				standard.WithCacheMinTTL(time.Minute),
+				standard.WithCacheMaxTTL(time.Second),
+			},
+			err: "problem with parameters: cache maximum TTL cannot be less than minimum TTL",
+		},
+		{
+			name: "ConsensusThresholdTooHigh",
+			params: []standard.Parameter{
+				standard.WithLogLevel(zerolog.Disabled),
+				standard.WithMonitor(monitor),
+				standard.WithTimeout(10 * time.Second),
+				standard.WithDomainControls(map[string]interface{}{
+					"wealdtech.eth": map[string]interface{}{
+						"owner-address":       "0x1a642f0E3c3aF545E7AcBD38b07251B3990914F1",
+						"passphrase":          map[string]interface{}{"file": "testdata/passphrase"},
+						"keystore":            "testdata/keystore",
+						"consensus-threshold": 2,
+					},
+				}),
+				standard.WithENS(ens),
+				standard.WithSigningGuard(mocksigningguard.New()),
+				standard.WithAuditLog(mockauditlog.New()),
+				standard.WithResolvers([]string{"127.0.0.1"}),
+			},
+			err: "consensus threshold 2 for wealdtech.eth exceeds 1 resolvers",
+		},
 		{
 			name: "Good",
 			params: []standard.Parameter{
@@ -105,6 +329,8 @@ func TestService(t *testing.T) {
 				standard.WithTimeout(10 * time.Second),
 				standard.WithDomainControls(domainControls),
 				standard.WithENS(ens),
+				standard.WithSigningGuard(mocksigningguard.New()),
+				standard.WithAuditLog(mockauditlog.New()),
 			},
 		},
 	}
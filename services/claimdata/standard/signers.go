@@ -0,0 +1,66 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package standard
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/wealdtech/edcd/services/signer"
+	clefsigner "github.com/wealdtech/edcd/services/signer/clef"
+	keystoresigner "github.com/wealdtech/edcd/services/signer/keystore"
+	"github.com/wealdtech/edcd/util/eip712"
+)
+
+// newSigner creates the signer for a signing key.
+func newSigner(ctx context.Context, parameters *parameters, key *signingKey) (signer.Service, error) {
+	switch key.SignerConfig.Type {
+	case "keystore":
+		passphrase, err := key.Passphrase.Secret()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to obtain passphrase from %s", key.Passphrase)
+		}
+		return keystoresigner.New(ctx,
+			keystoresigner.WithLogLevel(parameters.logLevel),
+			keystoresigner.WithMonitor(parameters.monitor),
+			keystoresigner.WithPath(key.Keystore),
+			keystoresigner.WithAddress(key.Owner),
+			keystoresigner.WithPassphrase(passphrase),
+		)
+	case "clef":
+		return clefsigner.New(ctx,
+			clefsigner.WithLogLevel(parameters.logLevel),
+			clefsigner.WithMonitor(parameters.monitor),
+			clefsigner.WithTimeout(parameters.timeout),
+			clefsigner.WithEndpoint(key.SignerConfig.Endpoint),
+		)
+	case "pkcs11":
+		return newPKCS11Signer(ctx, parameters, key)
+	default:
+		return nil, fmt.Errorf("unsupported signer type %s", key.SignerConfig.Type)
+	}
+}
+
+// signClaim signs the hash of a claim with the signing key.  If the claim
+// has typed data and the signer supports it then the typed data is passed
+// to the signer, allowing it to show the content of the claim.
+func signClaim(ctx context.Context, key *signingKey, hash [32]byte, typedData *eip712.TypedData) ([]byte, error) {
+	if typedData != nil {
+		if typedDataSigner, isTypedDataSigner := key.Signer.(signer.TypedDataSigner); isTypedDataSigner {
+			return typedDataSigner.SignTypedData(ctx, key.Owner, typedData)
+		}
+	}
+	return key.Signer.SignHash(ctx, key.Owner, hash)
+}
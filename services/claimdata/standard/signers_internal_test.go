@@ -0,0 +1,106 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package standard
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+	clefsigner "github.com/wealdtech/edcd/services/signer/clef"
+	"github.com/wealdtech/edcd/services/signer/clef/standin"
+	keystoresigner "github.com/wealdtech/edcd/services/signer/keystore"
+	"github.com/wealdtech/edcd/util/eip712"
+	"github.com/wealdtech/go-ens/v3"
+)
+
+func TestSignClaim(t *testing.T) {
+	ctx := context.Background()
+
+	key, err := crypto.HexToECDSA("0101010101010101010101010101010101010101010101010101010101010101")
+	require.NoError(t, err)
+	address := crypto.PubkeyToAddress(key.PublicKey)
+
+	keystoreSigner, err := keystoresigner.New(ctx,
+		keystoresigner.WithPath("testdata/keystore"),
+		keystoresigner.WithAddress(address),
+		keystoresigner.WithPassphrase("a secret"),
+	)
+	require.NoError(t, err)
+
+	clefServer, err := standin.New(key)
+	require.NoError(t, err)
+	defer clefServer.Close()
+	clefSigner, err := clefsigner.New(ctx,
+		clefsigner.WithTimeout(10*time.Second),
+		clefsigner.WithEndpoint(clefServer.Endpoint()),
+	)
+	require.NoError(t, err)
+
+	dc := &domainControl{
+		Domain:      "wealdtech.eth",
+		SigningMode: signingModeEIP712,
+		EIP712: &eip712.Domain{
+			Name:              "ENS DNS claim",
+			Version:           "1",
+			ChainID:           big.NewInt(5),
+			VerifyingContract: common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314"),
+		},
+	}
+	node, err := ens.NameHash(dc.Domain)
+	require.NoError(t, err)
+	owner := common.HexToAddress("0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5")
+
+	s := &Service{}
+	hash, typedData, err := s.claimHash(ctx, "test.wealdtech.eth", dc, node, "test", owner)
+	require.NoError(t, err)
+	require.Equal(t, eip712.ClaimHash(dc.EIP712, node, "test", owner), hash)
+	require.NotNil(t, typedData)
+	registrar, err := s.registrarAddress(ctx, dc)
+	require.NoError(t, err)
+	require.Equal(t, dc.EIP712.VerifyingContract, registrar)
+
+	tests := []struct {
+		name string
+		key  *signingKey
+	}{
+		{
+			name: "Keystore",
+			key: &signingKey{
+				Owner:  address,
+				Signer: keystoreSigner,
+			},
+		},
+		{
+			name: "Clef",
+			key: &signingKey{
+				Owner:  address,
+				Signer: clefSigner,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sig, err := signClaim(ctx, test.key, hash, typedData)
+			require.NoError(t, err)
+			// Both signers should produce a signature that verifies against the claim.
+			require.NoError(t, verifyClaim("test.wealdtech.eth", dc, test.key, node, "test", hash, sig))
+		})
+	}
+}
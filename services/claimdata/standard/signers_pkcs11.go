@@ -0,0 +1,47 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build cgo
+// +build cgo
+
+package standard
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/wealdtech/edcd/services/signer"
+	pkcs11signer "github.com/wealdtech/edcd/services/signer/pkcs11"
+)
+
+// newPKCS11Signer creates a PKCS#11 signer for a signing key.
+func newPKCS11Signer(ctx context.Context, parameters *parameters, key *signingKey) (signer.Service, error) {
+	pin, err := key.SignerConfig.PIN.Secret()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to obtain PIN from %s", key.SignerConfig.PIN)
+	}
+	params := []pkcs11signer.Parameter{
+		pkcs11signer.WithLogLevel(parameters.logLevel),
+		pkcs11signer.WithMonitor(parameters.monitor),
+		pkcs11signer.WithLibrary(key.SignerConfig.Library),
+		pkcs11signer.WithPIN(pin),
+		pkcs11signer.WithKeyLabel(key.SignerConfig.KeyLabel),
+		pkcs11signer.WithAddress(key.Owner),
+	}
+	if key.SignerConfig.Slot != nil {
+		params = append(params, pkcs11signer.WithSlot(*key.SignerConfig.Slot))
+	} else {
+		params = append(params, pkcs11signer.WithTokenLabel(key.SignerConfig.TokenLabel))
+	}
+	return pkcs11signer.New(ctx, params...)
+}
@@ -0,0 +1,30 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !cgo
+// +build !cgo
+
+package standard
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/wealdtech/edcd/services/signer"
+)
+
+// newPKCS11Signer creates a PKCS#11 signer for a signing key.
+// The PKCS#11 signer requires cgo, so is unavailable in this build.
+func newPKCS11Signer(_ context.Context, _ *parameters, _ *signingKey) (signer.Service, error) {
+	return nil, errors.New("pkcs11 signer not supported in this build")
+}
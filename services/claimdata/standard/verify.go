@@ -0,0 +1,86 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package standard
+
+import (
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+	"github.com/wealdtech/go-ens/v3"
+)
+
+// errVerificationFailed is returned when a claim fails self-verification.
+var errVerificationFailed = errors.New("claim verification failed")
+
+// verifySignature confirms that the signature over the hash recovers to the owner.
+func verifySignature(hash [32]byte, sig []byte, owner common.Address) error {
+	if len(sig) != 65 {
+		return errors.Wrapf(errVerificationFailed, "signature has invalid length %d", len(sig))
+	}
+	if sig[64] != 27 && sig[64] != 28 {
+		return errors.Wrapf(errVerificationFailed, "signature has invalid recovery ID %d", sig[64])
+	}
+
+	// Recovery expects V as 0 or 1.
+	recoverable := make([]byte, 65)
+	copy(recoverable, sig)
+	recoverable[64] -= 27
+	pubKey, err := crypto.SigToPub(hash[:], recoverable)
+	if err != nil {
+		return errors.Wrapf(errVerificationFailed, "failed to recover signer: %v", err)
+	}
+	signer := crypto.PubkeyToAddress(*pubKey)
+	if signer != owner {
+		return errors.Wrapf(errVerificationFailed, "signature recovers to %#x rather than %#x", signer, owner)
+	}
+
+	return nil
+}
+
+// verifyNode confirms that the label and node are those of the requested
+// domain.  The parent domain is obtained from the requested domain itself,
+// so that the node is checked against a value derived independently of it.
+func verifyNode(domain string, managedDomain string, node [32]byte, label string) error {
+	if !strings.HasPrefix(domain, label+".") {
+		return errors.Wrapf(errVerificationFailed, "label %s is not the first label of %s", label, domain)
+	}
+	parentDomain := domain[len(label)+1:]
+	if parentDomain != managedDomain {
+		return errors.Wrapf(errVerificationFailed, "parent domain %s is not managed domain %s", parentDomain, managedDomain)
+	}
+	parentNode, err := ens.NameHash(parentDomain)
+	if err != nil {
+		return errors.Wrap(err, "failed to calculate parent node")
+	}
+	if parentNode != node {
+		return errors.Wrapf(errVerificationFailed, "node %#x does not match parent domain %s", node, parentDomain)
+	}
+
+	return nil
+}
+
+// verifyClaim verifies a claim before it is returned.
+func verifyClaim(domain string, domainControl *domainControl, key *signingKey, node [32]byte, label string, hash [32]byte, sig []byte) error {
+	if err := verifyNode(normalizeDomain(domain), domainControl.Domain, node, label); err != nil {
+		verificationFailed("node")
+		return err
+	}
+	if err := verifySignature(hash, sig, key.Owner); err != nil {
+		verificationFailed("signature")
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package standard
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"github.com/wealdtech/go-ens/v3"
+)
+
+func TestVerifyClaim(t *testing.T) {
+	key, err := crypto.HexToECDSA("0101010101010101010101010101010101010101010101010101010101010101")
+	require.NoError(t, err)
+	owner := crypto.PubkeyToAddress(key.PublicKey)
+
+	hash := [32]byte{
+		0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f,
+		0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18, 0x19, 0x1a, 0x1b, 0x1c, 0x1d, 0x1e, 0x1f,
+	}
+	sig, err := crypto.Sign(hash[:], key)
+	require.NoError(t, err)
+	sig[64] += 27
+	badRecoveryID := make([]byte, 65)
+	copy(badRecoveryID, sig)
+	badRecoveryID[64] = 1
+
+	node, err := ens.NameHash("wealdtech.eth")
+	require.NoError(t, err)
+	otherNode, err := ens.NameHash("example.eth")
+	require.NoError(t, err)
+
+	signerKey := &signingKey{
+		Owner: owner,
+	}
+	domainControl := &domainControl{
+		Domain: "wealdtech.eth",
+		Keys:   []*signingKey{signerKey},
+	}
+
+	tests := []struct {
+		name   string
+		domain string
+		node   [32]byte
+		label  string
+		hash   [32]byte
+		sig    []byte
+		err    string
+	}{
+		{
+			name:   "NodeMismatch",
+			domain: "test.wealdtech.eth",
+			node:   otherNode,
+			label:  "test",
+			hash:   hash,
+			sig:    sig,
+			err:    "node 0x3d5d2e21162745e4df4f56471fd7f651f441adaaca25deb70e4738c6f63d1224 does not match parent domain wealdtech.eth: claim verification failed",
+		},
+		{
+			name:   "LabelMismatch",
+			domain: "test.wealdtech.eth",
+			node:   node,
+			label:  "other",
+			hash:   hash,
+			sig:    sig,
+			err:    "label other is not the first label of test.wealdtech.eth: claim verification failed",
+		},
+		{
+			name:   "DomainNotManaged",
+			domain: "test.example.eth",
+			node:   node,
+			label:  "test",
+			hash:   hash,
+			sig:    sig,
+			err:    "parent domain example.eth is not managed domain wealdtech.eth: claim verification failed",
+		},
+		{
+			name:   "SignatureShort",
+			domain: "test.wealdtech.eth",
+			node:   node,
+			label:  "test",
+			hash:   hash,
+			sig:    sig[:64],
+			err:    "signature has invalid length 64: claim verification failed",
+		},
+		{
+			name:   "SignatureRecoveryIDInvalid",
+			domain: "test.wealdtech.eth",
+			node:   node,
+			label:  "test",
+			hash:   hash,
+			sig:    badRecoveryID,
+			err:    "signature has invalid recovery ID 1: claim verification failed",
+		},
+		{
+			name:   "SignatureWrongHash",
+			domain: "test.wealdtech.eth",
+			node:   node,
+			label:  "test",
+			hash:   [32]byte{0x01},
+			sig:    sig,
+			err:    "signature recovers to 0xbe24d6360df2c4a5e628d883659fd4a87a229693 rather than 0x1a642f0e3c3af545e7acbd38b07251b3990914f1: claim verification failed",
+		},
+		{
+			name:   "Good",
+			domain: "test.wealdtech.eth",
+			node:   node,
+			label:  "test",
+			hash:   hash,
+			sig:    sig,
+		},
+		{
+			name:   "GoodTrailingPeriod",
+			domain: "test.wealdtech.eth.",
+			node:   node,
+			label:  "test",
+			hash:   hash,
+			sig:    sig,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := verifyClaim(test.domain, domainControl, signerKey, test.node, test.label, test.hash, test.sig)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+				require.True(t, errors.Is(err, errVerificationFailed))
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
@@ -32,7 +32,9 @@ type GetClaimDataResults struct {
 	Node      string `json:"node,omitempty"`
 	Label     string `json:"label,omitempty"`
 	NewOwner  string `json:"newowner,omitempty"`
+	OwnerName string `json:"ownername,omitempty"`
 	Signature string `json:"signature,omitempty"`
+	Signer    string `json:"signer,omitempty"`
 }
 
 // GetClaimData handles the JSON-RPC call ens_getclaimdata.
@@ -44,22 +46,26 @@ func (s *Service) GetClaimData(r *http.Request, args *GetClaimDataArgs, results
 	ctx := context.Background()
 	log.Trace().Str("domain", args.Domain).Msg("GetClaimData called")
 
-	node, label, newOwner, signature, err := s.claimData.GetClaimData(ctx, args.Domain)
+	claimData, err := s.claimData.GetClaimData(ctx, args.Domain)
 	if err != nil {
 		log.Trace().Err(err).Msg("GetClaimData failed")
 		return err
 	}
 
 	results.Message = "Success"
-	results.Node = fmt.Sprintf("%#x", node)
-	results.Label = label
-	results.NewOwner = fmt.Sprintf("%#x", newOwner)
-	results.Signature = fmt.Sprintf("%#x", signature)
+	results.Node = fmt.Sprintf("%#x", claimData.NameHash)
+	results.Label = claimData.Label
+	results.NewOwner = fmt.Sprintf("%#x", claimData.Owner)
+	results.OwnerName = claimData.OwnerName
+	results.Signature = fmt.Sprintf("%#x", claimData.Signature)
+	results.Signer = fmt.Sprintf("%#x", claimData.Signer)
 	log.Trace().
 		Str("nodehash", results.Node).
 		Str("label", results.Label).
 		Str("new_owner", results.NewOwner).
+		Str("owner_name", results.OwnerName).
 		Str("signature", results.Signature).
+		Str("signer", results.Signer).
 		Msg("GetClaimData succeeded")
 
 	return nil
@@ -15,18 +15,28 @@ package mock
 
 import (
 	"context"
+	"errors"
 
 	"github.com/ethereum/go-ethereum/common"
 )
 
 // Service is the mock ENS service.
-type Service struct{}
+type Service struct {
+	names map[string]common.Address
+}
 
 // New creates a new mock ENS service.
 func New() *Service {
 	return &Service{}
 }
 
+// NewWithNames creates a new mock ENS service that resolves the given names.
+func NewWithNames(names map[string]common.Address) *Service {
+	return &Service{
+		names: names,
+	}
+}
+
 // SignatureHash obtains the signature hash for a domain from its parent.
 // This is a mock; it always returns the same hash.
 func (s *Service) SignatureHash(ctx context.Context,
@@ -34,8 +44,37 @@ func (s *Service) SignatureHash(ctx context.Context,
 	domain string,
 	owner common.Address,
 ) (
-	[]byte,
+	[32]byte,
+	error,
+) {
+	return [32]byte{
+		0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f,
+		0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18, 0x19, 0x1a, 0x1b, 0x1c, 0x1d, 0x1e, 0x1f,
+	}, nil
+}
+
+// RegistrarAddress obtains the address of the registrar for a domain.
+// This is a mock; it always returns the same address.
+func (s *Service) RegistrarAddress(ctx context.Context,
+	domain string,
+) (
+	common.Address,
+	error,
+) {
+	return common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314"), nil
+}
+
+// Resolve resolves an ENS name to its address.
+// This is a mock; it resolves only the names supplied at creation.
+func (s *Service) Resolve(ctx context.Context,
+	name string,
+) (
+	common.Address,
 	error,
 ) {
-	return []byte{0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10, 0x12, 0x13, 0x14}, nil
+	address, exists := s.names[name]
+	if !exists {
+		return common.Address{}, errors.New("unregistered name")
+	}
+	return address, nil
 }
@@ -27,7 +27,23 @@ type Service interface {
 		domain string,
 		owner common.Address,
 	) (
-		[]byte,
+		[32]byte,
+		error,
+	)
+
+	// RegistrarAddress obtains the address of the registrar for a domain.
+	RegistrarAddress(ctx context.Context,
+		domain string,
+	) (
+		common.Address,
+		error,
+	)
+
+	// Resolve resolves an ENS name to its address.
+	Resolve(ctx context.Context,
+		name string,
+	) (
+		common.Address,
 		error,
 	)
 }
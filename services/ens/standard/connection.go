@@ -0,0 +1,318 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package standard
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/pkg/errors"
+)
+
+// maxReconnectBackoff is the longest time to wait between reconnection attempts.
+const maxReconnectBackoff = 5 * time.Minute
+
+// errWrongChain is returned when a node is not on the chain of the network.
+var errWrongChain = errors.New("wrong chain")
+
+// endpoint is a long-lived connection to an Ethereum 1 node.
+type endpoint struct {
+	// name identifies the endpoint in logs and metrics without exposing
+	// any credentials in its URL.
+	name string
+	url  string
+
+	mu             sync.RWMutex
+	client         *ethclient.Client
+	chainID        uint64
+	chainVerified  bool
+	healthy        bool
+	blockNumber    uint64
+	needsReconnect bool
+	backoff        time.Duration
+	nextReconnect  time.Time
+}
+
+// newEndpoint creates an endpoint for the given connection URL.
+func newEndpoint(connectionURL string) (*endpoint, error) {
+	if !strings.HasPrefix(connectionURL, "http") && !strings.HasPrefix(connectionURL, "ws") {
+		connectionURL = fmt.Sprintf("http://%s", connectionURL)
+	}
+	base, err := url.Parse(connectionURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid URL")
+	}
+	return &endpoint{
+		name: base.Host,
+		url:  base.String(),
+	}, nil
+}
+
+// ethClient returns the current connection to the node.
+func (e *endpoint) ethClient() *ethclient.Client {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.client
+}
+
+// isHealthy returns true if the node passed its last health check.
+func (e *endpoint) isHealthy() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.healthy
+}
+
+// isChainVerified returns true if the node is known to be on the expected chain.
+func (e *endpoint) isChainVerified() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.chainVerified
+}
+
+// observedChainID returns the chain ID reported by the node, or 0 if it has
+// not reported one.
+func (e *endpoint) observedChainID() uint64 {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.chainID
+}
+
+// status returns the health and last known block number of the node.
+func (e *endpoint) status() (bool, uint64) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.healthy, e.blockNumber
+}
+
+// setHealthy records the health of the node.
+func (e *endpoint) setHealthy(healthy bool) {
+	e.mu.Lock()
+	e.healthy = healthy
+	e.mu.Unlock()
+	connectionState(e.name, healthy)
+}
+
+// dial connects to the node, replacing any existing connection.
+func (e *endpoint) dial(ctx context.Context, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	client, err := ethclient.DialContext(ctx, e.url)
+	if err != nil {
+		return errors.Wrapf(err, "failed to connect to Ethereum 1 node %s", e.name)
+	}
+
+	e.mu.Lock()
+	old := e.client
+	e.client = client
+	e.needsReconnect = false
+	// The node behind the URL may have changed, so check its chain again.
+	e.chainVerified = false
+	e.mu.Unlock()
+	if old != nil {
+		old.Close()
+	}
+	return nil
+}
+
+// verifyChain checks that the node is on the expected chain.
+func (e *endpoint) verifyChain(ctx context.Context, chainID uint64) error {
+	id, err := e.ethClient().ChainID(ctx)
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	e.chainID = id.Uint64()
+	e.chainVerified = e.chainID == chainID
+	e.mu.Unlock()
+	if id.Uint64() != chainID {
+		return fmt.Errorf("%w: node is on chain %d, expected %d", errWrongChain, id.Uint64(), chainID)
+	}
+	return nil
+}
+
+// check checks that the node is on the expected chain and answers requests,
+// reconnecting first if a previous check failed and its backoff has passed.
+// It returns the block number of the node and true if the check succeeded.
+func (e *endpoint) check(ctx context.Context, timeout time.Duration, interval time.Duration, chainID uint64) (uint64, bool) {
+	e.mu.RLock()
+	needsReconnect := e.needsReconnect
+	nextReconnect := e.nextReconnect
+	e.mu.RUnlock()
+
+	if needsReconnect {
+		if time.Now().Before(nextReconnect) {
+			return 0, false
+		}
+		if err := e.dial(ctx, timeout); err != nil {
+			log.Debug().Str("endpoint", e.name).Err(err).Msg("Failed to reconnect to Ethereum 1 node")
+			e.failed(interval)
+			return 0, false
+		}
+		reconnected(e.name)
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if !e.isChainVerified() {
+		if err := e.verifyChain(checkCtx, chainID); err != nil {
+			switch {
+			case errors.Is(err, errWrongChain):
+				healthChecked("wrong_chain")
+				log.Error().Str("endpoint", e.name).Err(err).Msg("Ethereum 1 node is on the wrong chain; not using it")
+				e.failed(interval)
+			case ctx.Err() == nil:
+				healthChecked("failed")
+				log.Debug().Str("endpoint", e.name).Err(err).Msg("Failed to obtain chain ID of Ethereum 1 node")
+				e.failed(interval)
+			}
+			return 0, false
+		}
+	}
+	blockNumber, err := e.ethClient().BlockNumber(checkCtx)
+	if err != nil {
+		if ctx.Err() == nil {
+			healthChecked("failed")
+			if e.isHealthy() {
+				log.Warn().Str("endpoint", e.name).Err(err).Msg("Ethereum 1 node failed health check; reconnecting")
+			}
+			e.failed(interval)
+		}
+		return 0, false
+	}
+	healthChecked("succeeded")
+
+	e.mu.Lock()
+	e.blockNumber = blockNumber
+	e.backoff = 0
+	e.mu.Unlock()
+	if needsReconnect {
+		log.Info().Str("endpoint", e.name).Msg("Reconnected to Ethereum 1 node")
+	}
+	return blockNumber, true
+}
+
+// failed marks the node as unhealthy, and schedules a reconnection with
+// exponential backoff.
+func (e *endpoint) failed(interval time.Duration) {
+	e.mu.Lock()
+	e.needsReconnect = true
+	e.nextReconnect = time.Now().Add(e.backoff)
+	switch {
+	case e.backoff == 0:
+		e.backoff = interval
+	case e.backoff < maxReconnectBackoff:
+		e.backoff *= 2
+		if e.backoff > maxReconnectBackoff {
+			e.backoff = maxReconnectBackoff
+		}
+	}
+	e.mu.Unlock()
+	e.setHealthy(false)
+}
+
+// close closes the connection to the node.
+func (e *endpoint) close() {
+	if client := e.ethClient(); client != nil {
+		client.Close()
+	}
+	e.setHealthy(false)
+}
+
+// checkEndpoints checks the health of all endpoints in parallel.  Endpoints
+// that answer but lag the highest block seen by more than the maximum lag
+// are marked as unhealthy so that calls are routed elsewhere.
+func (s *Service) checkEndpoints(ctx context.Context) {
+	blockNumbers := make([]uint64, len(s.endpoints))
+	answered := make([]bool, len(s.endpoints))
+	var wg sync.WaitGroup
+	for i := range s.endpoints {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			blockNumbers[i], answered[i] = s.endpoints[i].check(ctx, s.timeout, s.healthCheckInterval, s.chainID)
+		}(i)
+	}
+	wg.Wait()
+
+	highest := uint64(0)
+	for i := range s.endpoints {
+		if answered[i] && blockNumbers[i] > highest {
+			highest = blockNumbers[i]
+		}
+	}
+	for i, e := range s.endpoints {
+		if !answered[i] {
+			continue
+		}
+		lag := highest - blockNumbers[i]
+		healthy := lag <= s.maxLag
+		if !healthy && e.isHealthy() {
+			log.Warn().Str("endpoint", e.name).Uint64("lag", lag).Msg("Ethereum 1 node is lagging")
+		}
+		e.setHealthy(healthy)
+	}
+}
+
+// isConnected returns true if any endpoint is healthy.
+func (s *Service) isConnected() bool {
+	for _, e := range s.endpoints {
+		if e.isHealthy() {
+			return true
+		}
+	}
+	return false
+}
+
+// monitorConnections checks the health of the endpoints and re-verifies
+// registrars periodically until the service is closed.
+func (s *Service) monitorConnections(ctx context.Context) {
+	defer close(s.monitorDone)
+
+	ticker := time.NewTicker(s.healthCheckInterval)
+	defer ticker.Stop()
+	registrarTicker := time.NewTicker(s.registrarVerificationInterval)
+	defer registrarTicker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.checkEndpoints(ctx)
+		case <-registrarTicker.C:
+			s.reverifyRegistrars(ctx)
+		}
+	}
+}
+
+// Close stops health checks and closes the connections to the Ethereum 1
+// nodes.  It is safe to call more than once.
+func (s *Service) Close() {
+	s.closeOnce.Do(func() {
+		s.cancelMonitor()
+		<-s.monitorDone
+		s.closeEndpoints()
+	})
+}
+
+// closeEndpoints closes the connections to the Ethereum 1 nodes.
+func (s *Service) closeEndpoints() {
+	for _, e := range s.endpoints {
+		e.close()
+	}
+}
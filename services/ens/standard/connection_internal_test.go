@@ -0,0 +1,267 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package standard
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	nullmetrics "github.com/wealdtech/edcd/services/metrics/null"
+)
+
+// eth1Server is a JSON-RPC server that answers eth_chainId and
+// eth_blockNumber while healthy, along with eth_getCode and
+// supportsInterface calls for a single contract.
+type eth1Server struct {
+	*httptest.Server
+	healthy     int32
+	chainID     uint64
+	blockNumber uint64
+	mu          sync.Mutex
+	code        []byte
+	interfaces  map[[4]byte]bool
+}
+
+func startEth1Server(t *testing.T, healthy bool, blockNumber uint64) *eth1Server {
+	t.Helper()
+	server := &eth1Server{chainID: 1, blockNumber: blockNumber}
+	server.setHealthy(healthy)
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&server.healthy) == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		req := struct {
+			ID     json.RawMessage   `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch req.Method {
+		case "eth_chainId":
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":"%#x"}`, req.ID, atomic.LoadUint64(&server.chainID))
+		case "eth_blockNumber":
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":"%#x"}`, req.ID, atomic.LoadUint64(&server.blockNumber))
+		case "eth_getCode":
+			server.mu.Lock()
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":"%s"}`, req.ID, hexutil.Bytes(server.code))
+			server.mu.Unlock()
+		case "eth_call":
+			// Only supportsInterface is answered; anything else returns no data.
+			msg := struct {
+				Data hexutil.Bytes `json:"data"`
+			}{}
+			if len(req.Params) > 0 {
+				_ = json.Unmarshal(req.Params[0], &msg)
+			}
+			result := []byte{}
+			server.mu.Lock()
+			if len(server.code) > 0 && len(msg.Data) == 36 {
+				var interfaceID [4]byte
+				copy(interfaceID[:], msg.Data[4:8])
+				result = make([]byte, 32)
+				if server.interfaces[interfaceID] {
+					result[31] = 1
+				}
+			}
+			server.mu.Unlock()
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":"%s"}`, req.ID, hexutil.Bytes(result))
+		default:
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"error":{"code":-32601,"message":"method not found"}}`, req.ID)
+		}
+	}))
+	return server
+}
+
+func (s *eth1Server) setHealthy(healthy bool) {
+	if healthy {
+		atomic.StoreInt32(&s.healthy, 1)
+	} else {
+		atomic.StoreInt32(&s.healthy, 0)
+	}
+}
+
+func (s *eth1Server) setChainID(chainID uint64) {
+	atomic.StoreUint64(&s.chainID, chainID)
+}
+
+func (s *eth1Server) setBlockNumber(blockNumber uint64) {
+	atomic.StoreUint64(&s.blockNumber, blockNumber)
+}
+
+func (s *eth1Server) setContract(code []byte, interfaces ...[4]byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.code = code
+	s.interfaces = make(map[[4]byte]bool)
+	for _, interfaceID := range interfaces {
+		s.interfaces[interfaceID] = true
+	}
+}
+
+func TestConnection(t *testing.T) {
+	ctx := context.Background()
+
+	server := startEth1Server(t, true, 16)
+	defer server.Close()
+
+	s, err := New(ctx,
+		WithLogLevel(zerolog.Disabled),
+		WithMonitor(nullmetrics.New()),
+		WithTimeout(time.Second),
+		WithConnectionURL(server.URL),
+		WithHealthCheckInterval(10*time.Millisecond),
+	)
+	require.NoError(t, err)
+	require.True(t, s.isConnected())
+	client := s.endpoints[0].ethClient()
+
+	// Connection is marked as down when the node fails health checks.
+	server.setHealthy(false)
+	require.Eventually(t, func() bool { return !s.isConnected() }, time.Second, 5*time.Millisecond)
+
+	// Connection is replaced when the node recovers.
+	server.setHealthy(true)
+	require.Eventually(t, s.isConnected, time.Second, 5*time.Millisecond)
+	require.NotSame(t, client, s.endpoints[0].ethClient())
+
+	// Close can be called more than once.
+	s.Close()
+	require.False(t, s.isConnected())
+	s.Close()
+}
+
+func TestConnectionUnavailable(t *testing.T) {
+	ctx := context.Background()
+
+	server := startEth1Server(t, false, 16)
+	defer server.Close()
+
+	// The service starts even if the node is unavailable, and connects when it
+	// becomes available.
+	s, err := New(ctx,
+		WithLogLevel(zerolog.Disabled),
+		WithMonitor(nullmetrics.New()),
+		WithTimeout(time.Second),
+		WithConnectionURL(server.URL),
+		WithHealthCheckInterval(10*time.Millisecond),
+	)
+	require.NoError(t, err)
+	defer s.Close()
+	require.False(t, s.isConnected())
+
+	server.setHealthy(true)
+	require.Eventually(t, s.isConnected, time.Second, 5*time.Millisecond)
+}
+
+func TestEndpointsByHealth(t *testing.T) {
+	ctx := context.Background()
+
+	server1 := startEth1Server(t, true, 100)
+	defer server1.Close()
+	server2 := startEth1Server(t, true, 90)
+	defer server2.Close()
+	server3 := startEth1Server(t, false, 100)
+	defer server3.Close()
+
+	s, err := New(ctx,
+		WithLogLevel(zerolog.Disabled),
+		WithMonitor(nullmetrics.New()),
+		WithTimeout(time.Second),
+		WithConnectionURLs([]string{server3.URL, server2.URL, server1.URL}),
+		WithHealthCheckInterval(time.Hour),
+		WithMaxLag(5),
+	)
+	require.NoError(t, err)
+	defer s.Close()
+
+	// The lagging node is unhealthy but still used; the failed node has not
+	// had its chain verified so is not used at all.
+	require.True(t, s.endpoints[2].isHealthy())
+	require.False(t, s.endpoints[1].isHealthy())
+	require.False(t, s.endpoints[0].isHealthy())
+	require.Equal(t, []*endpoint{s.endpoints[2], s.endpoints[1]}, s.endpointsByHealth())
+
+	// The lagging node catches up and overtakes; the other node is now
+	// within the maximum lag.
+	server2.setBlockNumber(103)
+	s.checkEndpoints(ctx)
+	require.True(t, s.endpoints[1].isHealthy())
+	require.True(t, s.endpoints[2].isHealthy())
+	require.Equal(t, []*endpoint{s.endpoints[1], s.endpoints[2]}, s.endpointsByHealth())
+}
+
+func TestChainVerification(t *testing.T) {
+	ctx := context.Background()
+
+	server := startEth1Server(t, true, 100)
+	defer server.Close()
+	server.setChainID(5)
+
+	// A node on the wrong chain is refused at startup.
+	_, err := New(ctx,
+		WithLogLevel(zerolog.Disabled),
+		WithMonitor(nullmetrics.New()),
+		WithTimeout(time.Second),
+		WithConnectionURL(server.URL),
+		WithHealthCheckInterval(time.Hour),
+	)
+	require.EqualError(t, err, fmt.Sprintf("connected node %s is on chain 5, expected 1", server.Listener.Addr().String()))
+
+	// The node is accepted for its own network.
+	s, err := New(ctx,
+		WithLogLevel(zerolog.Disabled),
+		WithMonitor(nullmetrics.New()),
+		WithTimeout(time.Second),
+		WithConnectionURL(server.URL),
+		WithHealthCheckInterval(time.Hour),
+		WithNetwork("goerli"),
+	)
+	require.NoError(t, err)
+	require.True(t, s.isConnected())
+	s.Close()
+
+	// A node that is unavailable at startup and later turns out to be on the
+	// wrong chain is never used.
+	server.setHealthy(false)
+	s, err = New(ctx,
+		WithLogLevel(zerolog.Disabled),
+		WithMonitor(nullmetrics.New()),
+		WithTimeout(time.Second),
+		WithConnectionURL(server.URL),
+		WithHealthCheckInterval(time.Hour),
+	)
+	require.NoError(t, err)
+	defer s.Close()
+	server.setHealthy(true)
+	s.checkEndpoints(ctx)
+	require.False(t, s.isConnected())
+	require.Equal(t, uint64(5), s.endpoints[0].observedChainID())
+	require.EqualError(t, s.call(ctx, func(client *ethclient.Client) error { return nil }), "no Ethereum 1 node verified on chain 1")
+}
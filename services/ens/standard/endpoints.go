@@ -0,0 +1,126 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package standard
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/pkg/errors"
+)
+
+// endpointsByHealth returns the endpoints in the order in which they should
+// be used: healthy endpoints first, then those with the highest block, then
+// in the order in which they were configured.  Endpoints that are not known
+// to be on the chain of the network are never used.
+func (s *Service) endpointsByHealth() []*endpoint {
+	type endpointStatus struct {
+		endpoint    *endpoint
+		healthy     bool
+		blockNumber uint64
+	}
+	statuses := make([]endpointStatus, 0, len(s.endpoints))
+	for _, e := range s.endpoints {
+		if !e.isChainVerified() {
+			continue
+		}
+		status := endpointStatus{endpoint: e}
+		status.healthy, status.blockNumber = e.status()
+		statuses = append(statuses, status)
+	}
+	sort.SliceStable(statuses, func(i, j int) bool {
+		if statuses[i].healthy != statuses[j].healthy {
+			return statuses[i].healthy
+		}
+		return statuses[i].blockNumber > statuses[j].blockNumber
+	})
+
+	endpoints := make([]*endpoint, len(statuses))
+	for i := range statuses {
+		endpoints[i] = statuses[i].endpoint
+	}
+	return endpoints
+}
+
+// isEndpointError returns true if the error is due to the endpoint rather
+// than the request, in which case the request can be tried elsewhere.
+func isEndpointError(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return true
+	}
+	return errors.Is(err, context.DeadlineExceeded)
+}
+
+// call calls the function against the healthiest endpoint, failing over to
+// the next endpoint if the endpoint fails.
+func (s *Service) call(ctx context.Context, f func(client *ethclient.Client) error) error {
+	endpoints := s.endpointsByHealth()
+	if len(endpoints) == 0 {
+		return fmt.Errorf("no Ethereum 1 node verified on chain %d", s.chainID)
+	}
+	var err error
+	for _, e := range endpoints {
+		err = f(e.ethClient())
+		if err == nil || !isEndpointError(err) || ctx.Err() != nil {
+			return err
+		}
+		log.Debug().Str("endpoint", e.name).Err(err).Msg("Ethereum 1 node failed request; failing over")
+		e.setHealthy(false)
+		failedOver(e.name)
+	}
+	return errors.Wrap(err, "all Ethereum 1 nodes failed")
+}
+
+// callQuorum calls the function against the healthiest endpoints until the
+// quorum of endpoints have answered, failing over if an endpoint fails.  All
+// answers must be identical.
+func (s *Service) callQuorum(ctx context.Context, f func(client *ethclient.Client) ([32]byte, error)) ([32]byte, error) {
+	var res [32]byte
+	answers := 0
+	for _, e := range s.endpointsByHealth() {
+		answer, err := f(e.ethClient())
+		if err != nil {
+			if !isEndpointError(err) || ctx.Err() != nil {
+				quorumReached("failed")
+				return [32]byte{}, err
+			}
+			log.Debug().Str("endpoint", e.name).Err(err).Msg("Ethereum 1 node failed request; failing over")
+			e.setHealthy(false)
+			failedOver(e.name)
+			continue
+		}
+		if answers > 0 && answer != res {
+			quorumReached("conflicted")
+			log.Error().Str("endpoint", e.name).Str("answer", fmt.Sprintf("%#x", answer)).Str("expected", fmt.Sprintf("%#x", res)).Msg("Ethereum 1 nodes disagree")
+			return [32]byte{}, fmt.Errorf("endpoints disagree: %s returned %#x, expected %#x", e.name, answer, res)
+		}
+		res = answer
+		answers++
+		if answers == s.quorum {
+			quorumReached("agreed")
+			return res, nil
+		}
+	}
+	quorumReached("failed")
+	return [32]byte{}, fmt.Errorf("quorum not reached: %d of %d required Ethereum 1 nodes answered", answers, s.quorum)
+}
@@ -0,0 +1,195 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package standard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	nullmetrics "github.com/wealdtech/edcd/services/metrics/null"
+)
+
+func TestIsEndpointError(t *testing.T) {
+	require.True(t, isEndpointError(&net.OpError{Op: "dial", Err: errors.New("connection refused")}))
+	require.True(t, isEndpointError(rpc.HTTPError{StatusCode: 503, Status: "503 Service Unavailable"}))
+	require.True(t, isEndpointError(context.DeadlineExceeded))
+	require.False(t, isEndpointError(errors.New("no registrar for wealdtech.com")))
+}
+
+// answers returns a function that gives the answer for each endpoint's client.
+func answers(s *Service, results [][32]byte, errs []error, calls *int) func(client *ethclient.Client) ([32]byte, error) {
+	return func(client *ethclient.Client) ([32]byte, error) {
+		*calls++
+		for i := range s.endpoints {
+			if s.endpoints[i].ethClient() == client {
+				return results[i], errs[i]
+			}
+		}
+		return [32]byte{}, errors.New("unknown client")
+	}
+}
+
+func TestCall(t *testing.T) {
+	ctx := context.Background()
+
+	server1 := startEth1Server(t, true, 100)
+	defer server1.Close()
+	server2 := startEth1Server(t, true, 100)
+	defer server2.Close()
+
+	endpointErr := &net.OpError{Op: "read", Err: errors.New("connection reset")}
+	tests := []struct {
+		name  string
+		errs  []error
+		calls int
+		err   string
+	}{
+		{
+			name:  "First",
+			errs:  []error{nil, nil},
+			calls: 1,
+		},
+		{
+			name:  "FailOver",
+			errs:  []error{endpointErr, nil},
+			calls: 2,
+		},
+		{
+			name:  "RequestError",
+			errs:  []error{errors.New("no registrar for wealdtech.com"), nil},
+			calls: 1,
+			err:   "no registrar for wealdtech.com",
+		},
+		{
+			name:  "AllFailed",
+			errs:  []error{endpointErr, endpointErr},
+			calls: 2,
+			err:   "all Ethereum 1 nodes failed: read: connection reset",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := New(ctx,
+				WithLogLevel(zerolog.Disabled),
+				WithMonitor(nullmetrics.New()),
+				WithTimeout(time.Second),
+				WithConnectionURLs([]string{server1.URL, server2.URL}),
+				WithHealthCheckInterval(time.Hour),
+			)
+			require.NoError(t, err)
+			defer s.Close()
+
+			calls := 0
+			f := answers(s, make([][32]byte, 2), test.errs, &calls)
+			err = s.call(ctx, func(client *ethclient.Client) error {
+				_, err := f(client)
+				return err
+			})
+			require.Equal(t, test.calls, calls)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+			} else {
+				require.NoError(t, err)
+			}
+			// An endpoint that fails is no longer preferred.
+			require.Equal(t, test.errs[0] != endpointErr, s.endpoints[0].isHealthy())
+		})
+	}
+}
+
+func TestCallQuorum(t *testing.T) {
+	ctx := context.Background()
+
+	server1 := startEth1Server(t, true, 100)
+	defer server1.Close()
+	server2 := startEth1Server(t, true, 100)
+	defer server2.Close()
+	server3 := startEth1Server(t, true, 100)
+	defer server3.Close()
+
+	endpointErr := &net.OpError{Op: "read", Err: errors.New("connection reset")}
+	hash := [32]byte{0x01, 0x02}
+	other := [32]byte{0x03}
+	tests := []struct {
+		name    string
+		results [][32]byte
+		errs    []error
+		res     [32]byte
+		err     string
+	}{
+		{
+			name:    "Agreed",
+			results: [][32]byte{hash, hash, other},
+			errs:    []error{nil, nil, nil},
+			res:     hash,
+		},
+		{
+			name:    "FailOver",
+			results: [][32]byte{hash, {}, hash},
+			errs:    []error{nil, endpointErr, nil},
+			res:     hash,
+		},
+		{
+			name:    "Conflicted",
+			results: [][32]byte{hash, other, hash},
+			errs:    []error{nil, nil, nil},
+			err:     fmt.Sprintf("endpoints disagree: %s returned %#x, expected %#x", server2.Listener.Addr().String(), other, hash),
+		},
+		{
+			name:    "RequestError",
+			results: [][32]byte{hash, {}, hash},
+			errs:    []error{nil, errors.New("execution reverted"), nil},
+			err:     "execution reverted",
+		},
+		{
+			name:    "NotReached",
+			results: [][32]byte{hash, {}, {}},
+			errs:    []error{nil, endpointErr, endpointErr},
+			err:     "quorum not reached: 1 of 2 required Ethereum 1 nodes answered",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := New(ctx,
+				WithLogLevel(zerolog.Disabled),
+				WithMonitor(nullmetrics.New()),
+				WithTimeout(time.Second),
+				WithConnectionURLs([]string{server1.URL, server2.URL, server3.URL}),
+				WithHealthCheckInterval(time.Hour),
+				WithQuorum(2),
+			)
+			require.NoError(t, err)
+			defer s.Close()
+
+			calls := 0
+			res, err := s.callQuorum(ctx, answers(s, test.results, test.errs, &calls))
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, test.res, res)
+			}
+		})
+	}
+}
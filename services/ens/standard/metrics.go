@@ -24,6 +24,12 @@ import (
 var metricsNamespace = "edcd"
 
 var requests *prometheus.GaugeVec
+var connected *prometheus.GaugeVec
+var healthChecks *prometheus.GaugeVec
+var reconnects *prometheus.GaugeVec
+var failovers *prometheus.GaugeVec
+var quorums *prometheus.GaugeVec
+var registrarVerifications *prometheus.GaugeVec
 
 func registerMetrics(ctx context.Context, monitor metrics.Service) error {
 	if requests != nil {
@@ -53,6 +59,78 @@ func registerPrometheusMetrics(ctx context.Context) error {
 		return errors.Wrap(err, "failed to register requests_total")
 	}
 
+	connected = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "ens",
+		Name:      "connected",
+		Help:      "1 if the Ethereum 1 node is connected and healthy, otherwise 0",
+	},
+		[]string{"endpoint"},
+	)
+	if err := prometheus.Register(connected); err != nil {
+		return errors.Wrap(err, "failed to register connected")
+	}
+
+	healthChecks = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "ens",
+		Name:      "health_checks_total",
+		Help:      "Health checks of the Ethereum 1 node",
+	},
+		[]string{"result"},
+	)
+	if err := prometheus.Register(healthChecks); err != nil {
+		return errors.Wrap(err, "failed to register health_checks_total")
+	}
+
+	reconnects = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "ens",
+		Name:      "reconnects_total",
+		Help:      "Reconnections to the Ethereum 1 node",
+	},
+		[]string{"endpoint"},
+	)
+	if err := prometheus.Register(reconnects); err != nil {
+		return errors.Wrap(err, "failed to register reconnects_total")
+	}
+
+	failovers = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "ens",
+		Name:      "failovers_total",
+		Help:      "Requests failed over from the Ethereum 1 node",
+	},
+		[]string{"endpoint"},
+	)
+	if err := prometheus.Register(failovers); err != nil {
+		return errors.Wrap(err, "failed to register failovers_total")
+	}
+
+	quorums = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "ens",
+		Name:      "quorum_total",
+		Help:      "Quorum reads of signature hashes",
+	},
+		[]string{"result"},
+	)
+	if err := prometheus.Register(quorums); err != nil {
+		return errors.Wrap(err, "failed to register quorum_total")
+	}
+
+	registrarVerifications = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "ens",
+		Name:      "registrar_verifications_total",
+		Help:      "Verifications of registrar contracts",
+	},
+		[]string{"result"},
+	)
+	if err := prometheus.Register(registrarVerifications); err != nil {
+		return errors.Wrap(err, "failed to register registrar_verifications_total")
+	}
+
 	return nil
 }
 
@@ -61,3 +139,43 @@ func requestHandled(result string) {
 		requests.WithLabelValues(result).Inc()
 	}
 }
+
+func connectionState(endpoint string, state bool) {
+	if connected != nil {
+		if state {
+			connected.WithLabelValues(endpoint).Set(1)
+		} else {
+			connected.WithLabelValues(endpoint).Set(0)
+		}
+	}
+}
+
+func healthChecked(result string) {
+	if healthChecks != nil {
+		healthChecks.WithLabelValues(result).Inc()
+	}
+}
+
+func reconnected(endpoint string) {
+	if reconnects != nil {
+		reconnects.WithLabelValues(endpoint).Inc()
+	}
+}
+
+func failedOver(endpoint string) {
+	if failovers != nil {
+		failovers.WithLabelValues(endpoint).Inc()
+	}
+}
+
+func quorumReached(result string) {
+	if quorums != nil {
+		quorums.WithLabelValues(result).Inc()
+	}
+}
+
+func registrarVerified(result string) {
+	if registrarVerifications != nil {
+		registrarVerifications.WithLabelValues(result).Inc()
+	}
+}
@@ -27,6 +27,12 @@ func TestRegisterMetrics(t *testing.T) {
 
 	// Ensure metrics handler can be called without failing.
 	requestHandled("success")
+	connectionState("localhost:8545", true)
+	healthChecked("succeeded")
+	reconnected("localhost:8545")
+	failedOver("localhost:8545")
+	quorumReached("agreed")
+	registrarVerified("verified")
 
 	// Ensure metrics can be registered without monitor.
 	require.NoError(t, registerMetrics(ctx, nil))
@@ -50,4 +56,10 @@ func TestRegisterMetrics(t *testing.T) {
 
 	// Ensure metrics handler can be called without failing.
 	requestHandled("success")
+	connectionState("localhost:8545", true)
+	healthChecked("succeeded")
+	reconnected("localhost:8545")
+	failedOver("localhost:8545")
+	quorumReached("agreed")
+	registrarVerified("verified")
 }
@@ -0,0 +1,39 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package standard
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// customNetwork is the name of the network profile configured by chain ID
+// and registry address.
+const customNetwork = "custom"
+
+// network is the profile of an Ethereum network on which ENS is deployed.
+type network struct {
+	chainID  uint64
+	registry common.Address
+}
+
+// ensRegistry is the address of the ENS registry on networks deployed by ENS.
+var ensRegistry = common.HexToAddress("0x00000000000C2E074eC69A0dFb2997BA6C7d2e1e")
+
+// networks are the known network profiles.
+var networks = map[string]*network{
+	"mainnet": {chainID: 1, registry: ensRegistry},
+	"ropsten": {chainID: 3, registry: ensRegistry},
+	"rinkeby": {chainID: 4, registry: ensRegistry},
+	"goerli":  {chainID: 5, registry: ensRegistry},
+}
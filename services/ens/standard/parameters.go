@@ -15,18 +15,29 @@ package standard
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/rs/zerolog"
 	"github.com/wealdtech/edcd/services/metrics"
 	nullmetrics "github.com/wealdtech/edcd/services/metrics/null"
 )
 
 type parameters struct {
-	logLevel      zerolog.Level
-	monitor       metrics.Service
-	timeout       time.Duration
-	connectionURL string
+	logLevel            zerolog.Level
+	monitor             metrics.Service
+	timeout             time.Duration
+	connectionURLs      []string
+	healthCheckInterval time.Duration
+	maxLag              uint64
+	quorum              int
+	network             string
+	chainID             uint64
+	registryAddress     common.Address
+
+	registrarPins                 map[string]*RegistrarPin
+	registrarVerificationInterval time.Duration
 }
 
 // Parameter is the interface for service parameters.
@@ -64,16 +75,93 @@ func WithTimeout(timeout time.Duration) Parameter {
 // WithConnectionURL sets the Ethereum 1 connection URL service for this module.
 func WithConnectionURL(url string) Parameter {
 	return parameterFunc(func(p *parameters) {
-		p.connectionURL = url
+		p.connectionURLs = []string{url}
+	})
+}
+
+// WithConnectionURLs sets multiple Ethereum 1 connection URLs for this
+// module.  Requests are routed to the healthiest node, failing over to the
+// others.
+func WithConnectionURLs(urls []string) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.connectionURLs = urls
+	})
+}
+
+// WithHealthCheckInterval sets the interval between health checks of the
+// connection to the Ethereum 1 node.
+func WithHealthCheckInterval(interval time.Duration) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.healthCheckInterval = interval
+	})
+}
+
+// WithMaxLag sets the number of blocks a node can lag behind the highest
+// node before it is considered unhealthy.
+func WithMaxLag(blocks uint64) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.maxLag = blocks
+	})
+}
+
+// WithQuorum sets the number of nodes that must return identical
+// signature hashes before one is returned.  A quorum of 1 uses a single node.
+func WithQuorum(quorum int) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.quorum = quorum
+	})
+}
+
+// WithNetwork sets the network profile, which provides the chain ID of the
+// network and the address of its ENS registry.  The "custom" network takes
+// these from WithChainID and WithRegistryAddress.
+func WithNetwork(network string) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.network = network
+	})
+}
+
+// WithChainID sets the chain ID of the custom network.
+func WithChainID(chainID uint64) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.chainID = chainID
+	})
+}
+
+// WithRegistryAddress sets the address of the ENS registry of the custom network.
+func WithRegistryAddress(address common.Address) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.registryAddress = address
+	})
+}
+
+// WithRegistrarPins sets the pins for the registrars of parent domains.
+func WithRegistrarPins(pins map[string]*RegistrarPin) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.registrarPins = pins
+	})
+}
+
+// WithRegistrarVerificationInterval sets the interval after which registrars
+// are verified again.
+func WithRegistrarVerificationInterval(interval time.Duration) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.registrarVerificationInterval = interval
 	})
 }
 
 // parseAndCheckParameters parses and checks parameters to ensure that mandatory parameters are present and correct.
 func parseAndCheckParameters(params ...Parameter) (*parameters, error) {
 	parameters := parameters{
-		logLevel: zerolog.GlobalLevel(),
-		monitor:  nullmetrics.New(),
-		timeout:  30 * time.Second,
+		logLevel:            zerolog.GlobalLevel(),
+		monitor:             nullmetrics.New(),
+		timeout:             30 * time.Second,
+		healthCheckInterval: 30 * time.Second,
+		maxLag:              5,
+		quorum:              1,
+		network:             "mainnet",
+
+		registrarVerificationInterval: time.Hour,
 	}
 	for _, p := range params {
 		if params != nil {
@@ -87,9 +175,44 @@ func parseAndCheckParameters(params ...Parameter) (*parameters, error) {
 	if parameters.monitor == nil {
 		return nil, errors.New("no monitor specified")
 	}
-	if parameters.connectionURL == "" {
+	if len(parameters.connectionURLs) == 0 {
 		return nil, errors.New("no connection URL specified")
 	}
+	for _, connectionURL := range parameters.connectionURLs {
+		if connectionURL == "" {
+			return nil, errors.New("empty connection URL specified")
+		}
+	}
+	if parameters.healthCheckInterval <= 0 {
+		return nil, errors.New("health check interval must be positive")
+	}
+	if parameters.registrarVerificationInterval <= 0 {
+		return nil, errors.New("registrar verification interval must be positive")
+	}
+	if parameters.quorum < 1 {
+		return nil, errors.New("quorum must be at least 1")
+	}
+	if parameters.quorum > len(parameters.connectionURLs) {
+		return nil, fmt.Errorf("quorum of %d exceeds %d connection URLs", parameters.quorum, len(parameters.connectionURLs))
+	}
+	if parameters.network == customNetwork {
+		if parameters.chainID == 0 {
+			return nil, errors.New("no chain ID specified for custom network")
+		}
+		if parameters.registryAddress == (common.Address{}) {
+			return nil, errors.New("no registry address specified for custom network")
+		}
+	} else {
+		network, exists := networks[parameters.network]
+		if !exists {
+			return nil, fmt.Errorf("unknown network %s", parameters.network)
+		}
+		if parameters.chainID != 0 || parameters.registryAddress != (common.Address{}) {
+			return nil, errors.New("chain ID and registry address can only be specified for custom network")
+		}
+		parameters.chainID = network.chainID
+		parameters.registryAddress = network.registry
+	}
 
 	return &parameters, nil
 }
@@ -16,16 +16,18 @@ package standard
 import (
 	"context"
 	"fmt"
-	"strings"
 
 	ethereum "github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/pkg/errors"
 	ens "github.com/wealdtech/go-ens/v3"
 )
 
-var registrarABI = `[{"inputs":[{"internalType":"bytes32","name":"node","type":"bytes32"},{"internalType":"address","name":"owner","type":"address"}],"name":"getSignatureHash","outputs":[{"internalType":"bytes32","name":"","type":"bytes32"}],"stateMutability":"view","type":"function"}]`
+var registrarABIJSON = `[{"inputs":[{"internalType":"bytes32","name":"node","type":"bytes32"},{"internalType":"address","name":"owner","type":"address"}],"name":"getSignatureHash","outputs":[{"internalType":"bytes32","name":"","type":"bytes32"}],"stateMutability":"view","type":"function"}]`
 
 // SignatureHash obtains the signature hash for a domain from its parent.
 func (s *Service) SignatureHash(ctx context.Context,
@@ -33,47 +35,122 @@ func (s *Service) SignatureHash(ctx context.Context,
 	domain string,
 	owner common.Address,
 ) (
-	[]byte,
+	[32]byte,
 	error,
 ) {
 	log := log.With().Str("domain", domain).Logger()
 
 	nameHash, err := ens.NameHash(name)
 	if err != nil {
-		return nil, err
+		return [32]byte{}, err
 	}
 	log.Trace().Str("name_hash", fmt.Sprintf("%#x", nameHash)).Msg("Calculated name hash")
 
-	abi, err := abi.JSON(strings.NewReader(registrarABI))
+	data, err := s.registrarABI.Pack("getSignatureHash", nameHash, owner)
 	if err != nil {
-		return nil, err
+		return [32]byte{}, err
 	}
 
-	data, err := abi.Pack("getSignatureHash", nameHash, owner)
-	if err != nil {
-		return nil, err
+	if s.quorum > 1 {
+		return s.callQuorum(ctx, func(client *ethclient.Client) ([32]byte, error) {
+			return s.signatureHash(ctx, client, domain, owner, data)
+		})
 	}
 
-	backend, err := ethclient.Dial(s.base.String())
-	if err != nil {
-		return nil, err
-	}
+	var res [32]byte
+	err = s.call(ctx, func(client *ethclient.Client) error {
+		var err error
+		res, err = s.signatureHash(ctx, client, domain, owner, data)
+		return err
+	})
+	return res, err
+}
 
-	registrarAddress, err := ens.RegistrarContractAddress(backend, domain)
+// signatureHash obtains the signature hash from the registrar of a domain
+// using a single node.
+func (s *Service) signatureHash(ctx context.Context,
+	client *ethclient.Client,
+	domain string,
+	owner common.Address,
+	data []byte,
+) (
+	[32]byte,
+	error,
+) {
+	registrarAddress, err := s.verifiedRegistrarAddress(ctx, client, domain)
 	if err != nil {
-		return nil, err
+		return [32]byte{}, err
 	}
-	log.Trace().Str("address", fmt.Sprintf("%#x", registrarAddress)).Msg("Obtained registrar address")
+	log.Trace().Str("domain", domain).Str("address", fmt.Sprintf("%#x", registrarAddress)).Msg("Obtained registrar address")
 
 	// TODO can from be 0?
 	msg := ethereum.CallMsg{From: owner, To: &registrarAddress, Data: data}
-	res, err := backend.CallContract(ctx, msg, nil)
+	res, err := client.CallContract(ctx, msg, nil)
 	if err != nil {
-		return nil, err
+		return [32]byte{}, registrarCallError(registrarAddress, err)
 	}
 
-	// TODO need to unpack this?
-	fmt.Printf("Res is %#x (%d)\n", res, len(res))
+	return s.unpackSignatureHash(registrarAddress, res)
+}
 
-	return res, nil
+// unpackSignatureHash decodes the result of a call to getSignatureHash.
+func (s *Service) unpackSignatureHash(registrarAddress common.Address, res []byte) ([32]byte, error) {
+	if len(res) == 0 {
+		// A call to an address without code returns no data, as does a
+		// revert without a reason on some nodes.
+		return [32]byte{}, fmt.Errorf("registrar %#x returned no data; the contract is missing or the call reverted", registrarAddress)
+	}
+	if len(res) != 32 {
+		return [32]byte{}, fmt.Errorf("registrar %#x returned %d bytes rather than a signature hash", registrarAddress, len(res))
+	}
+
+	values, err := s.registrarABI.Unpack("getSignatureHash", res)
+	if err != nil {
+		return [32]byte{}, errors.Wrapf(err, "failed to decode signature hash from registrar %#x", registrarAddress)
+	}
+	hash, isHash := values[0].([32]byte)
+	if !isHash {
+		return [32]byte{}, fmt.Errorf("registrar %#x returned %T rather than a signature hash", registrarAddress, values[0])
+	}
+
+	return hash, nil
+}
+
+// registrarCallError returns the error for a failed call to the registrar,
+// decoding the revert reason if the call reverted with one.
+func registrarCallError(registrarAddress common.Address, err error) error {
+	var dataErr rpc.DataError
+	if !errors.As(err, &dataErr) {
+		return err
+	}
+	encoded, isString := dataErr.ErrorData().(string)
+	if !isString {
+		return err
+	}
+	data, decodeErr := hexutil.Decode(encoded)
+	if decodeErr != nil {
+		return err
+	}
+	reason, unpackErr := abi.UnpackRevert(data)
+	if unpackErr != nil {
+		return errors.Wrapf(err, "registrar %#x reverted", registrarAddress)
+	}
+	return fmt.Errorf("registrar %#x reverted: %s", registrarAddress, reason)
+}
+
+// RegistrarAddress obtains the address of the registrar for a domain,
+// verifying it before it is returned.
+func (s *Service) RegistrarAddress(ctx context.Context,
+	domain string,
+) (
+	common.Address,
+	error,
+) {
+	var address common.Address
+	err := s.call(ctx, func(client *ethclient.Client) error {
+		var err error
+		address, err = s.verifiedRegistrarAddress(ctx, client, domain)
+		return err
+	})
+	return address, err
 }
@@ -0,0 +1,128 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package standard
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/stretchr/testify/require"
+)
+
+// dataError is an RPC error with data, as returned for reverted calls.
+type dataError struct {
+	data interface{}
+}
+
+func (e *dataError) Error() string          { return "execution reverted" }
+func (e *dataError) ErrorCode() int         { return 3 }
+func (e *dataError) ErrorData() interface{} { return e.data }
+
+func TestUnpackSignatureHash(t *testing.T) {
+	registrarABI, err := abi.JSON(strings.NewReader(registrarABIJSON))
+	require.NoError(t, err)
+	s := &Service{registrarABI: registrarABI}
+	registrar := common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314")
+
+	tests := []struct {
+		name string
+		res  []byte
+		hash [32]byte
+		err  string
+	}{
+		{
+			name: "Empty",
+			res:  []byte{},
+			err:  "registrar 0x0102030405060708090a0b0c0d0e0f1011121314 returned no data; the contract is missing or the call reverted",
+		},
+		{
+			name: "Short",
+			res:  []byte{0x01, 0x02},
+			err:  "registrar 0x0102030405060708090a0b0c0d0e0f1011121314 returned 2 bytes rather than a signature hash",
+		},
+		{
+			name: "Good",
+			res:  common.FromHex("0x000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"),
+			hash: [32]byte{
+				0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f,
+				0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18, 0x19, 0x1a, 0x1b, 0x1c, 0x1d, 0x1e, 0x1f,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hash, err := s.unpackSignatureHash(registrar, test.res)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, test.hash, hash)
+			}
+		})
+	}
+}
+
+func TestRegistrarCallError(t *testing.T) {
+	registrar := common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314")
+
+	stringType, err := abi.NewType("string", "", nil)
+	require.NoError(t, err)
+	reason, err := abi.Arguments{{Type: stringType}}.Pack("claim not permitted")
+	require.NoError(t, err)
+	revertData := hexutil.Encode(append(common.FromHex("0x08c379a0"), reason...))
+
+	tests := []struct {
+		name string
+		err  error
+		res  string
+	}{
+		{
+			name: "Plain",
+			err:  context.DeadlineExceeded,
+			res:  "context deadline exceeded",
+		},
+		{
+			name: "DataNotString",
+			err:  &dataError{data: 1},
+			res:  "execution reverted",
+		},
+		{
+			name: "DataNotHex",
+			err:  &dataError{data: "invalid"},
+			res:  "execution reverted",
+		},
+		{
+			name: "NoReason",
+			err:  &dataError{data: "0x12345678"},
+			res:  "registrar 0x0102030405060708090a0b0c0d0e0f1011121314 reverted: execution reverted",
+		},
+		{
+			name: "Reason",
+			err:  &dataError{data: revertData},
+			res:  "registrar 0x0102030405060708090a0b0c0d0e0f1011121314 reverted: claim not permitted",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := registrarCallError(registrar, test.err)
+			require.EqualError(t, err, test.res)
+		})
+	}
+}
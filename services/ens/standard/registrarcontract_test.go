@@ -33,7 +33,7 @@ func TestSignatureHash(t *testing.T) {
 		domain string
 		owner  common.Address
 		err    string
-		res    []byte
+		res    [32]byte
 	}{
 		{
 			name:   "sub.wealdtech.eth",
@@ -0,0 +1,266 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package standard
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/pkg/errors"
+)
+
+var erc165ABIJSON = `[{"inputs":[{"internalType":"bytes4","name":"interfaceID","type":"bytes4"}],"name":"supportsInterface","outputs":[{"internalType":"bool","name":"","type":"bool"}],"stateMutability":"view","type":"function"}]`
+
+// erc165InterfaceID is the interface ID of ERC-165 itself.
+var erc165InterfaceID = [4]byte{0x01, 0xff, 0xc9, 0xa7}
+
+// invalidInterfaceID is the interface ID that no ERC-165 contract supports.
+var invalidInterfaceID = [4]byte{0xff, 0xff, 0xff, 0xff}
+
+// erc165Gas is the gas for supportsInterface calls defined by ERC-165.
+const erc165Gas = 30000
+
+// codeHashRegexp matches a 32-byte hex hash.
+var codeHashRegexp = regexp.MustCompile("^0x[0-9a-fA-F]{64}$")
+
+// interfaceIDRegexp matches a 4-byte hex interface ID.
+var interfaceIDRegexp = regexp.MustCompile("^0x[0-9a-fA-F]{8}$")
+
+// defaultRegistrarInterfaceID calculates the ERC-165 interface ID of the
+// registrar from its ABI.  As per ERC-165 this is the XOR of the selectors
+// of the functions in the interface; the registrar ABI contains only
+// getSignatureHash(bytes32,address), so it is that function's selector.
+// Registrars that declare a wider interface must be pinned with their
+// interface ID.
+func defaultRegistrarInterfaceID(registrarABI abi.ABI) [4]byte {
+	var interfaceID [4]byte
+	for _, method := range registrarABI.Methods {
+		for i := range interfaceID {
+			interfaceID[i] ^= method.ID[i]
+		}
+	}
+	return interfaceID
+}
+
+// RegistrarPin pins the registrar of a parent domain.
+type RegistrarPin struct {
+	// Address is the address that the registrar must have, if set.
+	Address common.Address
+	// CodeHash is the hash of the runtime code that the registrar must have, if set.
+	CodeHash common.Hash
+	// InterfaceID is the ERC-165 interface ID that the registrar must support, if set.
+	// If not set the interface ID is calculated from the registrar ABI.
+	InterfaceID [4]byte
+}
+
+// ParseRegistrarPin parses the registrar pin in the configuration of a
+// domain control, returning nil if the domain control does not pin its
+// registrar.
+func ParseRegistrarPin(config map[string]interface{}) (*RegistrarPin, error) {
+	pin := &RegistrarPin{}
+	pinned := false
+	if input, exists := config["registrar-address"]; exists {
+		address, isString := input.(string)
+		if !isString || !common.IsHexAddress(address) || common.HexToAddress(address) == (common.Address{}) {
+			return nil, errors.New("registrar-address invalid")
+		}
+		pin.Address = common.HexToAddress(address)
+		pinned = true
+	}
+	if input, exists := config["registrar-code-hash"]; exists {
+		codeHash, isString := input.(string)
+		if !isString || !codeHashRegexp.MatchString(codeHash) {
+			return nil, errors.New("registrar-code-hash invalid")
+		}
+		pin.CodeHash = common.HexToHash(codeHash)
+		pinned = true
+	}
+	if input, exists := config["registrar-interface-id"]; exists {
+		interfaceID, isString := input.(string)
+		if !isString || !interfaceIDRegexp.MatchString(interfaceID) {
+			return nil, errors.New("registrar-interface-id invalid")
+		}
+		copy(pin.InterfaceID[:], common.FromHex(interfaceID))
+		pinned = true
+	}
+	if !pinned {
+		return nil, nil
+	}
+	return pin, nil
+}
+
+// verifiedRegistrar is a registrar that passed verification.
+type verifiedRegistrar struct {
+	address    common.Address
+	verifiedAt time.Time
+}
+
+// verifiedRegistrarAddress obtains the address of the registrar for a
+// domain, verifying it unless it matches a recent verification.
+func (s *Service) verifiedRegistrarAddress(ctx context.Context, client *ethclient.Client, domain string) (common.Address, error) {
+	address, err := s.registrarContractAddress(ctx, client, domain)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	s.registrarsMu.RLock()
+	cached, exists := s.registrars[domain]
+	s.registrarsMu.RUnlock()
+	if exists && cached.address == address && time.Since(cached.verifiedAt) < s.registrarVerificationInterval {
+		return address, nil
+	}
+
+	if err := s.verifyRegistrar(ctx, client, domain, address); err != nil {
+		return common.Address{}, err
+	}
+	return address, nil
+}
+
+// verifyRegistrar checks the registrar for a domain against its pin and that
+// it implements the registrar interface, recording the result.
+func (s *Service) verifyRegistrar(ctx context.Context, client *ethclient.Client, domain string, address common.Address) error {
+	if err := s.checkRegistrar(ctx, client, domain, address); err != nil {
+		if isEndpointError(err) {
+			// The node failed, rather than the registrar.
+			registrarVerified("failed")
+			return err
+		}
+		registrarVerified("refused")
+		log.Warn().Str("domain", domain).Str("registrar", address.Hex()).Err(err).Msg("Registrar failed verification")
+		s.registrarsMu.Lock()
+		delete(s.registrars, domain)
+		s.registrarsMu.Unlock()
+		return err
+	}
+
+	registrarVerified("verified")
+	log.Trace().Str("domain", domain).Str("registrar", address.Hex()).Msg("Verified registrar")
+	s.registrarsMu.Lock()
+	s.registrars[domain] = &verifiedRegistrar{
+		address:    address,
+		verifiedAt: time.Now(),
+	}
+	s.registrarsMu.Unlock()
+	return nil
+}
+
+// checkRegistrar checks the registrar for a domain against its pin and that
+// it implements the registrar interface according to ERC-165.
+func (s *Service) checkRegistrar(ctx context.Context, client *ethclient.Client, domain string, address common.Address) error {
+	pin := s.registrarPins[domain]
+	if pin != nil && pin.Address != (common.Address{}) && pin.Address != address {
+		return fmt.Errorf("registrar %#x for %s does not match pinned address %#x", address, domain, pin.Address)
+	}
+
+	code, err := client.CodeAt(ctx, address, nil)
+	if err != nil {
+		return err
+	}
+	if len(code) == 0 {
+		return fmt.Errorf("registrar %#x for %s has no code", address, domain)
+	}
+	if pin != nil && pin.CodeHash != (common.Hash{}) {
+		if codeHash := crypto.Keccak256Hash(code); codeHash != pin.CodeHash {
+			return fmt.Errorf("registrar %#x for %s has code hash %#x, pinned %#x", address, domain, codeHash, pin.CodeHash)
+		}
+	}
+
+	// ERC-165 detection requires that the contract supports ERC-165 and
+	// does not claim to support the invalid interface.
+	supported, err := s.supportsInterface(ctx, client, address, erc165InterfaceID)
+	if err != nil {
+		return err
+	}
+	if supported {
+		supported, err = s.supportsInterface(ctx, client, address, invalidInterfaceID)
+		if err != nil {
+			return err
+		}
+		supported = !supported
+	}
+	if !supported {
+		return fmt.Errorf("registrar %#x for %s does not support ERC-165", address, domain)
+	}
+
+	interfaceID := s.registrarInterfaceID
+	if pin != nil && pin.InterfaceID != ([4]byte{}) {
+		interfaceID = pin.InterfaceID
+	}
+	supported, err = s.supportsInterface(ctx, client, address, interfaceID)
+	if err != nil {
+		return err
+	}
+	if !supported {
+		return fmt.Errorf("registrar %#x for %s does not implement the registrar interface %#x", address, domain, interfaceID)
+	}
+
+	return nil
+}
+
+// supportsInterface calls supportsInterface on a contract.  A call that
+// fails or returns an invalid result means that the interface is not
+// supported, unless the node itself failed.
+func (s *Service) supportsInterface(ctx context.Context, client *ethclient.Client, address common.Address, interfaceID [4]byte) (bool, error) {
+	data, err := s.erc165ABI.Pack("supportsInterface", interfaceID)
+	if err != nil {
+		return false, err
+	}
+	res, err := client.CallContract(ctx, ethereum.CallMsg{To: &address, Gas: erc165Gas, Data: data}, nil)
+	if err != nil {
+		if isEndpointError(err) {
+			return false, err
+		}
+		return false, nil
+	}
+	if len(res) != 32 {
+		return false, nil
+	}
+	values, err := s.erc165ABI.Unpack("supportsInterface", res)
+	if err != nil {
+		return false, nil
+	}
+	supported, isBool := values[0].(bool)
+	return isBool && supported, nil
+}
+
+// reverifyRegistrars verifies the registrars that have been verified before,
+// so that a registrar that changes is noticed before it is next used.
+func (s *Service) reverifyRegistrars(ctx context.Context) {
+	s.registrarsMu.RLock()
+	domains := make([]string, 0, len(s.registrars))
+	for domain := range s.registrars {
+		domains = append(domains, domain)
+	}
+	s.registrarsMu.RUnlock()
+
+	for _, domain := range domains {
+		err := s.call(ctx, func(client *ethclient.Client) error {
+			address, err := s.registrarContractAddress(ctx, client, domain)
+			if err != nil {
+				return err
+			}
+			return s.verifyRegistrar(ctx, client, domain, address)
+		})
+		if err != nil && ctx.Err() == nil {
+			log.Warn().Str("domain", domain).Err(err).Msg("Failed to re-verify registrar")
+		}
+	}
+}
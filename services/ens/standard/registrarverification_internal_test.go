@@ -0,0 +1,277 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package standard
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	nullmetrics "github.com/wealdtech/edcd/services/metrics/null"
+)
+
+func TestParseRegistrarPin(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[string]interface{}
+		pin    *RegistrarPin
+		err    string
+	}{
+		{
+			name:   "None",
+			config: map[string]interface{}{"signing-mode": "registrar"},
+		},
+		{
+			name:   "AddressInvalid",
+			config: map[string]interface{}{"registrar-address": "0x1234"},
+			err:    "registrar-address invalid",
+		},
+		{
+			name:   "AddressZero",
+			config: map[string]interface{}{"registrar-address": "0x0000000000000000000000000000000000000000"},
+			err:    "registrar-address invalid",
+		},
+		{
+			name:   "AddressNotString",
+			config: map[string]interface{}{"registrar-address": 1},
+			err:    "registrar-address invalid",
+		},
+		{
+			name:   "CodeHashShort",
+			config: map[string]interface{}{"registrar-code-hash": "0x0102"},
+			err:    "registrar-code-hash invalid",
+		},
+		{
+			name:   "CodeHashNotHex",
+			config: map[string]interface{}{"registrar-code-hash": "0xzz02030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20"},
+			err:    "registrar-code-hash invalid",
+		},
+		{
+			name:   "InterfaceIDShort",
+			config: map[string]interface{}{"registrar-interface-id": "0x0102"},
+			err:    "registrar-interface-id invalid",
+		},
+		{
+			name:   "InterfaceIDNotString",
+			config: map[string]interface{}{"registrar-interface-id": 1},
+			err:    "registrar-interface-id invalid",
+		},
+		{
+			name:   "InterfaceID",
+			config: map[string]interface{}{"registrar-interface-id": "0x01020304"},
+			pin: &RegistrarPin{
+				InterfaceID: [4]byte{0x01, 0x02, 0x03, 0x04},
+			},
+		},
+		{
+			name:   "Address",
+			config: map[string]interface{}{"registrar-address": "0x0102030405060708090a0b0c0d0e0f1011121314"},
+			pin: &RegistrarPin{
+				Address: common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314"),
+			},
+		},
+		{
+			name: "AddressAndCodeHash",
+			config: map[string]interface{}{
+				"registrar-address":   "0x0102030405060708090a0b0c0d0e0f1011121314",
+				"registrar-code-hash": "0x0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20",
+			},
+			pin: &RegistrarPin{
+				Address:  common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314"),
+				CodeHash: common.HexToHash("0x0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20"),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pin, err := ParseRegistrarPin(test.config)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, test.pin, pin)
+			}
+		})
+	}
+}
+
+func TestDefaultRegistrarInterfaceID(t *testing.T) {
+	registrarABI, err := abi.JSON(strings.NewReader(registrarABIJSON))
+	require.NoError(t, err)
+
+	// The registrar interface contains only getSignatureHash, so its ID is
+	// that function's selector.
+	var expected [4]byte
+	copy(expected[:], crypto.Keccak256([]byte("getSignatureHash(bytes32,address)")))
+	require.Equal(t, [4]byte{0x93, 0x9a, 0x8f, 0x8d}, expected)
+	require.Equal(t, expected, defaultRegistrarInterfaceID(registrarABI))
+
+	// The ID of a wider interface is the XOR of its selectors.
+	wideABI, err := abi.JSON(strings.NewReader(`[` +
+		`{"inputs":[{"internalType":"bytes32","name":"node","type":"bytes32"},{"internalType":"address","name":"owner","type":"address"}],"name":"getSignatureHash","outputs":[{"internalType":"bytes32","name":"","type":"bytes32"}],"stateMutability":"view","type":"function"},` +
+		`{"inputs":[{"internalType":"bytes4","name":"interfaceID","type":"bytes4"}],"name":"supportsInterface","outputs":[{"internalType":"bool","name":"","type":"bool"}],"stateMutability":"view","type":"function"}` +
+		`]`))
+	require.NoError(t, err)
+	require.Equal(t, [4]byte{0x93 ^ 0x01, 0x9a ^ 0xff, 0x8f ^ 0xc9, 0x8d ^ 0xa7}, defaultRegistrarInterfaceID(wideABI))
+}
+
+func TestCheckRegistrar(t *testing.T) {
+	ctx := context.Background()
+
+	server := startEth1Server(t, true, 16)
+	defer server.Close()
+
+	code := []byte{0x60, 0x80, 0x60, 0x40}
+	otherCode := []byte{0x60, 0x80}
+	address := common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314")
+	otherAddress := common.HexToAddress("0x1415161718191a1b1c1d1e1f2021222324252627")
+
+	tests := []struct {
+		name       string
+		code       []byte
+		interfaces [][4]byte
+		pin        *RegistrarPin
+		err        string
+	}{
+		{
+			name: "NoCode",
+			err:  "registrar 0x0102030405060708090a0b0c0d0e0f1011121314 for example.com has no code",
+		},
+		{
+			name: "NoERC165",
+			code: code,
+			err:  "registrar 0x0102030405060708090a0b0c0d0e0f1011121314 for example.com does not support ERC-165",
+		},
+		{
+			name:       "InvalidInterface",
+			code:       code,
+			interfaces: [][4]byte{erc165InterfaceID, invalidInterfaceID},
+			err:        "registrar 0x0102030405060708090a0b0c0d0e0f1011121314 for example.com does not support ERC-165",
+		},
+		{
+			name:       "NoRegistrarInterface",
+			code:       code,
+			interfaces: [][4]byte{erc165InterfaceID},
+			err:        "registrar 0x0102030405060708090a0b0c0d0e0f1011121314 for example.com does not implement the registrar interface 0x939a8f8d",
+		},
+		{
+			name:       "AddressPinMismatch",
+			code:       code,
+			interfaces: [][4]byte{erc165InterfaceID, {0x93, 0x9a, 0x8f, 0x8d}},
+			pin:        &RegistrarPin{Address: otherAddress},
+			err:        "registrar 0x0102030405060708090a0b0c0d0e0f1011121314 for example.com does not match pinned address 0x1415161718191a1b1c1d1e1f2021222324252627",
+		},
+		{
+			name:       "CodeHashPinMismatch",
+			code:       code,
+			interfaces: [][4]byte{erc165InterfaceID, {0x93, 0x9a, 0x8f, 0x8d}},
+			pin:        &RegistrarPin{CodeHash: crypto.Keccak256Hash(otherCode)},
+			err:        "registrar 0x0102030405060708090a0b0c0d0e0f1011121314 for example.com has code hash 0x" + common.Bytes2Hex(crypto.Keccak256(code)) + ", pinned 0x" + common.Bytes2Hex(crypto.Keccak256(otherCode)),
+		},
+		{
+			name:       "InterfaceIDPinMismatch",
+			code:       code,
+			interfaces: [][4]byte{erc165InterfaceID, {0x93, 0x9a, 0x8f, 0x8d}},
+			pin:        &RegistrarPin{InterfaceID: [4]byte{0x01, 0x02, 0x03, 0x04}},
+			err:        "registrar 0x0102030405060708090a0b0c0d0e0f1011121314 for example.com does not implement the registrar interface 0x01020304",
+		},
+		{
+			name:       "GoodInterfaceIDPinned",
+			code:       code,
+			interfaces: [][4]byte{erc165InterfaceID, {0x01, 0x02, 0x03, 0x04}},
+			pin:        &RegistrarPin{InterfaceID: [4]byte{0x01, 0x02, 0x03, 0x04}},
+		},
+		{
+			name:       "Good",
+			code:       code,
+			interfaces: [][4]byte{erc165InterfaceID, {0x93, 0x9a, 0x8f, 0x8d}},
+		},
+		{
+			name:       "GoodPinned",
+			code:       code,
+			interfaces: [][4]byte{erc165InterfaceID, {0x93, 0x9a, 0x8f, 0x8d}},
+			pin:        &RegistrarPin{Address: address, CodeHash: crypto.Keccak256Hash(code)},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pins := make(map[string]*RegistrarPin)
+			if test.pin != nil {
+				pins["example.com"] = test.pin
+			}
+			s, err := New(ctx,
+				WithLogLevel(zerolog.Disabled),
+				WithMonitor(nullmetrics.New()),
+				WithTimeout(time.Second),
+				WithConnectionURL(server.URL),
+				WithHealthCheckInterval(time.Hour),
+				WithRegistrarPins(pins),
+			)
+			require.NoError(t, err)
+			defer s.Close()
+
+			server.setContract(test.code, test.interfaces...)
+			err = s.checkRegistrar(ctx, s.endpoints[0].ethClient(), "example.com", address)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestVerifyRegistrar(t *testing.T) {
+	ctx := context.Background()
+
+	server := startEth1Server(t, true, 16)
+	defer server.Close()
+
+	s, err := New(ctx,
+		WithLogLevel(zerolog.Disabled),
+		WithMonitor(nullmetrics.New()),
+		WithTimeout(time.Second),
+		WithConnectionURL(server.URL),
+		WithHealthCheckInterval(time.Hour),
+	)
+	require.NoError(t, err)
+	defer s.Close()
+	client := s.endpoints[0].ethClient()
+	address := common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314")
+
+	// A registrar that passes verification is cached.
+	server.setContract([]byte{0x60, 0x80}, erc165InterfaceID, s.registrarInterfaceID)
+	require.NoError(t, s.verifyRegistrar(ctx, client, "example.com", address))
+	require.Contains(t, s.registrars, "example.com")
+	require.Equal(t, address, s.registrars["example.com"].address)
+
+	// The cached verification survives a node failure.
+	server.setHealthy(false)
+	require.Error(t, s.verifyRegistrar(ctx, client, "example.com", address))
+	require.Contains(t, s.registrars, "example.com")
+
+	// The cached verification is dropped when the registrar fails verification.
+	server.setHealthy(true)
+	server.setContract([]byte{0x60, 0x80}, erc165InterfaceID)
+	require.Error(t, s.verifyRegistrar(ctx, client, "example.com", address))
+	require.NotContains(t, s.registrars, "example.com")
+}
@@ -0,0 +1,87 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package standard
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/pkg/errors"
+	ens "github.com/wealdtech/go-ens/v3"
+	"github.com/wealdtech/go-ens/v3/contracts/registry"
+	"github.com/wealdtech/go-ens/v3/contracts/resolver"
+)
+
+// registrarContractAddress obtains the address of the registrar for a domain
+// from the network's registry.
+func (s *Service) registrarContractAddress(ctx context.Context, client *ethclient.Client, domain string) (common.Address, error) {
+	nameHash, err := ens.NameHash(domain)
+	if err != nil {
+		return common.Address{}, err
+	}
+	registryContract, err := registry.NewContractCaller(s.registry, client)
+	if err != nil {
+		return common.Address{}, err
+	}
+	address, err := registryContract.Owner(&bind.CallOpts{Context: ctx}, nameHash)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if address == (common.Address{}) {
+		return common.Address{}, fmt.Errorf("no registrar for %s", domain)
+	}
+	return address, nil
+}
+
+// resolve resolves a name to its address using the network's registry.
+func (s *Service) resolve(ctx context.Context, client *ethclient.Client, name string) (common.Address, error) {
+	nameHash, err := ens.NameHash(name)
+	if err != nil {
+		return common.Address{}, err
+	}
+	opts := &bind.CallOpts{Context: ctx}
+	registryContract, err := registry.NewContractCaller(s.registry, client)
+	if err != nil {
+		return common.Address{}, err
+	}
+	owner, err := registryContract.Owner(opts, nameHash)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if owner == (common.Address{}) {
+		return common.Address{}, errors.New("unregistered name")
+	}
+	resolverAddress, err := registryContract.Resolver(opts, nameHash)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if resolverAddress == (common.Address{}) {
+		return common.Address{}, errors.New("no resolver")
+	}
+	resolverContract, err := resolver.NewContractCaller(resolverAddress, client)
+	if err != nil {
+		return common.Address{}, err
+	}
+	address, err := resolverContract.Addr(opts, nameHash)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if address == (common.Address{}) {
+		return common.Address{}, errors.New("no address")
+	}
+	return address, nil
+}
@@ -0,0 +1,52 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package standard
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	nullmetrics "github.com/wealdtech/edcd/services/metrics/null"
+)
+
+func TestRegistryContext(t *testing.T) {
+	ctx := context.Background()
+
+	server := startEth1Server(t, true, 16)
+	defer server.Close()
+
+	s, err := New(ctx,
+		WithLogLevel(zerolog.Disabled),
+		WithMonitor(nullmetrics.New()),
+		WithTimeout(time.Second),
+		WithConnectionURL(server.URL),
+		WithHealthCheckInterval(time.Hour),
+	)
+	require.NoError(t, err)
+	defer s.Close()
+	client := s.endpoints[0].ethClient()
+
+	// Calls to the registry and resolver are abandoned with their context.
+	cancelledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+
+	_, err = s.registrarContractAddress(cancelledCtx, client, "example.com")
+	require.ErrorIs(t, err, context.Canceled)
+
+	_, err = s.resolve(cancelledCtx, client, "test.example.com")
+	require.ErrorIs(t, err, context.Canceled)
+}
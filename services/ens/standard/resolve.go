@@ -0,0 +1,43 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package standard
+
+import (
+	"context"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/pkg/errors"
+)
+
+// Resolve resolves an ENS name to the address in its addr record.
+func (s *Service) Resolve(ctx context.Context,
+	name string,
+) (
+	common.Address,
+	error,
+) {
+	if !strings.Contains(name, ".") {
+		return common.Address{}, errors.New("invalid name")
+	}
+
+	var address common.Address
+	err := s.call(ctx, func(client *ethclient.Client) error {
+		var err error
+		address, err = s.resolve(ctx, client, name)
+		return err
+	})
+	return address, err
+}
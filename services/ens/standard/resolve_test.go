@@ -0,0 +1,61 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package standard_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"github.com/wealdtech/edcd/services/ens/standard"
+	nullmetrics "github.com/wealdtech/edcd/services/metrics/null"
+)
+
+func TestResolveInvalid(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name  string
+		input string
+		err   string
+	}{
+		{
+			name:  "Empty",
+			input: "",
+			err:   "invalid name",
+		},
+		{
+			name:  "Address",
+			input: "0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5",
+			err:   "invalid name",
+		},
+	}
+
+	s, err := standard.New(ctx,
+		standard.WithLogLevel(zerolog.Disabled),
+		standard.WithMonitor(nullmetrics.New()),
+		standard.WithTimeout(10*time.Second),
+		standard.WithConnectionURL("localhost:8545/"),
+	)
+	require.NoError(t, err)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := s.Resolve(ctx, test.input)
+			require.EqualError(t, err, test.err)
+		})
+	}
+}
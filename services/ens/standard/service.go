@@ -16,10 +16,12 @@ package standard
 import (
 	"context"
 	"fmt"
-	"net/url"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 	zerologger "github.com/rs/zerolog/log"
@@ -27,8 +29,25 @@ import (
 
 // Service is the ENS service.
 type Service struct {
-	base    *url.URL
-	timeout time.Duration
+	endpoints           []*endpoint
+	timeout             time.Duration
+	healthCheckInterval time.Duration
+	maxLag              uint64
+	quorum              int
+	chainID             uint64
+	registry            common.Address
+	registrarABI        abi.ABI
+	erc165ABI           abi.ABI
+
+	registrarInterfaceID          [4]byte
+	registrarPins                 map[string]*RegistrarPin
+	registrarVerificationInterval time.Duration
+	registrarsMu                  sync.RWMutex
+	registrars                    map[string]*verifiedRegistrar
+
+	cancelMonitor context.CancelFunc
+	monitorDone   chan struct{}
+	closeOnce     sync.Once
 }
 
 // module-wide log.
@@ -51,20 +70,62 @@ func New(ctx context.Context, params ...Parameter) (*Service, error) {
 		return nil, errors.New("failed to register metrics")
 	}
 
-	// Connect to Ethereum 1.
-	connectionURL := parameters.connectionURL
-	if !strings.HasPrefix(connectionURL, "http") {
-		connectionURL = fmt.Sprintf("http://%s", parameters.connectionURL)
+	registrarABI, err := abi.JSON(strings.NewReader(registrarABIJSON))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse registrar ABI")
 	}
-	base, err := url.Parse(connectionURL)
+	erc165ABI, err := abi.JSON(strings.NewReader(erc165ABIJSON))
 	if err != nil {
-		return nil, errors.Wrap(err, "invalid URL")
+		return nil, errors.Wrap(err, "failed to parse ERC-165 ABI")
 	}
 
 	s := &Service{
-		base:    base,
-		timeout: parameters.timeout,
+		endpoints:           make([]*endpoint, 0, len(parameters.connectionURLs)),
+		timeout:             parameters.timeout,
+		healthCheckInterval: parameters.healthCheckInterval,
+		maxLag:              parameters.maxLag,
+		quorum:              parameters.quorum,
+		chainID:             parameters.chainID,
+		registry:            parameters.registryAddress,
+		registrarABI:        registrarABI,
+		erc165ABI:           erc165ABI,
+		monitorDone:         make(chan struct{}),
+
+		registrarInterfaceID:          defaultRegistrarInterfaceID(registrarABI),
+		registrarPins:                 parameters.registrarPins,
+		registrarVerificationInterval: parameters.registrarVerificationInterval,
+		registrars:                    make(map[string]*verifiedRegistrar),
 	}
 
+	// Connect to Ethereum 1.  Connections are long-lived, and replaced if
+	// they fail health checks.
+	for _, connectionURL := range parameters.connectionURLs {
+		e, err := newEndpoint(connectionURL)
+		if err != nil {
+			return nil, err
+		}
+		if err := e.dial(ctx, s.timeout); err != nil {
+			s.closeEndpoints()
+			return nil, err
+		}
+		s.endpoints = append(s.endpoints, e)
+	}
+	s.checkEndpoints(ctx)
+	for _, e := range s.endpoints {
+		// Refuse to start with a node on another chain, as claims for one
+		// chain must not be produced from another.
+		if chainID := e.observedChainID(); chainID != 0 && chainID != s.chainID {
+			s.closeEndpoints()
+			return nil, fmt.Errorf("connected node %s is on chain %d, expected %d", e.name, chainID, s.chainID)
+		}
+	}
+	if !s.isConnected() {
+		log.Warn().Msg("No Ethereum 1 node passed initial health check")
+	}
+
+	monitorCtx, cancel := context.WithCancel(ctx)
+	s.cancelMonitor = cancel
+	go s.monitorConnections(monitorCtx)
+
 	return s, nil
 }
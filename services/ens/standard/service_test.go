@@ -18,6 +18,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/require"
 	"github.com/wealdtech/edcd/services/ens/standard"
@@ -73,6 +74,107 @@ func TestService(t *testing.T) {
 			},
 			err: "invalid URL: parse \"http://\\a\\b\": net/url: invalid control character in URL",
 		},
+		{
+			name: "HealthCheckIntervalZero",
+			params: []standard.Parameter{
+				standard.WithLogLevel(zerolog.Disabled),
+				standard.WithMonitor(monitor),
+				standard.WithTimeout(10 * time.Second),
+				standard.WithConnectionURL("localhost:8545/"),
+				standard.WithHealthCheckInterval(0),
+			},
+			err: "problem with parameters: health check interval must be positive",
+		},
+		{
+			name: "RegistrarVerificationIntervalZero",
+			params: []standard.Parameter{
+				standard.WithLogLevel(zerolog.Disabled),
+				standard.WithMonitor(monitor),
+				standard.WithTimeout(10 * time.Second),
+				standard.WithConnectionURL("localhost:8545/"),
+				standard.WithRegistrarVerificationInterval(0),
+			},
+			err: "problem with parameters: registrar verification interval must be positive",
+		},
+		{
+			name: "ConnectionURLEmpty",
+			params: []standard.Parameter{
+				standard.WithLogLevel(zerolog.Disabled),
+				standard.WithMonitor(monitor),
+				standard.WithTimeout(10 * time.Second),
+				standard.WithConnectionURLs([]string{"localhost:8545/", ""}),
+			},
+			err: "problem with parameters: empty connection URL specified",
+		},
+		{
+			name: "QuorumZero",
+			params: []standard.Parameter{
+				standard.WithLogLevel(zerolog.Disabled),
+				standard.WithMonitor(monitor),
+				standard.WithTimeout(10 * time.Second),
+				standard.WithConnectionURL("localhost:8545/"),
+				standard.WithQuorum(0),
+			},
+			err: "problem with parameters: quorum must be at least 1",
+		},
+		{
+			name: "QuorumTooHigh",
+			params: []standard.Parameter{
+				standard.WithLogLevel(zerolog.Disabled),
+				standard.WithMonitor(monitor),
+				standard.WithTimeout(10 * time.Second),
+				standard.WithConnectionURLs([]string{"localhost:8545/", "localhost:8546/"}),
+				standard.WithQuorum(3),
+			},
+			err: "problem with parameters: quorum of 3 exceeds 2 connection URLs",
+		},
+		{
+			name: "NetworkUnknown",
+			params: []standard.Parameter{
+				standard.WithLogLevel(zerolog.Disabled),
+				standard.WithMonitor(monitor),
+				standard.WithTimeout(10 * time.Second),
+				standard.WithConnectionURL("localhost:8545/"),
+				standard.WithNetwork("unknown"),
+			},
+			err: "problem with parameters: unknown network unknown",
+		},
+		{
+			name: "CustomChainIDMissing",
+			params: []standard.Parameter{
+				standard.WithLogLevel(zerolog.Disabled),
+				standard.WithMonitor(monitor),
+				standard.WithTimeout(10 * time.Second),
+				standard.WithConnectionURL("localhost:8545/"),
+				standard.WithNetwork("custom"),
+				standard.WithRegistryAddress(common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314")),
+			},
+			err: "problem with parameters: no chain ID specified for custom network",
+		},
+		{
+			name: "CustomRegistryMissing",
+			params: []standard.Parameter{
+				standard.WithLogLevel(zerolog.Disabled),
+				standard.WithMonitor(monitor),
+				standard.WithTimeout(10 * time.Second),
+				standard.WithConnectionURL("localhost:8545/"),
+				standard.WithNetwork("custom"),
+				standard.WithChainID(1337),
+			},
+			err: "problem with parameters: no registry address specified for custom network",
+		},
+		{
+			name: "NetworkOverride",
+			params: []standard.Parameter{
+				standard.WithLogLevel(zerolog.Disabled),
+				standard.WithMonitor(monitor),
+				standard.WithTimeout(10 * time.Second),
+				standard.WithConnectionURL("localhost:8545/"),
+				standard.WithNetwork("mainnet"),
+				standard.WithChainID(1337),
+			},
+			err: "problem with parameters: chain ID and registry address can only be specified for custom network",
+		},
 		{
 			name: "Good",
 			params: []standard.Parameter{
@@ -82,15 +184,39 @@ func TestService(t *testing.T) {
 				standard.WithConnectionURL("localhost:8545/"),
 			},
 		},
+		{
+			name: "GoodMultiple",
+			params: []standard.Parameter{
+				standard.WithLogLevel(zerolog.Disabled),
+				standard.WithMonitor(monitor),
+				standard.WithTimeout(10 * time.Second),
+				standard.WithConnectionURLs([]string{"localhost:8545/", "localhost:8546/"}),
+				standard.WithQuorum(2),
+				standard.WithMaxLag(10),
+			},
+		},
+		{
+			name: "GoodCustomNetwork",
+			params: []standard.Parameter{
+				standard.WithLogLevel(zerolog.Disabled),
+				standard.WithMonitor(monitor),
+				standard.WithTimeout(10 * time.Second),
+				standard.WithConnectionURL("localhost:8545/"),
+				standard.WithNetwork("custom"),
+				standard.WithChainID(1337),
+				standard.WithRegistryAddress(common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314")),
+			},
+		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			_, err := standard.New(ctx, test.params...)
+			s, err := standard.New(ctx, test.params...)
 			if test.err != "" {
 				require.EqualError(t, err, test.err)
 			} else {
 				require.NoError(t, err)
+				s.Close()
 			}
 		})
 	}
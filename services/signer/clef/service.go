@@ -0,0 +1,70 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clef
+
+import (
+	"context"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	zerologger "github.com/rs/zerolog/log"
+)
+
+// Service is a signer service that forwards requests to an external
+// signer speaking the Clef JSON-RPC API.
+//
+// Note that Clef has no method that signs a raw hash, so this service
+// cannot sign hashes.  EIP-712 claims are signed with account_signTypedData.
+type Service struct {
+	client  *rpc.Client
+	timeout time.Duration
+}
+
+// module-wide log.
+var log zerolog.Logger
+
+// New creates a new Clef signer service.
+func New(ctx context.Context, params ...Parameter) (*Service, error) {
+	parameters, err := parseAndCheckParameters(params...)
+	if err != nil {
+		return nil, errors.Wrap(err, "problem with parameters")
+	}
+
+	// Set logging.
+	log = zerologger.With().Str("service", "signer").Str("impl", "clef").Logger()
+	if parameters.logLevel != log.GetLevel() {
+		log = log.Level(parameters.logLevel)
+	}
+
+	if err := registerMetrics(ctx, parameters.monitor); err != nil {
+		return nil, errors.New("failed to register metrics")
+	}
+
+	dialCtx, cancel := context.WithTimeout(ctx, parameters.timeout)
+	defer cancel()
+	client, err := rpc.DialContext(dialCtx, parameters.endpoint)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to connect to signer")
+	}
+	log.Trace().Str("endpoint", parameters.endpoint).Msg("Connected to signer")
+
+	s := &Service{
+		client:  client,
+		timeout: parameters.timeout,
+	}
+
+	return s, nil
+}
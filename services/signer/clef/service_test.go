@@ -0,0 +1,97 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clef_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	nullmetrics "github.com/wealdtech/edcd/services/metrics/null"
+	"github.com/wealdtech/edcd/services/signer/clef"
+)
+
+func TestService(t *testing.T) {
+	ctx := context.Background()
+
+	monitor := nullmetrics.New()
+
+	tests := []struct {
+		name   string
+		params []clef.Parameter
+		err    string
+	}{
+		{
+			name: "MonitorMissing",
+			params: []clef.Parameter{
+				clef.WithLogLevel(zerolog.Disabled),
+				clef.WithMonitor(nil),
+				clef.WithTimeout(10 * time.Second),
+				clef.WithEndpoint("http://localhost:8550/"),
+			},
+			err: "problem with parameters: no monitor specified",
+		},
+		{
+			name: "TimeoutZero",
+			params: []clef.Parameter{
+				clef.WithLogLevel(zerolog.Disabled),
+				clef.WithMonitor(monitor),
+				clef.WithTimeout(0),
+				clef.WithEndpoint("http://localhost:8550/"),
+			},
+			err: "problem with parameters: no timeout specified",
+		},
+		{
+			name: "EndpointMissing",
+			params: []clef.Parameter{
+				clef.WithLogLevel(zerolog.Disabled),
+				clef.WithMonitor(monitor),
+				clef.WithTimeout(10 * time.Second),
+			},
+			err: "problem with parameters: no endpoint specified",
+		},
+		{
+			name: "EndpointBad",
+			params: []clef.Parameter{
+				clef.WithLogLevel(zerolog.Disabled),
+				clef.WithMonitor(monitor),
+				clef.WithTimeout(10 * time.Second),
+				clef.WithEndpoint("foo://localhost:8550/"),
+			},
+			err: "failed to connect to signer: no known transport for URL scheme \"foo\"",
+		},
+		{
+			name: "Good",
+			params: []clef.Parameter{
+				clef.WithLogLevel(zerolog.Disabled),
+				clef.WithMonitor(monitor),
+				clef.WithTimeout(10 * time.Second),
+				clef.WithEndpoint("http://localhost:8550/"),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := clef.New(ctx, test.params...)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
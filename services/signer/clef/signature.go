@@ -0,0 +1,34 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clef
+
+import (
+	"fmt"
+)
+
+// normalizeSignature checks a signature returned by the signer and returns it
+// in the form expected by ecrecover.
+func normalizeSignature(sig []byte) ([]byte, error) {
+	if len(sig) != 65 {
+		return nil, fmt.Errorf("signer returned signature of invalid length %d", len(sig))
+	}
+	res := make([]byte, 65)
+	copy(res, sig)
+	// Some signers return V as 0 or 1, but ecrecover requires 27 or 28.
+	if res[64] < 27 {
+		res[64] += 27
+	}
+
+	return res, nil
+}
@@ -0,0 +1,77 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clef
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeSignature(t *testing.T) {
+	tests := []struct {
+		name     string
+		sig      []byte
+		expected []byte
+		err      string
+	}{
+		{
+			name: "Nil",
+			err:  "signer returned signature of invalid length 0",
+		},
+		{
+			name: "Short",
+			sig:  bytes.Repeat([]byte{0x01}, 64),
+			err:  "signer returned signature of invalid length 64",
+		},
+		{
+			name: "Long",
+			sig:  bytes.Repeat([]byte{0x01}, 66),
+			err:  "signer returned signature of invalid length 66",
+		},
+		{
+			name:     "V0",
+			sig:      append(bytes.Repeat([]byte{0x01}, 64), 0x00),
+			expected: append(bytes.Repeat([]byte{0x01}, 64), 0x1b),
+		},
+		{
+			name:     "V1",
+			sig:      append(bytes.Repeat([]byte{0x01}, 64), 0x01),
+			expected: append(bytes.Repeat([]byte{0x01}, 64), 0x1c),
+		},
+		{
+			name:     "V27",
+			sig:      append(bytes.Repeat([]byte{0x01}, 64), 0x1b),
+			expected: append(bytes.Repeat([]byte{0x01}, 64), 0x1b),
+		},
+		{
+			name:     "V28",
+			sig:      append(bytes.Repeat([]byte{0x01}, 64), 0x1c),
+			expected: append(bytes.Repeat([]byte{0x01}, 64), 0x1c),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := normalizeSignature(test.sig)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, test.expected, res)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clef
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+// SignHash is not supported by Clef.  Clef's account_signData signs the
+// EIP-191 personal message hash of the data it is given rather than the data
+// itself, and Clef has no method that signs a raw hash, so any signature it
+// returned would not be over the supplied hash.
+func (s *Service) SignHash(_ context.Context,
+	_ common.Address,
+	_ [32]byte,
+) (
+	[]byte,
+	error,
+) {
+	return nil, errors.New("clef signer does not support signing raw hashes")
+}
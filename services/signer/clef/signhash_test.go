@@ -0,0 +1,50 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clef_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"github.com/wealdtech/edcd/services/signer/clef"
+	"github.com/wealdtech/edcd/services/signer/clef/standin"
+)
+
+func TestSignHash(t *testing.T) {
+	ctx := context.Background()
+
+	key, err := crypto.HexToECDSA("0101010101010101010101010101010101010101010101010101010101010101")
+	require.NoError(t, err)
+	address := crypto.PubkeyToAddress(key.PublicKey)
+
+	server, err := standin.New(key)
+	require.NoError(t, err)
+	defer server.Close()
+
+	s, err := clef.New(ctx,
+		clef.WithLogLevel(zerolog.Disabled),
+		clef.WithTimeout(10*time.Second),
+		clef.WithEndpoint(server.Endpoint()),
+	)
+	require.NoError(t, err)
+
+	// Clef cannot sign a raw hash, so the request must be refused rather
+	// than returning a signature over a different hash.
+	_, err = s.SignHash(ctx, address, [32]byte{0x01})
+	require.EqualError(t, err, "clef signer does not support signing raw hashes")
+}
@@ -0,0 +1,51 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clef
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/pkg/errors"
+	"github.com/wealdtech/edcd/util/eip712"
+)
+
+// SignTypedData signs EIP-712 typed data with the key for the given address.
+func (s *Service) SignTypedData(ctx context.Context,
+	address common.Address,
+	data *eip712.TypedData,
+) (
+	[]byte,
+	error,
+) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	var res hexutil.Bytes
+	if err := s.client.CallContext(ctx, &res, "account_signTypedData", address.Hex(), data); err != nil {
+		requestHandled("failed")
+		return nil, errors.Wrap(err, "failed to obtain signature from signer")
+	}
+	sig, err := normalizeSignature(res)
+	if err != nil {
+		requestHandled("invalid")
+		return nil, err
+	}
+	log.Trace().Str("address", fmt.Sprintf("%#x", address)).Str("signature", fmt.Sprintf("%#x", sig)).Msg("Signed typed data")
+
+	requestHandled("succeeded")
+	return sig, nil
+}
@@ -0,0 +1,106 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clef_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"github.com/wealdtech/edcd/services/signer/clef"
+	"github.com/wealdtech/edcd/services/signer/clef/standin"
+	"github.com/wealdtech/edcd/util/eip712"
+)
+
+func TestSignTypedData(t *testing.T) {
+	ctx := context.Background()
+
+	key, err := crypto.HexToECDSA("0101010101010101010101010101010101010101010101010101010101010101")
+	require.NoError(t, err)
+	address := crypto.PubkeyToAddress(key.PublicKey)
+
+	server, err := standin.New(key)
+	require.NoError(t, err)
+	defer server.Close()
+
+	domain := &eip712.Domain{
+		Name:              "ENS DNS claim",
+		Version:           "1",
+		ChainID:           big.NewInt(5),
+		VerifyingContract: common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314"),
+	}
+	parentNode := common.HexToHash("0x87e957ac2ae4a1e2e5d5dc2deb3b6a03e2aa62d5e3cc81da5bbd1b7b83823169")
+	owner := common.HexToAddress("0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5")
+	unsupported := eip712.ClaimTypedData(domain, parentNode, "test", owner)
+	unsupported.PrimaryType = "Mail"
+
+	tests := []struct {
+		name    string
+		address common.Address
+		data    *eip712.TypedData
+		err     string
+	}{
+		{
+			name:    "UnknownAccount",
+			address: owner,
+			data:    eip712.ClaimTypedData(domain, parentNode, "test", owner),
+			err:     "failed to obtain signature from signer: unknown account",
+		},
+		{
+			name:    "UnsupportedType",
+			address: address,
+			data:    unsupported,
+			err:     "failed to obtain signature from signer: unsupported primary type Mail",
+		},
+		{
+			name:    "Good",
+			address: address,
+			data:    eip712.ClaimTypedData(domain, parentNode, "test", owner),
+		},
+	}
+
+	s, err := clef.New(ctx,
+		clef.WithLogLevel(zerolog.Disabled),
+		clef.WithTimeout(10*time.Second),
+		clef.WithEndpoint(server.Endpoint()),
+	)
+	require.NoError(t, err)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sig, err := s.SignTypedData(ctx, test.address, test.data)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+			} else {
+				require.NoError(t, err)
+				require.Len(t, sig, 65)
+				require.Contains(t, []byte{27, 28}, sig[64])
+
+				// Typed data is signed over its EIP-712 hash, with no prefix.
+				hash := eip712.ClaimHash(domain, parentNode, "test", owner)
+				recoverable := make([]byte, 65)
+				copy(recoverable, sig)
+				recoverable[64] -= 27
+				pubKey, err := crypto.SigToPub(hash[:], recoverable)
+				require.NoError(t, err)
+				require.Equal(t, test.address, crypto.PubkeyToAddress(*pubKey))
+			}
+		})
+	}
+}
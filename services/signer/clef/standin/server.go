@@ -0,0 +1,172 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package standin provides a local stand-in for a Clef signer.  It holds
+// keys in memory and approves every request, so must only be used in tests.
+package standin
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/pkg/errors"
+	"github.com/wealdtech/edcd/util/eip712"
+)
+
+// Server is a stand-in Clef server.
+type Server struct {
+	endpoint string
+	rpcSrv   *rpc.Server
+	listener net.Listener
+	httpSrv  *http.Server
+}
+
+// New creates a stand-in Clef server listening for HTTP on a loopback port.
+func New(keys ...*ecdsa.PrivateKey) (*Server, error) {
+	rpcSrv, err := newRPCServer(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to listen")
+	}
+
+	s := &Server{
+		endpoint: fmt.Sprintf("http://%s/", listener.Addr().String()),
+		rpcSrv:   rpcSrv,
+		listener: listener,
+		httpSrv:  &http.Server{Handler: rpcSrv},
+	}
+	go func() {
+		_ = s.httpSrv.Serve(listener)
+	}()
+
+	return s, nil
+}
+
+// NewIPC creates a stand-in Clef server listening on an IPC socket at the given path.
+func NewIPC(path string, keys ...*ecdsa.PrivateKey) (*Server, error) {
+	rpcSrv, err := newRPCServer(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to listen")
+	}
+
+	s := &Server{
+		endpoint: path,
+		rpcSrv:   rpcSrv,
+		listener: listener,
+	}
+	go func() {
+		_ = rpcSrv.ServeListener(listener)
+	}()
+
+	return s, nil
+}
+
+// Endpoint returns the endpoint on which the server is listening.
+func (s *Server) Endpoint() string {
+	return s.endpoint
+}
+
+// Close stops the server.
+func (s *Server) Close() {
+	if s.httpSrv != nil {
+		_ = s.httpSrv.Shutdown(context.Background())
+	} else {
+		_ = s.listener.Close()
+	}
+	s.rpcSrv.Stop()
+}
+
+func newRPCServer(keys []*ecdsa.PrivateKey) (*rpc.Server, error) {
+	api := &accountAPI{
+		keys: make(map[common.Address]*ecdsa.PrivateKey),
+	}
+	for _, key := range keys {
+		api.keys[crypto.PubkeyToAddress(key.PublicKey)] = key
+	}
+
+	rpcSrv := rpc.NewServer()
+	if err := rpcSrv.RegisterName("account", api); err != nil {
+		return nil, errors.Wrap(err, "failed to register API")
+	}
+	return rpcSrv, nil
+}
+
+// accountAPI provides the subset of Clef's account API used by edcd.
+// Typed data is limited to claims.
+type accountAPI struct {
+	keys map[common.Address]*ecdsa.PrivateKey
+}
+
+// SignData signs data in the same way as Clef's account_signData.
+func (a *accountAPI) SignData(ctx context.Context, contentType string, addr common.MixedcaseAddress, data hexutil.Bytes) (hexutil.Bytes, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, err
+	}
+	switch mediaType {
+	case accounts.MimetypeDataWithValidator, accounts.MimetypeTypedData, accounts.MimetypeClique:
+		return nil, fmt.Errorf("content type %s not supported", mediaType)
+	}
+
+	key, exists := a.keys[addr.Address()]
+	if !exists {
+		return nil, errors.New("unknown account")
+	}
+
+	// As per Clef, all other content types are treated as text/plain.
+	sig, err := crypto.Sign(accounts.TextHash(data), key)
+	if err != nil {
+		return nil, err
+	}
+	sig[64] += 27
+
+	return sig, nil
+}
+
+// SignTypedData signs claim typed data in the same way as Clef's account_signTypedData.
+func (a *accountAPI) SignTypedData(ctx context.Context, addr common.MixedcaseAddress, data eip712.TypedData) (hexutil.Bytes, error) {
+	key, exists := a.keys[addr.Address()]
+	if !exists {
+		return nil, errors.New("unknown account")
+	}
+
+	hash, err := data.ClaimHash()
+	if err != nil {
+		return nil, err
+	}
+	sig, err := crypto.Sign(hash[:], key)
+	if err != nil {
+		return nil, err
+	}
+	sig[64] += 27
+
+	return sig, nil
+}
@@ -0,0 +1,89 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keystore
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+// loadKey loads and decrypts the key for the given address.
+// The path can either be a keystore directory or a single key file.
+func loadKey(path string, address common.Address, passphrase string) (*ecdsa.PrivateKey, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to access keystore")
+	}
+
+	keyFile := path
+	if info.IsDir() {
+		keyFile, err = findKeyFile(path, address)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read key file")
+	}
+	key, err := keystore.DecryptKey(data, passphrase)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to decrypt key for %#x", address)
+	}
+	if key.Address != address {
+		return nil, fmt.Errorf("key file %s does not hold the key for %#x", keyFile, address)
+	}
+
+	return key.PrivateKey, nil
+}
+
+// findKeyFile finds the key file for the given address in a keystore directory.
+func findKeyFile(dir string, address common.Address) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read keystore directory")
+	}
+
+	for _, entry := range entries {
+		// Skip directories, and editor and hidden files.
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") || strings.HasSuffix(entry.Name(), "~") {
+			continue
+		}
+		keyFile := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			continue
+		}
+		keyJSON := struct {
+			Address string `json:"address"`
+		}{}
+		if err := json.Unmarshal(data, &keyJSON); err != nil {
+			continue
+		}
+		if common.IsHexAddress(keyJSON.Address) && common.HexToAddress(keyJSON.Address) == address {
+			return keyFile, nil
+		}
+	}
+
+	return "", fmt.Errorf("no key for %#x in %s", address, dir)
+}
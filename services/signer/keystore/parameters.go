@@ -0,0 +1,102 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keystore
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/rs/zerolog"
+	"github.com/wealdtech/edcd/services/metrics"
+	nullmetrics "github.com/wealdtech/edcd/services/metrics/null"
+)
+
+type parameters struct {
+	logLevel   zerolog.Level
+	monitor    metrics.Service
+	path       string
+	address    common.Address
+	passphrase string
+}
+
+// Parameter is the interface for service parameters.
+type Parameter interface {
+	apply(*parameters)
+}
+
+type parameterFunc func(*parameters)
+
+func (f parameterFunc) apply(p *parameters) {
+	f(p)
+}
+
+// WithLogLevel sets the log level for the module.
+func WithLogLevel(logLevel zerolog.Level) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.logLevel = logLevel
+	})
+}
+
+// WithMonitor sets the monitor for the module.
+func WithMonitor(monitor metrics.Service) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.monitor = monitor
+	})
+}
+
+// WithPath sets the path to the keystore directory.
+func WithPath(path string) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.path = path
+	})
+}
+
+// WithAddress sets the address of the account used for signing.
+func WithAddress(address common.Address) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.address = address
+	})
+}
+
+// WithPassphrase sets the passphrase used to unlock the account.
+func WithPassphrase(passphrase string) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.passphrase = passphrase
+	})
+}
+
+// parseAndCheckParameters parses and checks parameters to ensure that mandatory parameters are present and correct.
+func parseAndCheckParameters(params ...Parameter) (*parameters, error) {
+	parameters := parameters{
+		logLevel: zerolog.GlobalLevel(),
+		monitor:  nullmetrics.New(),
+	}
+	for _, p := range params {
+		if params != nil {
+			p.apply(&parameters)
+		}
+	}
+
+	if parameters.monitor == nil {
+		return nil, errors.New("no monitor specified")
+	}
+	if parameters.path == "" {
+		return nil, errors.New("no path specified")
+	}
+	if parameters.address == (common.Address{}) {
+		return nil, errors.New("no address specified")
+	}
+
+	return &parameters, nil
+}
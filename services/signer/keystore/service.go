@@ -0,0 +1,65 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keystore
+
+import (
+	"context"
+	"crypto/ecdsa"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	zerologger "github.com/rs/zerolog/log"
+)
+
+// Service is a signer service backed by an encrypted Geth keystore.
+type Service struct {
+	address common.Address
+	key     *ecdsa.PrivateKey
+}
+
+// module-wide log.
+var log zerolog.Logger
+
+// New creates a new keystore signer service.
+func New(ctx context.Context, params ...Parameter) (*Service, error) {
+	parameters, err := parseAndCheckParameters(params...)
+	if err != nil {
+		return nil, errors.Wrap(err, "problem with parameters")
+	}
+
+	// Set logging.
+	log = zerologger.With().Str("service", "signer").Str("impl", "keystore").Logger()
+	if parameters.logLevel != log.GetLevel() {
+		log = log.Level(parameters.logLevel)
+	}
+
+	if err := registerMetrics(ctx, parameters.monitor); err != nil {
+		return nil, errors.New("failed to register metrics")
+	}
+
+	// Load and decrypt the key up front, so that problems are found at startup.
+	key, err := loadKey(parameters.path, parameters.address, parameters.passphrase)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load key")
+	}
+	log.Trace().Str("path", parameters.path).Str("address", parameters.address.Hex()).Msg("Loaded key")
+
+	s := &Service{
+		address: parameters.address,
+		key:     key,
+	}
+
+	return s, nil
+}
@@ -0,0 +1,155 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keystore_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	gethkeystore "github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	nullmetrics "github.com/wealdtech/edcd/services/metrics/null"
+	"github.com/wealdtech/edcd/services/signer/keystore"
+)
+
+func TestService(t *testing.T) {
+	ctx := context.Background()
+
+	monitor := nullmetrics.New()
+
+	path := t.TempDir()
+	ks := gethkeystore.NewKeyStore(path, gethkeystore.LightScryptN, gethkeystore.LightScryptP)
+	account, err := ks.NewAccount("a secret")
+	require.NoError(t, err)
+	otherAccount, err := ks.NewAccount("a secret")
+	require.NoError(t, err)
+	unknownAddress := common.HexToAddress("0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5")
+
+	tests := []struct {
+		name   string
+		params []keystore.Parameter
+		err    string
+	}{
+		{
+			name: "MonitorMissing",
+			params: []keystore.Parameter{
+				keystore.WithLogLevel(zerolog.Disabled),
+				keystore.WithMonitor(nil),
+				keystore.WithPath(path),
+				keystore.WithAddress(account.Address),
+				keystore.WithPassphrase("a secret"),
+			},
+			err: "problem with parameters: no monitor specified",
+		},
+		{
+			name: "PathMissing",
+			params: []keystore.Parameter{
+				keystore.WithLogLevel(zerolog.Disabled),
+				keystore.WithMonitor(monitor),
+				keystore.WithAddress(account.Address),
+				keystore.WithPassphrase("a secret"),
+			},
+			err: "problem with parameters: no path specified",
+		},
+		{
+			name: "AddressMissing",
+			params: []keystore.Parameter{
+				keystore.WithLogLevel(zerolog.Disabled),
+				keystore.WithMonitor(monitor),
+				keystore.WithPath(path),
+				keystore.WithPassphrase("a secret"),
+			},
+			err: "problem with parameters: no address specified",
+		},
+		{
+			name: "PathInvalid",
+			params: []keystore.Parameter{
+				keystore.WithLogLevel(zerolog.Disabled),
+				keystore.WithMonitor(monitor),
+				keystore.WithPath(filepath.Join(path, "missing")),
+				keystore.WithAddress(account.Address),
+				keystore.WithPassphrase("a secret"),
+			},
+			err: fmt.Sprintf("failed to load key: failed to access keystore: stat %s: no such file or directory", filepath.Join(path, "missing")),
+		},
+		{
+			name: "KeyMissing",
+			params: []keystore.Parameter{
+				keystore.WithLogLevel(zerolog.Disabled),
+				keystore.WithMonitor(monitor),
+				keystore.WithPath(path),
+				keystore.WithAddress(unknownAddress),
+				keystore.WithPassphrase("a secret"),
+			},
+			err: fmt.Sprintf("failed to load key: no key for 0x388ea662ef2c223ec0b047d41bf3c0f362142ad5 in %s", path),
+		},
+		{
+			name: "PassphraseIncorrect",
+			params: []keystore.Parameter{
+				keystore.WithLogLevel(zerolog.Disabled),
+				keystore.WithMonitor(monitor),
+				keystore.WithPath(path),
+				keystore.WithAddress(account.Address),
+				keystore.WithPassphrase("wrong"),
+			},
+			err: fmt.Sprintf("failed to load key: failed to decrypt key for %#x: could not decrypt key with given password", account.Address),
+		},
+		{
+			name: "KeyFileMismatch",
+			params: []keystore.Parameter{
+				keystore.WithLogLevel(zerolog.Disabled),
+				keystore.WithMonitor(monitor),
+				keystore.WithPath(otherAccount.URL.Path),
+				keystore.WithAddress(account.Address),
+				keystore.WithPassphrase("a secret"),
+			},
+			err: fmt.Sprintf("failed to load key: key file %s does not hold the key for %#x", otherAccount.URL.Path, account.Address),
+		},
+		{
+			name: "GoodDirectory",
+			params: []keystore.Parameter{
+				keystore.WithLogLevel(zerolog.Disabled),
+				keystore.WithMonitor(monitor),
+				keystore.WithPath(path),
+				keystore.WithAddress(account.Address),
+				keystore.WithPassphrase("a secret"),
+			},
+		},
+		{
+			name: "GoodFile",
+			params: []keystore.Parameter{
+				keystore.WithLogLevel(zerolog.Disabled),
+				keystore.WithMonitor(monitor),
+				keystore.WithPath(account.URL.Path),
+				keystore.WithAddress(account.Address),
+				keystore.WithPassphrase("a secret"),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := keystore.New(ctx, test.params...)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keystore
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+)
+
+// SignHash signs a 32-byte hash with the key for the given address.
+func (s *Service) SignHash(ctx context.Context,
+	address common.Address,
+	hash [32]byte,
+) (
+	[]byte,
+	error,
+) {
+	if address != s.address {
+		requestHandled("unknown_account")
+		return nil, fmt.Errorf("unknown account %#x", address)
+	}
+
+	sig, err := crypto.Sign(hash[:], s.key)
+	if err != nil {
+		requestHandled("failed")
+		return nil, errors.Wrap(err, "failed to sign hash")
+	}
+	// Go-ethereum returns V as 0 or 1, but ecrecover requires 27 or 28.
+	sig[64] += 27
+	log.Trace().Str("address", fmt.Sprintf("%#x", address)).Str("signature", fmt.Sprintf("%#x", sig)).Msg("Signed hash")
+
+	requestHandled("succeeded")
+	return sig, nil
+}
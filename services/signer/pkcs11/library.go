@@ -0,0 +1,96 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build cgo
+// +build cgo
+
+package pkcs11
+
+import (
+	"fmt"
+	"sync"
+
+	p11 "github.com/miekg/pkcs11"
+	"github.com/pkg/errors"
+)
+
+// library is a loaded PKCS#11 library shared by the services that use it.
+type library struct {
+	ctx         *p11.Ctx
+	initialised bool
+	refs        int
+}
+
+var (
+	librariesMu sync.Mutex
+	libraries   = make(map[string]*library)
+)
+
+// openLibrary loads and initialises the PKCS#11 library at the given path,
+// or returns the context for the library if it is already open.
+// Each call must be matched by a call to closeLibrary.
+func openLibrary(path string) (*p11.Ctx, error) {
+	librariesMu.Lock()
+	defer librariesMu.Unlock()
+
+	if lib, exists := libraries[path]; exists {
+		lib.refs++
+		return lib.ctx, nil
+	}
+
+	ctx := p11.New(path)
+	if ctx == nil {
+		return nil, fmt.Errorf("failed to load PKCS#11 library %s", path)
+	}
+	// The library may have been initialised outside of this module, in
+	// which case it is left for its initialiser to finalise.
+	initialised := false
+	if err := ctx.Initialize(); err != nil {
+		if err != p11.Error(p11.CKR_CRYPTOKI_ALREADY_INITIALIZED) {
+			ctx.Destroy()
+			return nil, errors.Wrap(err, "failed to initialise PKCS#11 library")
+		}
+	} else {
+		initialised = true
+	}
+	libraries[path] = &library{
+		ctx:         ctx,
+		initialised: initialised,
+		refs:        1,
+	}
+
+	return ctx, nil
+}
+
+// closeLibrary releases a reference to the PKCS#11 library at the given path,
+// finalising and unloading it when it is no longer in use.
+func closeLibrary(path string) {
+	librariesMu.Lock()
+	defer librariesMu.Unlock()
+
+	lib, exists := libraries[path]
+	if !exists {
+		return
+	}
+	lib.refs--
+	if lib.refs > 0 {
+		return
+	}
+	delete(libraries, path)
+	if lib.initialised {
+		if err := lib.ctx.Finalize(); err != nil {
+			log.Warn().Str("library", path).Err(err).Msg("Failed to finalise PKCS#11 library")
+		}
+	}
+	lib.ctx.Destroy()
+}
@@ -0,0 +1,142 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build cgo
+// +build cgo
+
+package pkcs11
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/rs/zerolog"
+	"github.com/wealdtech/edcd/services/metrics"
+	nullmetrics "github.com/wealdtech/edcd/services/metrics/null"
+)
+
+type parameters struct {
+	logLevel   zerolog.Level
+	monitor    metrics.Service
+	library    string
+	slot       *uint
+	tokenLabel string
+	pin        string
+	keyLabel   string
+	address    common.Address
+}
+
+// Parameter is the interface for service parameters.
+type Parameter interface {
+	apply(*parameters)
+}
+
+type parameterFunc func(*parameters)
+
+func (f parameterFunc) apply(p *parameters) {
+	f(p)
+}
+
+// WithLogLevel sets the log level for the module.
+func WithLogLevel(logLevel zerolog.Level) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.logLevel = logLevel
+	})
+}
+
+// WithMonitor sets the monitor for the module.
+func WithMonitor(monitor metrics.Service) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.monitor = monitor
+	})
+}
+
+// WithLibrary sets the path to the PKCS#11 library.
+func WithLibrary(library string) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.library = library
+	})
+}
+
+// WithSlot sets the slot of the token holding the key.
+func WithSlot(slot uint) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.slot = &slot
+	})
+}
+
+// WithTokenLabel sets the label of the token holding the key, as an
+// alternative to its slot.
+func WithTokenLabel(tokenLabel string) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.tokenLabel = tokenLabel
+	})
+}
+
+// WithPIN sets the user PIN for the token.
+func WithPIN(pin string) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.pin = pin
+	})
+}
+
+// WithKeyLabel sets the label of the key on the token.
+func WithKeyLabel(keyLabel string) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.keyLabel = keyLabel
+	})
+}
+
+// WithAddress sets the address of the account used for signing.
+func WithAddress(address common.Address) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.address = address
+	})
+}
+
+// parseAndCheckParameters parses and checks parameters to ensure that mandatory parameters are present and correct.
+func parseAndCheckParameters(params ...Parameter) (*parameters, error) {
+	parameters := parameters{
+		logLevel: zerolog.GlobalLevel(),
+		monitor:  nullmetrics.New(),
+	}
+	for _, p := range params {
+		if params != nil {
+			p.apply(&parameters)
+		}
+	}
+
+	if parameters.monitor == nil {
+		return nil, errors.New("no monitor specified")
+	}
+	if parameters.library == "" {
+		return nil, errors.New("no library specified")
+	}
+	if parameters.slot == nil && parameters.tokenLabel == "" {
+		return nil, errors.New("no slot or token label specified")
+	}
+	if parameters.slot != nil && parameters.tokenLabel != "" {
+		return nil, errors.New("both slot and token label specified")
+	}
+	if parameters.pin == "" {
+		return nil, errors.New("no PIN specified")
+	}
+	if parameters.keyLabel == "" {
+		return nil, errors.New("no key label specified")
+	}
+	if parameters.address == (common.Address{}) {
+		return nil, errors.New("no address specified")
+	}
+
+	return &parameters, nil
+}
@@ -0,0 +1,210 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build cgo
+// +build cgo
+
+package pkcs11
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	p11 "github.com/miekg/pkcs11"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	zerologger "github.com/rs/zerolog/log"
+)
+
+// Service is a signer service backed by a key held in a PKCS#11 token.
+type Service struct {
+	mutex     sync.Mutex
+	closeOnce sync.Once
+	library   string
+	ctx       *p11.Ctx
+	session   p11.SessionHandle
+	key       p11.ObjectHandle
+	address   common.Address
+	pubKey    *ecdsa.PublicKey
+}
+
+// module-wide log.
+var log zerolog.Logger
+
+// New creates a new PKCS#11 signer service.
+func New(ctx context.Context, params ...Parameter) (*Service, error) {
+	parameters, err := parseAndCheckParameters(params...)
+	if err != nil {
+		return nil, errors.Wrap(err, "problem with parameters")
+	}
+
+	// Set logging.
+	log = zerologger.With().Str("service", "signer").Str("impl", "pkcs11").Logger()
+	if parameters.logLevel != log.GetLevel() {
+		log = log.Level(parameters.logLevel)
+	}
+
+	if err := registerMetrics(ctx, parameters.monitor); err != nil {
+		return nil, errors.New("failed to register metrics")
+	}
+
+	p11Ctx, err := openLibrary(parameters.library)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Service{
+		library: parameters.library,
+		ctx:     p11Ctx,
+		address: parameters.address,
+	}
+
+	slot, err := s.findSlot(parameters.slot, parameters.tokenLabel)
+	if err != nil {
+		closeLibrary(parameters.library)
+		return nil, err
+	}
+
+	// Find the key up front, so that problems are found at startup.
+	if err := s.openKey(slot, parameters.pin, parameters.keyLabel); err != nil {
+		closeLibrary(parameters.library)
+		return nil, err
+	}
+	log.Trace().Uint("slot", slot).Str("label", parameters.keyLabel).Str("address", parameters.address.Hex()).Msg("Opened key")
+
+	return s, nil
+}
+
+// Close closes the session with the token, and releases the library.
+func (s *Service) Close() {
+	s.closeOnce.Do(func() {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		if err := s.ctx.CloseSession(s.session); err != nil {
+			log.Warn().Err(err).Msg("Failed to close session")
+		}
+		closeLibrary(s.library)
+	})
+}
+
+// findSlot finds the slot to use, either as supplied or as the slot holding
+// the token with the given label.
+func (s *Service) findSlot(slot *uint, tokenLabel string) (uint, error) {
+	if slot != nil {
+		return *slot, nil
+	}
+
+	slots, err := s.ctx.GetSlotList(true)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to obtain slots")
+	}
+	found := make([]uint, 0, 1)
+	for _, candidate := range slots {
+		info, err := s.ctx.GetTokenInfo(candidate)
+		if err != nil {
+			return 0, errors.Wrapf(err, "failed to obtain token information for slot %d", candidate)
+		}
+		// Token labels are padded with spaces.
+		if strings.TrimRight(info.Label, " \x00") == tokenLabel {
+			found = append(found, candidate)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return 0, fmt.Errorf("no token with label %s", tokenLabel)
+	case 1:
+		return found[0], nil
+	default:
+		return 0, fmt.Errorf("multiple tokens with label %s", tokenLabel)
+	}
+}
+
+// openKey opens a session on the slot and finds the key with the given label.
+func (s *Service) openKey(slot uint, pin string, label string) error {
+	var err error
+	s.session, err = s.ctx.OpenSession(slot, p11.CKF_SERIAL_SESSION)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open session on slot %d", slot)
+	}
+	// Login state is shared by all sessions on the token, so may already be present.
+	if err := s.ctx.Login(s.session, p11.CKU_USER, pin); err != nil && err != p11.Error(p11.CKR_USER_ALREADY_LOGGED_IN) {
+		s.ctx.CloseSession(s.session)
+		return errors.Wrapf(err, "failed to log in to slot %d", slot)
+	}
+
+	s.key, err = s.findObject(p11.CKO_PRIVATE_KEY, label)
+	if err != nil {
+		s.ctx.CloseSession(s.session)
+		return errors.Wrap(err, "failed to find private key")
+	}
+	publicKey, err := s.findObject(p11.CKO_PUBLIC_KEY, label)
+	if err != nil {
+		s.ctx.CloseSession(s.session)
+		return errors.Wrap(err, "failed to find public key")
+	}
+	attrs, err := s.ctx.GetAttributeValue(s.session, publicKey, []*p11.Attribute{
+		p11.NewAttribute(p11.CKA_EC_PARAMS, nil),
+		p11.NewAttribute(p11.CKA_EC_POINT, nil),
+	})
+	if err != nil {
+		s.ctx.CloseSession(s.session)
+		return errors.Wrap(err, "failed to obtain public key")
+	}
+	s.pubKey, err = parsePublicKey(attrs[0].Value, attrs[1].Value)
+	if err != nil {
+		s.ctx.CloseSession(s.session)
+		return errors.Wrapf(err, "invalid public key for %s", label)
+	}
+
+	address := crypto.PubkeyToAddress(*s.pubKey)
+	if address != s.address {
+		s.ctx.CloseSession(s.session)
+		return fmt.Errorf("key %s on slot %d is for %#x not %#x", label, slot, address, s.address)
+	}
+
+	return nil
+}
+
+// findObject finds the single object of the given class with the given label.
+func (s *Service) findObject(class uint, label string) (p11.ObjectHandle, error) {
+	if err := s.ctx.FindObjectsInit(s.session, []*p11.Attribute{
+		p11.NewAttribute(p11.CKA_CLASS, class),
+		p11.NewAttribute(p11.CKA_KEY_TYPE, p11.CKK_EC),
+		p11.NewAttribute(p11.CKA_LABEL, label),
+	}); err != nil {
+		return 0, err
+	}
+	objects, _, err := s.ctx.FindObjects(s.session, 2)
+	if err != nil {
+		_ = s.ctx.FindObjectsFinal(s.session)
+		return 0, err
+	}
+	if err := s.ctx.FindObjectsFinal(s.session); err != nil {
+		return 0, err
+	}
+
+	switch len(objects) {
+	case 0:
+		return 0, fmt.Errorf("no key with label %s", label)
+	case 1:
+		return objects[0], nil
+	default:
+		return 0, fmt.Errorf("multiple keys with label %s", label)
+	}
+}
@@ -0,0 +1,239 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build cgo
+// +build cgo
+
+package pkcs11_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"github.com/wealdtech/edcd/services/signer/pkcs11"
+)
+
+func TestService(t *testing.T) {
+	ctx := context.Background()
+
+	address := common.HexToAddress("0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5")
+
+	tests := []struct {
+		name   string
+		params []pkcs11.Parameter
+		err    string
+	}{
+		{
+			name: "MonitorMissing",
+			params: []pkcs11.Parameter{
+				pkcs11.WithLogLevel(zerolog.Disabled),
+				pkcs11.WithMonitor(nil),
+				pkcs11.WithLibrary("/path/to/library.so"),
+				pkcs11.WithSlot(1),
+				pkcs11.WithPIN(testUserPIN),
+				pkcs11.WithKeyLabel(testKeyLabel),
+				pkcs11.WithAddress(address),
+			},
+			err: "problem with parameters: no monitor specified",
+		},
+		{
+			name: "LibraryMissing",
+			params: []pkcs11.Parameter{
+				pkcs11.WithLogLevel(zerolog.Disabled),
+				pkcs11.WithPIN(testUserPIN),
+				pkcs11.WithKeyLabel(testKeyLabel),
+				pkcs11.WithAddress(address),
+			},
+			err: "problem with parameters: no library specified",
+		},
+		{
+			name: "SlotMissing",
+			params: []pkcs11.Parameter{
+				pkcs11.WithLogLevel(zerolog.Disabled),
+				pkcs11.WithLibrary("/path/to/library.so"),
+				pkcs11.WithPIN(testUserPIN),
+				pkcs11.WithKeyLabel(testKeyLabel),
+				pkcs11.WithAddress(address),
+			},
+			err: "problem with parameters: no slot or token label specified",
+		},
+		{
+			name: "SlotAndTokenLabel",
+			params: []pkcs11.Parameter{
+				pkcs11.WithLogLevel(zerolog.Disabled),
+				pkcs11.WithLibrary("/path/to/library.so"),
+				pkcs11.WithSlot(1),
+				pkcs11.WithTokenLabel(testKeyLabel),
+				pkcs11.WithPIN(testUserPIN),
+				pkcs11.WithKeyLabel(testKeyLabel),
+				pkcs11.WithAddress(address),
+			},
+			err: "problem with parameters: both slot and token label specified",
+		},
+		{
+			name: "PINMissing",
+			params: []pkcs11.Parameter{
+				pkcs11.WithLogLevel(zerolog.Disabled),
+				pkcs11.WithLibrary("/path/to/library.so"),
+				pkcs11.WithSlot(1),
+				pkcs11.WithKeyLabel(testKeyLabel),
+				pkcs11.WithAddress(address),
+			},
+			err: "problem with parameters: no PIN specified",
+		},
+		{
+			name: "KeyLabelMissing",
+			params: []pkcs11.Parameter{
+				pkcs11.WithLogLevel(zerolog.Disabled),
+				pkcs11.WithLibrary("/path/to/library.so"),
+				pkcs11.WithSlot(1),
+				pkcs11.WithPIN(testUserPIN),
+				pkcs11.WithAddress(address),
+			},
+			err: "problem with parameters: no key label specified",
+		},
+		{
+			name: "AddressMissing",
+			params: []pkcs11.Parameter{
+				pkcs11.WithLogLevel(zerolog.Disabled),
+				pkcs11.WithLibrary("/path/to/library.so"),
+				pkcs11.WithSlot(1),
+				pkcs11.WithPIN(testUserPIN),
+				pkcs11.WithKeyLabel(testKeyLabel),
+			},
+			err: "problem with parameters: no address specified",
+		},
+		{
+			name: "LibraryNotFound",
+			params: []pkcs11.Parameter{
+				pkcs11.WithLogLevel(zerolog.Disabled),
+				pkcs11.WithLibrary("/path/to/library.so"),
+				pkcs11.WithSlot(1),
+				pkcs11.WithPIN(testUserPIN),
+				pkcs11.WithKeyLabel(testKeyLabel),
+				pkcs11.WithAddress(address),
+			},
+			err: "failed to load PKCS#11 library /path/to/library.so",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := pkcs11.New(ctx, test.params...)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestServiceSoftHSM(t *testing.T) {
+	ctx := context.Background()
+
+	library, slot, address := softHSMToken(t)
+
+	tests := []struct {
+		name   string
+		params []pkcs11.Parameter
+		err    string
+	}{
+		{
+			name: "PINIncorrect",
+			params: []pkcs11.Parameter{
+				pkcs11.WithLogLevel(zerolog.Disabled),
+				pkcs11.WithLibrary(library),
+				pkcs11.WithSlot(slot),
+				pkcs11.WithPIN("wrong"),
+				pkcs11.WithKeyLabel(testKeyLabel),
+				pkcs11.WithAddress(address),
+			},
+			err: "failed to log in to slot",
+		},
+		{
+			name: "KeyLabelUnknown",
+			params: []pkcs11.Parameter{
+				pkcs11.WithLogLevel(zerolog.Disabled),
+				pkcs11.WithLibrary(library),
+				pkcs11.WithSlot(slot),
+				pkcs11.WithPIN(testUserPIN),
+				pkcs11.WithKeyLabel("unknown"),
+				pkcs11.WithAddress(address),
+			},
+			err: "failed to find private key: no key with label unknown",
+		},
+		{
+			name: "AddressMismatch",
+			params: []pkcs11.Parameter{
+				pkcs11.WithLogLevel(zerolog.Disabled),
+				pkcs11.WithLibrary(library),
+				pkcs11.WithSlot(slot),
+				pkcs11.WithPIN(testUserPIN),
+				pkcs11.WithKeyLabel(testKeyLabel),
+				pkcs11.WithAddress(common.HexToAddress("0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5")),
+			},
+			err: "is for",
+		},
+		{
+			name: "TokenLabelUnknown",
+			params: []pkcs11.Parameter{
+				pkcs11.WithLogLevel(zerolog.Disabled),
+				pkcs11.WithLibrary(library),
+				pkcs11.WithTokenLabel("unknown"),
+				pkcs11.WithPIN(testUserPIN),
+				pkcs11.WithKeyLabel(testKeyLabel),
+				pkcs11.WithAddress(address),
+			},
+			err: "no token with label unknown",
+		},
+		{
+			name: "Good",
+			params: []pkcs11.Parameter{
+				pkcs11.WithLogLevel(zerolog.Disabled),
+				pkcs11.WithLibrary(library),
+				pkcs11.WithSlot(slot),
+				pkcs11.WithPIN(testUserPIN),
+				pkcs11.WithKeyLabel(testKeyLabel),
+				pkcs11.WithAddress(address),
+			},
+		},
+		{
+			name: "GoodTokenLabel",
+			params: []pkcs11.Parameter{
+				pkcs11.WithLogLevel(zerolog.Disabled),
+				pkcs11.WithLibrary(library),
+				pkcs11.WithTokenLabel(testKeyLabel),
+				pkcs11.WithPIN(testUserPIN),
+				pkcs11.WithKeyLabel(testKeyLabel),
+				pkcs11.WithAddress(address),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := pkcs11.New(ctx, test.params...)
+			if test.err != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), test.err)
+			} else {
+				require.NoError(t, err)
+				s.Close()
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build cgo
+// +build cgo
+
+package pkcs11
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	// secp256k1Params is the DER encoding of the secp256k1 curve OID 1.3.132.0.10.
+	secp256k1Params = []byte{0x06, 0x05, 0x2b, 0x81, 0x04, 0x00, 0x0a}
+	secp256k1N      = crypto.S256().Params().N
+	secp256k1HalfN  = new(big.Int).Rsh(secp256k1N, 1)
+)
+
+// parsePublicKey parses the EC parameters and point of a public key held in a token.
+func parsePublicKey(params []byte, point []byte) (*ecdsa.PublicKey, error) {
+	if !bytes.Equal(params, secp256k1Params) {
+		return nil, errors.New("key is not secp256k1")
+	}
+
+	// The point should be a DER-encoded octet string, but some tokens return it raw.
+	if len(point) != 65 {
+		var encoded []byte
+		rest, err := asn1.Unmarshal(point, &encoded)
+		if err != nil || len(rest) != 0 {
+			return nil, errors.New("invalid EC point encoding")
+		}
+		point = encoded
+	}
+
+	return crypto.UnmarshalPubkey(point)
+}
+
+// ethSignature converts a signature from a token to a 65-byte Ethereum
+// signature, with a low S value and a recovery ID of 27 or 28.
+func ethSignature(hash [32]byte, tokenSig []byte, pubKey *ecdsa.PublicKey) ([]byte, error) {
+	r, s, err := parseSignature(tokenSig)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ethereum only accepts signatures in the lower half of the curve order.
+	if s.Cmp(secp256k1HalfN) > 0 {
+		s = new(big.Int).Sub(secp256k1N, s)
+	}
+
+	sig := make([]byte, 65)
+	r.FillBytes(sig[0:32])
+	s.FillBytes(sig[32:64])
+
+	// The token does not supply the recovery ID, so find the one that recovers our key.
+	for recoveryID := byte(0); recoveryID < 2; recoveryID++ {
+		sig[64] = recoveryID
+		recovered, err := crypto.SigToPub(hash[:], sig)
+		if err != nil {
+			continue
+		}
+		if recovered.X.Cmp(pubKey.X) == 0 && recovered.Y.Cmp(pubKey.Y) == 0 {
+			sig[64] += 27
+			return sig, nil
+		}
+	}
+
+	return nil, errors.New("signature does not recover to key")
+}
+
+// parseSignature parses a signature from a token, which may be in raw
+// (R||S) or DER format.
+func parseSignature(sig []byte) (*big.Int, *big.Int, error) {
+	var r, s *big.Int
+	if len(sig) == 64 {
+		r = new(big.Int).SetBytes(sig[0:32])
+		s = new(big.Int).SetBytes(sig[32:64])
+	} else {
+		der := struct {
+			R *big.Int
+			S *big.Int
+		}{}
+		rest, err := asn1.Unmarshal(sig, &der)
+		if err != nil || len(rest) != 0 {
+			return nil, nil, fmt.Errorf("invalid signature of length %d", len(sig))
+		}
+		r = der.R
+		s = der.S
+	}
+
+	if r.Sign() <= 0 || r.Cmp(secp256k1N) >= 0 {
+		return nil, nil, errors.New("signature R out of range")
+	}
+	if s.Sign() <= 0 || s.Cmp(secp256k1N) >= 0 {
+		return nil, nil, errors.New("signature S out of range")
+	}
+
+	return r, s, nil
+}
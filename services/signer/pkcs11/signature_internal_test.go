@@ -0,0 +1,162 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build cgo
+// +build cgo
+
+package pkcs11
+
+import (
+	"encoding/asn1"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePublicKey(t *testing.T) {
+	key, err := crypto.ToECDSA(common.FromHex("0x0101010101010101010101010101010101010101010101010101010101010101"))
+	require.NoError(t, err)
+	point := crypto.FromECDSAPub(&key.PublicKey)
+	derPoint, err := asn1.Marshal(point)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		params  []byte
+		point   []byte
+		address common.Address
+		err     string
+	}{
+		{
+			name:   "ParamsWrongCurve",
+			params: []byte{0x06, 0x08, 0x2a, 0x86, 0x48, 0xce, 0x3d, 0x03, 0x01, 0x07},
+			point:  derPoint,
+			err:    "key is not secp256k1",
+		},
+		{
+			name:   "PointInvalid",
+			params: secp256k1Params,
+			point:  []byte{0x04, 0x01},
+			err:    "invalid EC point encoding",
+		},
+		{
+			name:   "PointNotOnCurve",
+			params: secp256k1Params,
+			point:  append([]byte{0x04}, make([]byte, 64)...),
+			err:    "invalid secp256k1 public key",
+		},
+		{
+			name:    "Raw",
+			params:  secp256k1Params,
+			point:   point,
+			address: common.HexToAddress("0x1a642f0E3c3aF545E7AcBD38b07251B3990914F1"),
+		},
+		{
+			name:    "DER",
+			params:  secp256k1Params,
+			point:   derPoint,
+			address: common.HexToAddress("0x1a642f0E3c3aF545E7AcBD38b07251B3990914F1"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pubKey, err := parsePublicKey(test.params, test.point)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, test.address, crypto.PubkeyToAddress(*pubKey))
+			}
+		})
+	}
+}
+
+func TestEthSignature(t *testing.T) {
+	key, err := crypto.ToECDSA(common.FromHex("0x0101010101010101010101010101010101010101010101010101010101010101"))
+	require.NoError(t, err)
+	otherKey, err := crypto.ToECDSA(common.FromHex("0x0202020202020202020202020202020202020202020202020202020202020202"))
+	require.NoError(t, err)
+
+	hash := [32]byte{
+		0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f,
+		0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18, 0x19, 0x1a, 0x1b, 0x1c, 0x1d, 0x1e, 0x1f,
+	}
+	expected, err := crypto.Sign(hash[:], key)
+	require.NoError(t, err)
+	expected[64] += 27
+
+	raw := expected[:64]
+	r := new(big.Int).SetBytes(expected[0:32])
+	s := new(big.Int).SetBytes(expected[32:64])
+	der, err := asn1.Marshal(struct {
+		R *big.Int
+		S *big.Int
+	}{R: r, S: s})
+	require.NoError(t, err)
+	// Tokens are free to return either of the two valid S values.
+	highS := make([]byte, 64)
+	copy(highS, raw)
+	new(big.Int).Sub(secp256k1N, s).FillBytes(highS[32:64])
+	zeroR := make([]byte, 64)
+	copy(zeroR[32:], raw[32:])
+
+	tests := []struct {
+		name     string
+		tokenSig []byte
+		err      string
+	}{
+		{
+			name:     "Short",
+			tokenSig: raw[:63],
+			err:      "invalid signature of length 63",
+		},
+		{
+			name:     "RZero",
+			tokenSig: zeroR,
+			err:      "signature R out of range",
+		},
+		{
+			name:     "Raw",
+			tokenSig: raw,
+		},
+		{
+			name:     "DER",
+			tokenSig: der,
+		},
+		{
+			name:     "HighS",
+			tokenSig: highS,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sig, err := ethSignature(hash, test.tokenSig, &key.PublicKey)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, expected, sig)
+			}
+		})
+	}
+
+	t.Run("OtherKey", func(t *testing.T) {
+		_, err := ethSignature(hash, raw, &otherKey.PublicKey)
+		require.EqualError(t, err, "signature does not recover to key")
+	})
+}
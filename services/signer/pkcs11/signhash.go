@@ -0,0 +1,67 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build cgo
+// +build cgo
+
+package pkcs11
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	p11 "github.com/miekg/pkcs11"
+	"github.com/pkg/errors"
+)
+
+// SignHash signs a 32-byte hash with the key for the given address.
+func (s *Service) SignHash(ctx context.Context,
+	address common.Address,
+	hash [32]byte,
+) (
+	[]byte,
+	error,
+) {
+	if address != s.address {
+		requestHandled("unknown_account")
+		return nil, fmt.Errorf("unknown account %#x", address)
+	}
+
+	// A session can only carry out one signing operation at a time.
+	s.mutex.Lock()
+	tokenSig, err := s.sign(hash)
+	s.mutex.Unlock()
+	if err != nil {
+		requestHandled("failed")
+		return nil, errors.Wrap(err, "failed to sign hash")
+	}
+
+	sig, err := ethSignature(hash, tokenSig, s.pubKey)
+	if err != nil {
+		requestHandled("failed")
+		return nil, errors.Wrap(err, "failed to convert signature")
+	}
+	log.Trace().Str("address", fmt.Sprintf("%#x", address)).Str("signature", fmt.Sprintf("%#x", sig)).Msg("Signed hash")
+
+	requestHandled("succeeded")
+	return sig, nil
+}
+
+// sign signs the hash with the token.
+func (s *Service) sign(hash [32]byte) ([]byte, error) {
+	if err := s.ctx.SignInit(s.session, []*p11.Mechanism{p11.NewMechanism(p11.CKM_ECDSA, nil)}, s.key); err != nil {
+		return nil, err
+	}
+	return s.ctx.Sign(s.session, hash[:])
+}
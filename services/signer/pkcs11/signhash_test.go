@@ -0,0 +1,87 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build cgo
+// +build cgo
+
+package pkcs11_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"github.com/wealdtech/edcd/services/signer/pkcs11"
+)
+
+func TestSignHash(t *testing.T) {
+	ctx := context.Background()
+
+	library, slot, address := softHSMToken(t)
+
+	hash := [32]byte{
+		0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f,
+		0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18, 0x19, 0x1a, 0x1b, 0x1c, 0x1d, 0x1e, 0x1f,
+	}
+
+	s, err := pkcs11.New(ctx,
+		pkcs11.WithLogLevel(zerolog.Disabled),
+		pkcs11.WithLibrary(library),
+		pkcs11.WithSlot(slot),
+		pkcs11.WithPIN(testUserPIN),
+		pkcs11.WithKeyLabel(testKeyLabel),
+		pkcs11.WithAddress(address),
+	)
+	require.NoError(t, err)
+	defer s.Close()
+
+	tests := []struct {
+		name    string
+		address common.Address
+		err     string
+	}{
+		{
+			name:    "UnknownAccount",
+			address: common.HexToAddress("0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5"),
+			err:     "unknown account 0x388ea662ef2c223ec0b047d41bf3c0f362142ad5",
+		},
+		{
+			name:    "Good",
+			address: address,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sig, err := s.SignHash(ctx, test.address, hash)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+			} else {
+				require.NoError(t, err)
+				require.Len(t, sig, 65)
+				require.Contains(t, []byte{27, 28}, sig[64])
+
+				// Ensure the signature recovers to the signing address.
+				recoverable := make([]byte, 65)
+				copy(recoverable, sig)
+				recoverable[64] -= 27
+				pubKey, err := crypto.SigToPub(hash[:], recoverable)
+				require.NoError(t, err)
+				require.Equal(t, test.address, crypto.PubkeyToAddress(*pubKey))
+			}
+		})
+	}
+}
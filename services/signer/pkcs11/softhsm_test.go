@@ -0,0 +1,145 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build cgo
+// +build cgo
+
+package pkcs11_test
+
+import (
+	"encoding/asn1"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	p11 "github.com/miekg/pkcs11"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testSOPIN    = "87654321"
+	testUserPIN  = "12345678"
+	testKeyLabel = "edcd"
+)
+
+// softHSMLibrary returns the path to the SoftHSM library, skipping the test if it is not present.
+func softHSMLibrary(t *testing.T) string {
+	candidates := []string{
+		os.Getenv("SOFTHSM2_LIBRARY"),
+		"/usr/lib/softhsm/libsofthsm2.so",
+		"/usr/lib/x86_64-linux-gnu/softhsm/libsofthsm2.so",
+		"/usr/local/lib/softhsm/libsofthsm2.so",
+		"/opt/homebrew/lib/softhsm/libsofthsm2.so",
+	}
+	for _, candidate := range candidates {
+		if candidate == "" {
+			continue
+		}
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+	t.Skip("SoftHSM not available")
+	return ""
+}
+
+// softHSMToken creates a SoftHSM token holding a secp256k1 key, returning
+// the library, the slot of the token and the address of the key.
+func softHSMToken(t *testing.T) (string, uint, common.Address) {
+	library := softHSMLibrary(t)
+
+	dir := t.TempDir()
+	tokenDir := filepath.Join(dir, "tokens")
+	require.NoError(t, os.Mkdir(tokenDir, 0700))
+	conf := filepath.Join(dir, "softhsm2.conf")
+	require.NoError(t, os.WriteFile(conf, []byte(fmt.Sprintf("directories.tokendir = %s\nobjectstore.backend = file\n", tokenDir)), 0600))
+	require.NoError(t, os.Setenv("SOFTHSM2_CONF", conf))
+	t.Cleanup(func() {
+		os.Unsetenv("SOFTHSM2_CONF")
+	})
+
+	ctx := p11.New(library)
+	require.NotNil(t, ctx)
+	require.NoError(t, ctx.Initialize())
+	defer ctx.Destroy()
+	defer func() {
+		require.NoError(t, ctx.Finalize())
+	}()
+
+	slots, err := ctx.GetSlotList(false)
+	require.NoError(t, err)
+	require.NotEmpty(t, slots)
+	require.NoError(t, ctx.InitToken(slots[0], testSOPIN, testKeyLabel))
+
+	// SoftHSM moves the token to a new slot once it is initialised, so find it again.
+	slots, err = ctx.GetSlotList(true)
+	require.NoError(t, err)
+	var slot uint
+	found := false
+	for _, candidate := range slots {
+		info, err := ctx.GetTokenInfo(candidate)
+		require.NoError(t, err)
+		if strings.TrimSpace(info.Label) == testKeyLabel {
+			slot = candidate
+			found = true
+			break
+		}
+	}
+	require.True(t, found)
+
+	session, err := ctx.OpenSession(slot, p11.CKF_SERIAL_SESSION|p11.CKF_RW_SESSION)
+	require.NoError(t, err)
+	defer ctx.CloseSession(session)
+	require.NoError(t, ctx.Login(session, p11.CKU_SO, testSOPIN))
+	require.NoError(t, ctx.InitPIN(session, testUserPIN))
+	require.NoError(t, ctx.Logout(session))
+	require.NoError(t, ctx.Login(session, p11.CKU_USER, testUserPIN))
+	defer ctx.Logout(session)
+
+	secp256k1Params := []byte{0x06, 0x05, 0x2b, 0x81, 0x04, 0x00, 0x0a}
+	pubKey, _, err := ctx.GenerateKeyPair(session,
+		[]*p11.Mechanism{p11.NewMechanism(p11.CKM_EC_KEY_PAIR_GEN, nil)},
+		[]*p11.Attribute{
+			p11.NewAttribute(p11.CKA_CLASS, p11.CKO_PUBLIC_KEY),
+			p11.NewAttribute(p11.CKA_KEY_TYPE, p11.CKK_EC),
+			p11.NewAttribute(p11.CKA_TOKEN, true),
+			p11.NewAttribute(p11.CKA_VERIFY, true),
+			p11.NewAttribute(p11.CKA_EC_PARAMS, secp256k1Params),
+			p11.NewAttribute(p11.CKA_LABEL, testKeyLabel),
+		},
+		[]*p11.Attribute{
+			p11.NewAttribute(p11.CKA_CLASS, p11.CKO_PRIVATE_KEY),
+			p11.NewAttribute(p11.CKA_KEY_TYPE, p11.CKK_EC),
+			p11.NewAttribute(p11.CKA_TOKEN, true),
+			p11.NewAttribute(p11.CKA_PRIVATE, true),
+			p11.NewAttribute(p11.CKA_SENSITIVE, true),
+			p11.NewAttribute(p11.CKA_SIGN, true),
+			p11.NewAttribute(p11.CKA_LABEL, testKeyLabel),
+		},
+	)
+	require.NoError(t, err)
+
+	attrs, err := ctx.GetAttributeValue(session, pubKey, []*p11.Attribute{p11.NewAttribute(p11.CKA_EC_POINT, nil)})
+	require.NoError(t, err)
+	var point []byte
+	_, err = asn1.Unmarshal(attrs[0].Value, &point)
+	require.NoError(t, err)
+	key, err := crypto.UnmarshalPubkey(point)
+	require.NoError(t, err)
+
+	return library, slot, crypto.PubkeyToAddress(*key)
+}
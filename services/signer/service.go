@@ -0,0 +1,57 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signer
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/wealdtech/edcd/util/eip712"
+)
+
+// Service defines the signer service.
+type Service interface {
+	// SignHash signs a 32-byte hash with the key for the given address.
+	// The returned signature is 65 bytes long in Ethereum [R || S || V]
+	// format, with V being 27 or 28.
+	SignHash(ctx context.Context,
+		address common.Address,
+		hash [32]byte,
+	) (
+		[]byte,
+		error,
+	)
+}
+
+// TypedDataSigner is the interface for signers that can sign EIP-712 typed
+// data themselves, allowing them to show the content that is being signed.
+type TypedDataSigner interface {
+	// SignTypedData signs EIP-712 typed data with the key for the given
+	// address.  The returned signature is in the same format as that
+	// returned by SignHash.
+	SignTypedData(ctx context.Context,
+		address common.Address,
+		data *eip712.TypedData,
+	) (
+		[]byte,
+		error,
+	)
+}
+
+// Closer is the interface for signers that hold resources, such as sessions
+// with hardware tokens, that must be released when they are no longer used.
+type Closer interface {
+	// Close releases the resources held by the signer.
+	Close()
+}
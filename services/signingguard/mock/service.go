@@ -0,0 +1,38 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mock
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Service is a mock signing guard service.
+type Service struct{}
+
+// New creates a new mock signing guard service.
+func New() *Service {
+	return &Service{}
+}
+
+// Authorize is a mock; it authorizes everything.
+func (s *Service) Authorize(ctx context.Context,
+	parent string,
+	label string,
+	owner common.Address,
+	hash [32]byte,
+) error {
+	return nil
+}
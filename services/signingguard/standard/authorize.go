@@ -0,0 +1,118 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package standard
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	"github.com/wealdtech/edcd/util/eip712"
+	goens "github.com/wealdtech/go-ens/v3"
+)
+
+// Authorize checks that a claim for the label under the parent domain
+// may be signed, and records it if so.
+func (s *Service) Authorize(ctx context.Context,
+	parent string,
+	label string,
+	owner common.Address,
+	hash [32]byte,
+) error {
+	parent = normalizeDomain(parent)
+	name := fmt.Sprintf("%s.%s", label, parent)
+	log := log.With().Str("name", name).Str("owner", fmt.Sprintf("%#x", owner)).Logger()
+
+	if !s.domains[parent] {
+		requestHandled("refused")
+		return fmt.Errorf("domain %s not configured for signing", parent)
+	}
+
+	node, err := goens.NameHash(name)
+	if err != nil {
+		requestHandled("failed")
+		return errors.Wrap(err, "failed to calculate node")
+	}
+
+	if eip712Domain, exists := s.eip712[parent]; exists {
+		// Only sign hashes of the claim's typed data.
+		parentNode, err := goens.NameHash(parent)
+		if err != nil {
+			requestHandled("failed")
+			return errors.Wrap(err, "failed to calculate parent node")
+		}
+		expected := eip712.ClaimHash(eip712Domain, parentNode, label, owner)
+		if expected != hash {
+			requestHandled("refused")
+			log.Warn().Str("hash", fmt.Sprintf("%#x", hash)).Str("expected", fmt.Sprintf("%#x", expected)).Msg("Refusing to sign hash not derived from claim typed data")
+			return errors.New("hash does not match claim typed data hash")
+		}
+	} else {
+		// Only sign hashes that the registrar itself provides.
+		expected, err := s.ens.SignatureHash(ctx, name, parent, owner)
+		if err != nil {
+			requestHandled("failed")
+			return errors.Wrap(err, "failed to obtain signature hash from registrar")
+		}
+		if expected != hash {
+			requestHandled("refused")
+			log.Warn().Str("hash", fmt.Sprintf("%#x", hash)).Str("expected", fmt.Sprintf("%#x", expected)).Msg("Refusing to sign hash not provided by registrar")
+			return errors.New("hash does not match registrar signature hash")
+		}
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if existing, exists := s.claims[node]; exists {
+		if existing.Owner != owner {
+			requestHandled("refused")
+			log.Warn().Str("existing_owner", fmt.Sprintf("%#x", existing.Owner)).Msg("Refusing to sign conflicting owner")
+			return fmt.Errorf("%s already signed for owner %#x", name, existing.Owner)
+		}
+		if existing.Hash == hash {
+			// Already recorded.
+			requestHandled("authorized")
+			return nil
+		}
+	}
+
+	// Record the claim before it is signed.
+	record := &claim{
+		Parent: parent,
+		Label:  label,
+		Owner:  owner,
+		Hash:   hash,
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		requestHandled("failed")
+		return errors.Wrap(err, "failed to create record")
+	}
+	if _, err := s.file.Write(append(data, '\n')); err != nil {
+		requestHandled("failed")
+		return errors.Wrap(err, "failed to write record")
+	}
+	if err := s.file.Sync(); err != nil {
+		requestHandled("failed")
+		return errors.Wrap(err, "failed to sync record")
+	}
+	s.claims[node] = record
+	log.Trace().Msg("Recorded claim")
+
+	requestHandled("authorized")
+	return nil
+}
@@ -0,0 +1,243 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package standard_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"github.com/wealdtech/edcd/services/signingguard/standard"
+	"github.com/wealdtech/edcd/util/eip712"
+	goens "github.com/wealdtech/go-ens/v3"
+)
+
+// registrar provides signature hashes in the same way as a registrar contract.
+type registrar struct{}
+
+func (r *registrar) SignatureHash(ctx context.Context, name string, domain string, owner common.Address) ([32]byte, error) {
+	return signatureHash(name, owner), nil
+}
+
+func (r *registrar) RegistrarAddress(ctx context.Context, domain string) (common.Address, error) {
+	return common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314"), nil
+}
+
+func (r *registrar) Resolve(ctx context.Context, name string) (common.Address, error) {
+	return common.Address{}, errors.New("unregistered name")
+}
+
+func signatureHash(name string, owner common.Address) [32]byte {
+	var hash [32]byte
+	copy(hash[:], crypto.Keccak256([]byte(name), owner.Bytes()))
+	return hash
+}
+
+func TestAuthorize(t *testing.T) {
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "guard.json")
+	owner1 := common.HexToAddress("0x1a642f0E3c3aF545E7AcBD38b07251B3990914F1")
+	owner2 := common.HexToAddress("0x5050A4F4b3f9338C3472dcC01A87C76A144b3c9c")
+
+	tests := []struct {
+		name   string
+		parent string
+		label  string
+		owner  common.Address
+		hash   [32]byte
+		err    string
+	}{
+		{
+			name:   "DomainNotConfigured",
+			parent: "example.eth",
+			label:  "test",
+			owner:  owner1,
+			hash:   signatureHash("test.example.eth", owner1),
+			err:    "domain example.eth not configured for signing",
+		},
+		{
+			name:   "HashNotFromRegistrar",
+			parent: "wealdtech.eth",
+			label:  "test",
+			owner:  owner1,
+			hash:   [32]byte{0x01},
+			err:    "hash does not match registrar signature hash",
+		},
+		{
+			name:   "Good",
+			parent: "wealdtech.eth",
+			label:  "test",
+			owner:  owner1,
+			hash:   signatureHash("test.wealdtech.eth", owner1),
+		},
+		{
+			name:   "Repeat",
+			parent: "wealdtech.eth",
+			label:  "test",
+			owner:  owner1,
+			hash:   signatureHash("test.wealdtech.eth", owner1),
+		},
+		{
+			name:   "RepeatParentTrailingPeriod",
+			parent: "wealdtech.eth.",
+			label:  "test",
+			owner:  owner1,
+			hash:   signatureHash("test.wealdtech.eth", owner1),
+		},
+		{
+			name:   "ConflictingOwner",
+			parent: "wealdtech.eth",
+			label:  "test",
+			owner:  owner2,
+			hash:   signatureHash("test.wealdtech.eth", owner2),
+			err:    "test.wealdtech.eth already signed for owner 0x1a642f0e3c3af545e7acbd38b07251b3990914f1",
+		},
+		{
+			name:   "ConflictingOwnerLabelCase",
+			parent: "wealdtech.eth",
+			label:  "TEST",
+			owner:  owner2,
+			hash:   signatureHash("TEST.wealdtech.eth", owner2),
+			err:    "TEST.wealdtech.eth already signed for owner 0x1a642f0e3c3af545e7acbd38b07251b3990914f1",
+		},
+		{
+			name:   "OtherLabel",
+			parent: "wealdtech.eth",
+			label:  "other",
+			owner:  owner2,
+			hash:   signatureHash("other.wealdtech.eth", owner2),
+		},
+	}
+
+	s, err := standard.New(ctx,
+		standard.WithLogLevel(zerolog.Disabled),
+		standard.WithPath(path),
+		standard.WithDomains([]string{"wealdtech.eth"}),
+		standard.WithENS(&registrar{}),
+	)
+	require.NoError(t, err)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := s.Authorize(ctx, test.parent, test.label, test.owner, test.hash)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+
+	// Ensure that the record persists across restarts.
+	s, err = standard.New(ctx,
+		standard.WithLogLevel(zerolog.Disabled),
+		standard.WithPath(path),
+		standard.WithDomains([]string{"wealdtech.eth"}),
+		standard.WithENS(&registrar{}),
+	)
+	require.NoError(t, err)
+	require.EqualError(t, s.Authorize(ctx, "wealdtech.eth", "test", owner2, signatureHash("test.wealdtech.eth", owner2)),
+		"test.wealdtech.eth already signed for owner 0x1a642f0e3c3af545e7acbd38b07251b3990914f1")
+	require.NoError(t, s.Authorize(ctx, "wealdtech.eth", "other", owner2, signatureHash("other.wealdtech.eth", owner2)))
+}
+
+func TestAuthorizeEIP712(t *testing.T) {
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "guard.json")
+	owner := common.HexToAddress("0x1a642f0E3c3aF545E7AcBD38b07251B3990914F1")
+	domain := &eip712.Domain{
+		Name:              "ENS DNS claim",
+		Version:           "1",
+		ChainID:           big.NewInt(5),
+		VerifyingContract: common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314"),
+	}
+	parentNode, err := goens.NameHash("example.eth")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		parent string
+		label  string
+		owner  common.Address
+		hash   [32]byte
+		err    string
+	}{
+		{
+			name:   "RegistrarHash",
+			parent: "example.eth",
+			label:  "test",
+			owner:  owner,
+			hash:   signatureHash("test.example.eth", owner),
+			err:    "hash does not match claim typed data hash",
+		},
+		{
+			name:   "OtherLabel",
+			parent: "example.eth",
+			label:  "test",
+			owner:  owner,
+			hash:   eip712.ClaimHash(domain, parentNode, "other", owner),
+			err:    "hash does not match claim typed data hash",
+		},
+		{
+			name:   "TypedDataHashForRegistrarDomain",
+			parent: "wealdtech.eth",
+			label:  "test",
+			owner:  owner,
+			hash:   eip712.ClaimHash(domain, parentNode, "test", owner),
+			err:    "hash does not match registrar signature hash",
+		},
+		{
+			name:   "Good",
+			parent: "example.eth",
+			label:  "test",
+			owner:  owner,
+			hash:   eip712.ClaimHash(domain, parentNode, "test", owner),
+		},
+		{
+			name:   "GoodRegistrar",
+			parent: "wealdtech.eth",
+			label:  "test",
+			owner:  owner,
+			hash:   signatureHash("test.wealdtech.eth", owner),
+		},
+	}
+
+	s, err := standard.New(ctx,
+		standard.WithLogLevel(zerolog.Disabled),
+		standard.WithPath(path),
+		standard.WithDomains([]string{"wealdtech.eth", "example.eth"}),
+		standard.WithEIP712Domains(map[string]*eip712.Domain{"example.eth": domain}),
+		standard.WithENS(&registrar{}),
+	)
+	require.NoError(t, err)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := s.Authorize(ctx, test.parent, test.label, test.owner, test.hash)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package standard
+
+import (
+	"errors"
+
+	"github.com/rs/zerolog"
+	"github.com/wealdtech/edcd/services/ens"
+	"github.com/wealdtech/edcd/services/metrics"
+	nullmetrics "github.com/wealdtech/edcd/services/metrics/null"
+	"github.com/wealdtech/edcd/util/eip712"
+)
+
+type parameters struct {
+	logLevel zerolog.Level
+	monitor  metrics.Service
+	path     string
+	domains  []string
+	eip712   map[string]*eip712.Domain
+	ens      ens.Service
+}
+
+// Parameter is the interface for service parameters.
+type Parameter interface {
+	apply(*parameters)
+}
+
+type parameterFunc func(*parameters)
+
+func (f parameterFunc) apply(p *parameters) {
+	f(p)
+}
+
+// WithLogLevel sets the log level for the module.
+func WithLogLevel(logLevel zerolog.Level) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.logLevel = logLevel
+	})
+}
+
+// WithMonitor sets the monitor for the module.
+func WithMonitor(monitor metrics.Service) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.monitor = monitor
+	})
+}
+
+// WithPath sets the path to the file holding the record of signed claims.
+func WithPath(path string) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.path = path
+	})
+}
+
+// WithDomains sets the parent domains for which claims can be signed.
+func WithDomains(domains []string) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.domains = domains
+	})
+}
+
+// WithEIP712Domains sets the EIP-712 domains for parent domains whose
+// claims are signed as EIP-712 typed data rather than registrar hashes.
+func WithEIP712Domains(domains map[string]*eip712.Domain) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.eip712 = domains
+	})
+}
+
+// WithENS sets the ENS service for this module.
+func WithENS(ens ens.Service) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.ens = ens
+	})
+}
+
+// parseAndCheckParameters parses and checks parameters to ensure that mandatory parameters are present and correct.
+func parseAndCheckParameters(params ...Parameter) (*parameters, error) {
+	parameters := parameters{
+		logLevel: zerolog.GlobalLevel(),
+		monitor:  nullmetrics.New(),
+	}
+	for _, p := range params {
+		if params != nil {
+			p.apply(&parameters)
+		}
+	}
+
+	if parameters.monitor == nil {
+		return nil, errors.New("no monitor specified")
+	}
+	if parameters.path == "" {
+		return nil, errors.New("no path specified")
+	}
+	if len(parameters.domains) == 0 {
+		return nil, errors.New("no domains specified")
+	}
+	if parameters.ens == nil {
+		return nil, errors.New("no ENS service specified")
+	}
+
+	return &parameters, nil
+}
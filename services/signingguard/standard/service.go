@@ -0,0 +1,138 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package standard
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	zerologger "github.com/rs/zerolog/log"
+	"github.com/wealdtech/edcd/services/ens"
+	"github.com/wealdtech/edcd/util/eip712"
+	goens "github.com/wealdtech/go-ens/v3"
+)
+
+// Service is a signing guard service that keeps its record of signed
+// claims in an append-only file.
+type Service struct {
+	mutex   sync.Mutex
+	file    *os.File
+	domains map[string]bool
+	eip712  map[string]*eip712.Domain
+	ens     ens.Service
+	claims  map[[32]byte]*claim
+}
+
+// claim is the record of a signed claim.
+type claim struct {
+	Parent string         `json:"parent"`
+	Label  string         `json:"label"`
+	Owner  common.Address `json:"owner"`
+	Hash   common.Hash    `json:"hash"`
+}
+
+// module-wide log.
+var log zerolog.Logger
+
+// New creates a new signing guard service.
+func New(ctx context.Context, params ...Parameter) (*Service, error) {
+	parameters, err := parseAndCheckParameters(params...)
+	if err != nil {
+		return nil, errors.Wrap(err, "problem with parameters")
+	}
+
+	// Set logging.
+	log = zerologger.With().Str("service", "signingguard").Str("impl", "standard").Logger()
+	if parameters.logLevel != log.GetLevel() {
+		log = log.Level(parameters.logLevel)
+	}
+
+	if err := registerMetrics(ctx, parameters.monitor); err != nil {
+		return nil, errors.New("failed to register metrics")
+	}
+
+	domains := make(map[string]bool)
+	for _, domain := range parameters.domains {
+		domains[normalizeDomain(domain)] = true
+	}
+	eip712Domains := make(map[string]*eip712.Domain)
+	for domain, eip712Domain := range parameters.eip712 {
+		if !domains[normalizeDomain(domain)] {
+			return nil, fmt.Errorf("EIP-712 domain supplied for unknown domain %s", domain)
+		}
+		eip712Domains[normalizeDomain(domain)] = eip712Domain
+	}
+
+	file, err := os.OpenFile(parameters.path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open signing record")
+	}
+	claims, err := loadClaims(file)
+	if err != nil {
+		file.Close()
+		return nil, errors.Wrap(err, "failed to load signing record")
+	}
+	log.Trace().Int("claims", len(claims)).Msg("Loaded signing record")
+
+	s := &Service{
+		file:    file,
+		domains: domains,
+		eip712:  eip712Domains,
+		ens:     parameters.ens,
+		claims:  claims,
+	}
+
+	return s, nil
+}
+
+// loadClaims loads the claims from the signing record.
+func loadClaims(file *os.File) (map[[32]byte]*claim, error) {
+	claims := make(map[[32]byte]*claim)
+
+	scanner := bufio.NewScanner(file)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		record := &claim{}
+		if err := json.Unmarshal(scanner.Bytes(), record); err != nil {
+			return nil, errors.Wrapf(err, "invalid record at line %d", line)
+		}
+		node, err := goens.NameHash(fmt.Sprintf("%s.%s", record.Label, record.Parent))
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid name at line %d", line)
+		}
+		claims[node] = record
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// normalizeDomain normalizes a domain for comparison.
+func normalizeDomain(domain string) string {
+	return strings.ToLower(strings.Trim(domain, "."))
+}
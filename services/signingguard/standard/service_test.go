@@ -0,0 +1,142 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package standard_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	mockens "github.com/wealdtech/edcd/services/ens/mock"
+	nullmetrics "github.com/wealdtech/edcd/services/metrics/null"
+	"github.com/wealdtech/edcd/services/signingguard/standard"
+	"github.com/wealdtech/edcd/util/eip712"
+)
+
+func TestService(t *testing.T) {
+	ctx := context.Background()
+
+	monitor := nullmetrics.New()
+	ens := mockens.New()
+	dir := t.TempDir()
+
+	corruptPath := filepath.Join(dir, "corrupt.json")
+	require.NoError(t, os.WriteFile(corruptPath, []byte("{\"parent\":\"wealdtech.eth\",\"label\":\"test\",\"owner\":\"0x01\"}\n"), 0600))
+
+	tests := []struct {
+		name   string
+		params []standard.Parameter
+		err    string
+	}{
+		{
+			name: "MonitorMissing",
+			params: []standard.Parameter{
+				standard.WithLogLevel(zerolog.Disabled),
+				standard.WithMonitor(nil),
+				standard.WithPath(filepath.Join(dir, "guard.json")),
+				standard.WithDomains([]string{"wealdtech.eth"}),
+				standard.WithENS(ens),
+			},
+			err: "problem with parameters: no monitor specified",
+		},
+		{
+			name: "PathMissing",
+			params: []standard.Parameter{
+				standard.WithLogLevel(zerolog.Disabled),
+				standard.WithMonitor(monitor),
+				standard.WithDomains([]string{"wealdtech.eth"}),
+				standard.WithENS(ens),
+			},
+			err: "problem with parameters: no path specified",
+		},
+		{
+			name: "DomainsMissing",
+			params: []standard.Parameter{
+				standard.WithLogLevel(zerolog.Disabled),
+				standard.WithMonitor(monitor),
+				standard.WithPath(filepath.Join(dir, "guard.json")),
+				standard.WithENS(ens),
+			},
+			err: "problem with parameters: no domains specified",
+		},
+		{
+			name: "ENSMissing",
+			params: []standard.Parameter{
+				standard.WithLogLevel(zerolog.Disabled),
+				standard.WithMonitor(monitor),
+				standard.WithPath(filepath.Join(dir, "guard.json")),
+				standard.WithDomains([]string{"wealdtech.eth"}),
+			},
+			err: "problem with parameters: no ENS service specified",
+		},
+		{
+			name: "EIP712DomainUnknown",
+			params: []standard.Parameter{
+				standard.WithLogLevel(zerolog.Disabled),
+				standard.WithMonitor(monitor),
+				standard.WithPath(filepath.Join(dir, "guard.json")),
+				standard.WithDomains([]string{"wealdtech.eth"}),
+				standard.WithEIP712Domains(map[string]*eip712.Domain{"example.eth": {}}),
+				standard.WithENS(ens),
+			},
+			err: "EIP-712 domain supplied for unknown domain example.eth",
+		},
+		{
+			name: "PathInvalid",
+			params: []standard.Parameter{
+				standard.WithLogLevel(zerolog.Disabled),
+				standard.WithMonitor(monitor),
+				standard.WithPath(filepath.Join(dir, "missing", "guard.json")),
+				standard.WithDomains([]string{"wealdtech.eth"}),
+				standard.WithENS(ens),
+			},
+			err: "failed to open signing record: open " + filepath.Join(dir, "missing", "guard.json") + ": no such file or directory",
+		},
+		{
+			name: "RecordCorrupt",
+			params: []standard.Parameter{
+				standard.WithLogLevel(zerolog.Disabled),
+				standard.WithMonitor(monitor),
+				standard.WithPath(corruptPath),
+				standard.WithDomains([]string{"wealdtech.eth"}),
+				standard.WithENS(ens),
+			},
+			err: "failed to load signing record: invalid record at line 1: hex string has length 2, want 40 for common.Address",
+		},
+		{
+			name: "Good",
+			params: []standard.Parameter{
+				standard.WithLogLevel(zerolog.Disabled),
+				standard.WithMonitor(monitor),
+				standard.WithPath(filepath.Join(dir, "guard.json")),
+				standard.WithDomains([]string{"wealdtech.eth"}),
+				standard.WithENS(ens),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := standard.New(ctx, test.params...)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
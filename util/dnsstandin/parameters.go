@@ -0,0 +1,72 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dnsstandin
+
+import (
+	"errors"
+
+	"github.com/miekg/dns"
+)
+
+type parameters struct {
+	dnssec bool
+	origin string
+}
+
+// Parameter is the interface for server parameters.
+type Parameter interface {
+	apply(*parameters)
+}
+
+type parameterFunc func(*parameters)
+
+func (f parameterFunc) apply(p *parameters) {
+	f(p)
+}
+
+// WithDNSSEC signs the zone fixture, creating a chain of trust from a
+// generated root key.  Every zone with an SOA record in the fixture is
+// signed with its own key, and negative answers carry NSEC records that
+// prove the name or type does not exist.
+func WithDNSSEC() Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.dnssec = true
+	})
+}
+
+// WithOrigin sets the origin for relative names in the zone fixture.
+func WithOrigin(origin string) Parameter {
+	return parameterFunc(func(p *parameters) {
+		p.origin = origin
+	})
+}
+
+// parseAndCheckParameters parses and checks parameters to ensure that mandatory parameters are present and correct.
+func parseAndCheckParameters(params ...Parameter) (*parameters, error) {
+	parameters := parameters{
+		origin: ".",
+	}
+	for _, p := range params {
+		if params != nil {
+			p.apply(&parameters)
+		}
+	}
+
+	if parameters.origin == "" {
+		return nil, errors.New("no origin specified")
+	}
+	parameters.origin = dns.Fqdn(parameters.origin)
+
+	return &parameters, nil
+}
@@ -0,0 +1,398 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package dnsstandin provides a local stand-in DNS server.  It answers
+// queries authoritatively from a zone fixture, optionally signing it with
+// DNSSEC, so must only be used in tests.
+package dnsstandin
+
+import (
+	"crypto"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/pkg/errors"
+)
+
+// Server is a stand-in DNS server.
+type Server struct {
+	address   string
+	udpServer *dns.Server
+	tcpServer *dns.Server
+	records   map[string]map[uint16][]dns.RR
+	sigs      map[string]map[uint16][]dns.RR
+	apexes    map[string]*dns.SOA
+	denials   map[string][]dns.RR
+	anchors   []string
+	mutex     sync.Mutex
+	queries   int
+}
+
+// New creates a stand-in DNS server listening for UDP and TCP on a loopback
+// port, answering from the zone fixture in master file format.
+func New(zone io.Reader, params ...Parameter) (*Server, error) {
+	parameters, err := parseAndCheckParameters(params...)
+	if err != nil {
+		return nil, errors.Wrap(err, "problem with parameters")
+	}
+
+	s := &Server{
+		records: make(map[string]map[uint16][]dns.RR),
+		sigs:    make(map[string]map[uint16][]dns.RR),
+		apexes:  make(map[string]*dns.SOA),
+		denials: make(map[string][]dns.RR),
+	}
+	parser := dns.NewZoneParser(zone, parameters.origin, "")
+	for rr, ok := parser.Next(); ok; rr, ok = parser.Next() {
+		s.add(s.records, rr)
+		if soa, isSOA := rr.(*dns.SOA); isSOA {
+			s.apexes[dns.CanonicalName(soa.Hdr.Name)] = soa
+		}
+	}
+	if err := parser.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to parse zone")
+	}
+
+	if parameters.dnssec {
+		if err := s.sign(); err != nil {
+			return nil, errors.Wrap(err, "failed to sign zone")
+		}
+	}
+
+	if err := s.start(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// NewFromFile creates a stand-in DNS server answering from the zone fixture
+// in the given file.
+func NewFromFile(path string, params ...Parameter) (*Server, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open zone")
+	}
+	defer file.Close()
+
+	return New(file, params...)
+}
+
+// Address returns the address on which the server is listening.
+func (s *Server) Address() string {
+	return s.address
+}
+
+// TrustAnchors returns the DS records for the root key if the zone is signed.
+func (s *Server) TrustAnchors() []string {
+	return s.anchors
+}
+
+// Queries returns the number of queries the server has answered.
+func (s *Server) Queries() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.queries
+}
+
+// Close stops the server.
+func (s *Server) Close() {
+	_ = s.udpServer.Shutdown()
+	_ = s.tcpServer.Shutdown()
+}
+
+func (s *Server) start() error {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		return errors.Wrap(err, "failed to listen for UDP")
+	}
+	listener, err := net.Listen("tcp", pc.LocalAddr().String())
+	if err != nil {
+		pc.Close()
+		return errors.Wrap(err, "failed to listen for TCP")
+	}
+
+	s.address = pc.LocalAddr().String()
+	s.udpServer = &dns.Server{PacketConn: pc, Handler: s}
+	s.tcpServer = &dns.Server{Listener: listener, Handler: s}
+	started := make(chan struct{}, 2)
+	s.udpServer.NotifyStartedFunc = func() { started <- struct{}{} }
+	s.tcpServer.NotifyStartedFunc = func() { started <- struct{}{} }
+	go func() {
+		_ = s.udpServer.ActivateAndServe()
+	}()
+	go func() {
+		_ = s.tcpServer.ActivateAndServe()
+	}()
+	<-started
+	<-started
+
+	return nil
+}
+
+// add adds a record to a set of records.
+func (s *Server) add(set map[string]map[uint16][]dns.RR, rr dns.RR) {
+	name := dns.CanonicalName(rr.Header().Name)
+	rrtype := rr.Header().Rrtype
+	if sig, isSig := rr.(*dns.RRSIG); isSig {
+		rrtype = sig.TypeCovered
+	}
+	if _, exists := set[name]; !exists {
+		set[name] = make(map[uint16][]dns.RR)
+	}
+	set[name][rrtype] = append(set[name][rrtype], rr)
+}
+
+// sign signs every RRset with the key of the zone that holds it.
+func (s *Server) sign() error {
+	if _, exists := s.apexes["."]; !exists {
+		s.apexes["."] = nil
+	}
+
+	// Create a key for each zone.
+	keys := make(map[string]*dns.DNSKEY)
+	privs := make(map[string]crypto.Signer)
+	for apex := range s.apexes {
+		key := &dns.DNSKEY{
+			Hdr:       dns.RR_Header{Name: apex, Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: 3600},
+			Flags:     257,
+			Protocol:  3,
+			Algorithm: dns.ECDSAP256SHA256,
+		}
+		priv, err := key.Generate(256)
+		if err != nil {
+			return errors.Wrapf(err, "failed to generate key for %s", apex)
+		}
+		keys[apex] = key
+		privs[apex] = priv.(crypto.Signer)
+		s.add(s.records, key)
+		if apex == "." {
+			s.anchors = []string{key.ToDS(dns.SHA256).String()}
+		} else {
+			s.add(s.records, key.ToDS(dns.SHA256))
+		}
+	}
+
+	inception := uint32(time.Now().Add(-time.Hour).Unix())
+	expiration := uint32(time.Now().Add(24 * time.Hour).Unix())
+	signRRset := func(zone string, rrset []dns.RR) (*dns.RRSIG, error) {
+		sig := &dns.RRSIG{
+			Hdr:        dns.RR_Header{Name: rrset[0].Header().Name, Rrtype: dns.TypeRRSIG, Class: dns.ClassINET, Ttl: rrset[0].Header().Ttl},
+			Algorithm:  keys[zone].Algorithm,
+			Inception:  inception,
+			Expiration: expiration,
+			KeyTag:     keys[zone].KeyTag(),
+			SignerName: zone,
+		}
+		if err := sig.Sign(privs[zone], rrset); err != nil {
+			return nil, errors.Wrapf(err, "failed to sign %s %s", rrset[0].Header().Name, dns.TypeToString[rrset[0].Header().Rrtype])
+		}
+		return sig, nil
+	}
+	for name, rrsets := range s.records {
+		for rrtype, rrset := range rrsets {
+			zone := s.zone(name)
+			if rrtype == dns.TypeDS {
+				// DS records are held by the parent zone.
+				zone = s.zone(parent(name))
+			}
+			sig, err := signRRset(zone, rrset)
+			if err != nil {
+				return err
+			}
+			s.add(s.sigs, sig)
+		}
+	}
+
+	for apex := range s.apexes {
+		for _, nsec := range s.nsecChain(apex) {
+			sig, err := signRRset(apex, []dns.RR{nsec})
+			if err != nil {
+				return err
+			}
+			s.denials[apex] = append(s.denials[apex], nsec, sig)
+		}
+	}
+
+	return nil
+}
+
+// nsecChain creates the NSEC records for a zone, in canonical order.
+func (s *Server) nsecChain(apex string) []*dns.NSEC {
+	types := make(map[string][]uint16)
+	for name, rrsets := range s.records {
+		for rrtype := range rrsets {
+			switch {
+			case s.zone(name) == apex && !(name == apex && rrtype == dns.TypeDS):
+				types[name] = append(types[name], rrtype)
+			case rrtype == dns.TypeDS && name != apex && s.zone(parent(name)) == apex:
+				// A delegation to a child zone.
+				types[name] = append(types[name], dns.TypeNS, dns.TypeDS)
+			}
+		}
+	}
+
+	names := make([]string, 0, len(types))
+	for name := range types {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i int, j int) bool { return canonicalLess(names[i], names[j]) })
+
+	nsecs := make([]*dns.NSEC, len(names))
+	for i, name := range names {
+		bitmap := append(types[name], dns.TypeRRSIG, dns.TypeNSEC)
+		sort.Slice(bitmap, func(i int, j int) bool { return bitmap[i] < bitmap[j] })
+		ttl := uint32(300)
+		if soa := s.apexes[apex]; soa != nil {
+			ttl = soa.Minttl
+		}
+		nsecs[i] = &dns.NSEC{
+			Hdr:        dns.RR_Header{Name: name, Rrtype: dns.TypeNSEC, Class: dns.ClassINET, Ttl: ttl},
+			NextDomain: names[(i+1)%len(names)],
+			TypeBitMap: bitmap,
+		}
+	}
+	return nsecs
+}
+
+// denial returns the signed NSEC record that proves a negative answer for
+// the name in the given zone.
+func (s *Server) denial(zone string, name string) []dns.RR {
+	denials := s.denials[zone]
+	for i := 0; i < len(denials); i += 2 {
+		nsec := denials[i].(*dns.NSEC)
+		owner := nsec.Hdr.Name
+		next := nsec.NextDomain
+		switch {
+		case owner == name:
+			return denials[i : i+2]
+		case canonicalLess(owner, name) && (canonicalLess(name, next) || !canonicalLess(owner, next)):
+			return denials[i : i+2]
+		}
+	}
+	return nil
+}
+
+// canonicalLess returns true if the first name is before the second in
+// canonical DNS order.
+func canonicalLess(a string, b string) bool {
+	aLabels := dns.SplitDomainName(a)
+	bLabels := dns.SplitDomainName(b)
+	for i := 1; i <= len(aLabels) && i <= len(bLabels); i++ {
+		if aLabels[len(aLabels)-i] != bLabels[len(bLabels)-i] {
+			return aLabels[len(aLabels)-i] < bLabels[len(bLabels)-i]
+		}
+	}
+	return len(aLabels) < len(bLabels)
+}
+
+// zone returns the apex of the closest zone that holds the name.
+func (s *Server) zone(name string) string {
+	for {
+		if _, exists := s.apexes[name]; exists {
+			return name
+		}
+		if name == "." {
+			return ""
+		}
+		name = parent(name)
+	}
+}
+
+// parent returns the parent of a name.
+func parent(name string) string {
+	labels := dns.SplitDomainName(name)
+	if len(labels) <= 1 {
+		return "."
+	}
+	return dns.Fqdn(strings.Join(labels[1:], "."))
+}
+
+// exists returns true if the name, or any name below it, has records.
+func (s *Server) exists(name string) bool {
+	for recordName := range s.records {
+		if dns.IsSubDomain(name, recordName) {
+			return true
+		}
+	}
+	return false
+}
+
+// ServeDNS answers a query.
+func (s *Server) ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
+	s.mutex.Lock()
+	s.queries++
+	s.mutex.Unlock()
+
+	m := new(dns.Msg)
+	m.SetReply(req)
+	m.Authoritative = true
+	m.RecursionAvailable = true
+	dnssec := false
+	if opt := req.IsEdns0(); opt != nil {
+		dnssec = opt.Do()
+		m.SetEdns0(opt.UDPSize(), dnssec)
+	}
+
+	if len(req.Question) != 1 {
+		m.Rcode = dns.RcodeFormatError
+		_ = w.WriteMsg(m)
+		return
+	}
+	name := dns.CanonicalName(req.Question[0].Name)
+	qtype := req.Question[0].Qtype
+
+	if rrset, exists := s.records[name][qtype]; exists {
+		m.Answer = append(m.Answer, rrset...)
+		if dnssec {
+			m.Answer = append(m.Answer, s.sigs[name][qtype]...)
+		}
+	} else {
+		if !s.exists(name) {
+			m.Rcode = dns.RcodeNameError
+		}
+		zone := s.zone(name)
+		if qtype == dns.TypeDS && zone == name && name != "." {
+			// DS records are held by the parent zone.
+			zone = s.zone(parent(name))
+		}
+		if zone != "" && s.apexes[zone] != nil {
+			m.Ns = append(m.Ns, s.apexes[zone])
+			if dnssec {
+				m.Ns = append(m.Ns, s.sigs[zone][dns.TypeSOA]...)
+			}
+		}
+		if dnssec && zone != "" {
+			m.Ns = append(m.Ns, s.denial(zone, name)...)
+		}
+	}
+
+	if w.LocalAddr().Network() == "udp" {
+		m.Truncate(udpSize(req))
+	}
+	_ = w.WriteMsg(m)
+}
+
+// udpSize returns the maximum size of a UDP response to the request.
+func udpSize(req *dns.Msg) int {
+	if opt := req.IsEdns0(); opt != nil {
+		return int(opt.UDPSize())
+	}
+	return dns.MinMsgSize
+}
@@ -0,0 +1,185 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dnsstandin_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+	"github.com/wealdtech/edcd/util/dnsstandin"
+)
+
+const zone = `
+$ORIGIN example.com.
+@        3600 IN SOA ns.example.com. hostmaster.example.com. 1 7200 3600 1209600 300
+@        300  IN TXT "a=0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5"
+_ens.sub 300  IN TXT "a=0x3325a78425F17a7E487Eb5666b2bFd93aBb06c70"
+`
+
+func exchange(t *testing.T, address string, name string, qtype uint16, dnssec bool) *dns.Msg {
+	m := new(dns.Msg)
+	m.SetQuestion(name, qtype)
+	if dnssec {
+		m.SetEdns0(4096, true)
+	}
+	client := &dns.Client{Net: "tcp", Timeout: 5 * time.Second}
+	r, _, err := client.Exchange(m, address)
+	require.NoError(t, err)
+	return r
+}
+
+func TestServer(t *testing.T) {
+	_, err := dnsstandin.New(strings.NewReader("invalid"))
+	require.Error(t, err)
+	_, err = dnsstandin.NewFromFile("missing.db")
+	require.EqualError(t, err, "failed to open zone: open missing.db: no such file or directory")
+
+	s, err := dnsstandin.New(strings.NewReader(zone))
+	require.NoError(t, err)
+	defer s.Close()
+	require.Empty(t, s.TrustAnchors())
+
+	tests := []struct {
+		name    string
+		qname   string
+		qtype   uint16
+		rcode   int
+		answers int
+		soa     bool
+	}{
+		{
+			name:    "Answer",
+			qname:   "example.com.",
+			qtype:   dns.TypeTXT,
+			answers: 1,
+		},
+		{
+			name:    "AnswerCase",
+			qname:   "_ENS.Sub.Example.COM.",
+			qtype:   dns.TypeTXT,
+			answers: 1,
+		},
+		{
+			name:  "NoData",
+			qname: "example.com.",
+			qtype: dns.TypeA,
+			soa:   true,
+		},
+		{
+			name:  "EmptyNonTerminal",
+			qname: "sub.example.com.",
+			qtype: dns.TypeTXT,
+			soa:   true,
+		},
+		{
+			name:  "NXDomain",
+			qname: "missing.example.com.",
+			qtype: dns.TypeTXT,
+			rcode: dns.RcodeNameError,
+			soa:   true,
+		},
+		{
+			name:  "OutOfZone",
+			qname: "example.org.",
+			qtype: dns.TypeTXT,
+			rcode: dns.RcodeNameError,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := exchange(t, s.Address(), test.qname, test.qtype, false)
+			require.Equal(t, test.rcode, r.Rcode)
+			require.Len(t, r.Answer, test.answers)
+			if test.soa {
+				require.Len(t, r.Ns, 1)
+				require.Equal(t, dns.TypeSOA, r.Ns[0].Header().Rrtype)
+			} else {
+				require.Empty(t, r.Ns)
+			}
+		})
+	}
+	require.Equal(t, len(tests), s.Queries())
+}
+
+func TestServerOrigin(t *testing.T) {
+	_, err := dnsstandin.New(strings.NewReader(""), dnsstandin.WithOrigin(""))
+	require.EqualError(t, err, "problem with parameters: no origin specified")
+
+	s, err := dnsstandin.New(strings.NewReader(`test 300 IN TXT "relative"`), dnsstandin.WithOrigin("example.com"))
+	require.NoError(t, err)
+	defer s.Close()
+
+	r := exchange(t, s.Address(), "test.example.com.", dns.TypeTXT, false)
+	require.Equal(t, dns.RcodeSuccess, r.Rcode)
+	require.Len(t, r.Answer, 1)
+}
+
+func TestServerDNSSEC(t *testing.T) {
+	s, err := dnsstandin.New(strings.NewReader(zone), dnsstandin.WithDNSSEC())
+	require.NoError(t, err)
+	defer s.Close()
+	require.Len(t, s.TrustAnchors(), 1)
+
+	rr, err := dns.NewRR(s.TrustAnchors()[0])
+	require.NoError(t, err)
+	anchor := rr.(*dns.DS)
+
+	// Signatures are only returned if requested.
+	r := exchange(t, s.Address(), "example.com.", dns.TypeTXT, false)
+	require.Len(t, r.Answer, 1)
+
+	// Follow the chain of trust from the root to the TXT record.
+	rootKey := verifiedKey(t, s.Address(), ".", anchor)
+	r = exchange(t, s.Address(), "example.com.", dns.TypeDS, true)
+	require.Len(t, r.Answer, 2)
+	require.NoError(t, r.Answer[1].(*dns.RRSIG).Verify(rootKey, r.Answer[:1]))
+	zoneKey := verifiedKey(t, s.Address(), "example.com.", r.Answer[0].(*dns.DS))
+	r = exchange(t, s.Address(), "example.com.", dns.TypeTXT, true)
+	require.Len(t, r.Answer, 2)
+	require.NoError(t, r.Answer[1].(*dns.RRSIG).Verify(zoneKey, r.Answer[:1]))
+
+	// Negative answers carry a signed SOA and a signed NSEC that covers the
+	// missing name.
+	r = exchange(t, s.Address(), "missing.example.com.", dns.TypeTXT, true)
+	require.Equal(t, dns.RcodeNameError, r.Rcode)
+	require.Len(t, r.Ns, 4)
+	require.NoError(t, r.Ns[1].(*dns.RRSIG).Verify(zoneKey, r.Ns[:1]))
+	nsec := r.Ns[2].(*dns.NSEC)
+	require.Equal(t, "example.com.", nsec.Hdr.Name)
+	require.Equal(t, "_ens.sub.example.com.", nsec.NextDomain)
+	require.NoError(t, r.Ns[3].(*dns.RRSIG).Verify(zoneKey, r.Ns[2:3]))
+
+	// The NSEC for a name that exists lists its types.
+	r = exchange(t, s.Address(), "_ens.sub.example.com.", dns.TypeA, true)
+	require.Equal(t, dns.RcodeSuccess, r.Rcode)
+	require.Len(t, r.Ns, 4)
+	nsec = r.Ns[2].(*dns.NSEC)
+	require.Equal(t, "_ens.sub.example.com.", nsec.Hdr.Name)
+	require.Equal(t, "example.com.", nsec.NextDomain)
+	require.Equal(t, []uint16{dns.TypeTXT, dns.TypeRRSIG, dns.TypeNSEC}, nsec.TypeBitMap)
+}
+
+// verifiedKey obtains the key for a zone, checking it against its DS record.
+func verifiedKey(t *testing.T, address string, zone string, ds *dns.DS) *dns.DNSKEY {
+	r := exchange(t, address, zone, dns.TypeDNSKEY, true)
+	require.Len(t, r.Answer, 2)
+	key := r.Answer[0].(*dns.DNSKEY)
+	require.True(t, strings.EqualFold(ds.Digest, key.ToDS(ds.DigestType).Digest))
+	require.NoError(t, r.Answer[1].(*dns.RRSIG).Verify(key, r.Answer[:1]))
+	return key
+}
@@ -0,0 +1,233 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package eip712 provides EIP-712 typed data for domain claims.
+package eip712
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Domain contains the fields of the EIP-712 domain separator for claims.
+type Domain struct {
+	Name              string
+	Version           string
+	ChainID           *big.Int
+	VerifyingContract common.Address
+}
+
+// ClaimType is the primary type of claim typed data.
+const ClaimType = "Claim"
+
+var (
+	domainTypeHash = crypto.Keccak256([]byte("EIP712Domain(string name,string version,uint256 chainId,address verifyingContract)"))
+	claimTypeHash  = crypto.Keccak256([]byte("Claim(bytes32 parentNode,string label,address owner)"))
+)
+
+// Separator returns the domain separator.
+func (d *Domain) Separator() [32]byte {
+	var separator [32]byte
+	copy(separator[:], crypto.Keccak256(
+		domainTypeHash,
+		crypto.Keccak256([]byte(d.Name)),
+		crypto.Keccak256([]byte(d.Version)),
+		math.U256Bytes(new(big.Int).Set(d.ChainID)),
+		common.LeftPadBytes(d.VerifyingContract.Bytes(), 32),
+	))
+	return separator
+}
+
+// ClaimHash returns the hash that is signed for a claim of the label under
+// the parent node by the owner.
+func ClaimHash(domain *Domain, parentNode [32]byte, label string, owner common.Address) [32]byte {
+	separator := domain.Separator()
+	structHash := crypto.Keccak256(
+		claimTypeHash,
+		parentNode[:],
+		crypto.Keccak256([]byte(label)),
+		common.LeftPadBytes(owner.Bytes(), 32),
+	)
+
+	var hash [32]byte
+	copy(hash[:], crypto.Keccak256([]byte{0x19, 0x01}, separator[:], structHash))
+	return hash
+}
+
+// Type is a member of a type in typed data.
+type Type struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// TypedDataDomain is the domain of typed data.
+type TypedDataDomain struct {
+	Name              string                `json:"name"`
+	Version           string                `json:"version"`
+	ChainID           *math.HexOrDecimal256 `json:"chainId"`
+	VerifyingContract string                `json:"verifyingContract"`
+}
+
+// TypedData is typed data in the JSON format used by external signers.
+type TypedData struct {
+	Types       map[string][]Type      `json:"types"`
+	PrimaryType string                 `json:"primaryType"`
+	Domain      TypedDataDomain        `json:"domain"`
+	Message     map[string]interface{} `json:"message"`
+}
+
+// ClaimTypedData returns the typed data for a claim of the label under
+// the parent node by the owner.
+func ClaimTypedData(domain *Domain, parentNode [32]byte, label string, owner common.Address) *TypedData {
+	return &TypedData{
+		Types: map[string][]Type{
+			"EIP712Domain": {
+				{Name: "name", Type: "string"},
+				{Name: "version", Type: "string"},
+				{Name: "chainId", Type: "uint256"},
+				{Name: "verifyingContract", Type: "address"},
+			},
+			ClaimType: {
+				{Name: "parentNode", Type: "bytes32"},
+				{Name: "label", Type: "string"},
+				{Name: "owner", Type: "address"},
+			},
+		},
+		PrimaryType: ClaimType,
+		Domain: TypedDataDomain{
+			Name:              domain.Name,
+			Version:           domain.Version,
+			ChainID:           (*math.HexOrDecimal256)(new(big.Int).Set(domain.ChainID)),
+			VerifyingContract: domain.VerifyingContract.Hex(),
+		},
+		Message: map[string]interface{}{
+			"parentNode": fmt.Sprintf("%#x", parentNode),
+			"label":      label,
+			"owner":      owner.Hex(),
+		},
+	}
+}
+
+// ParseDomain parses an EIP-712 domain from configuration.
+func ParseDomain(input interface{}) (*Domain, error) {
+	config, isMap := input.(map[string]interface{})
+	if !isMap {
+		return nil, errors.New("invalid configuration")
+	}
+
+	domain := &Domain{}
+	var exists bool
+	domain.Name, exists = config["name"].(string)
+	if !exists || domain.Name == "" {
+		return nil, errors.New("name missing")
+	}
+	domain.Version, exists = config["version"].(string)
+	if !exists || domain.Version == "" {
+		return nil, errors.New("version missing")
+	}
+
+	if _, exists := config["chain-id"]; !exists {
+		return nil, errors.New("chain-id missing")
+	}
+	var err error
+	domain.ChainID, err = parseChainID(config["chain-id"])
+	if err != nil {
+		return nil, err
+	}
+
+	verifyingContract, exists := config["verifying-contract"].(string)
+	if !exists {
+		return nil, errors.New("verifying-contract missing")
+	}
+	if !common.IsHexAddress(verifyingContract) {
+		return nil, errors.New("verifying-contract invalid")
+	}
+	domain.VerifyingContract = common.HexToAddress(verifyingContract)
+
+	return domain, nil
+}
+
+// parseChainID parses a chain ID from configuration.
+func parseChainID(input interface{}) (*big.Int, error) {
+	var chainID *big.Int
+	switch v := input.(type) {
+	case int:
+		chainID = big.NewInt(int64(v))
+	case int64:
+		chainID = big.NewInt(v)
+	case uint64:
+		chainID = new(big.Int).SetUint64(v)
+	case float64:
+		if v == float64(int64(v)) {
+			chainID = big.NewInt(int64(v))
+		}
+	case string:
+		if strings.HasPrefix(v, "0x") {
+			chainID, _ = new(big.Int).SetString(v[2:], 16)
+		} else if id, err := strconv.ParseUint(v, 10, 64); err == nil {
+			chainID = new(big.Int).SetUint64(id)
+		}
+	}
+	if chainID == nil || chainID.Sign() <= 0 {
+		return nil, errors.New("chain-id invalid")
+	}
+	return chainID, nil
+}
+
+// ClaimHash returns the hash that is signed for claim typed data.
+func (t *TypedData) ClaimHash() ([32]byte, error) {
+	if t.PrimaryType != ClaimType {
+		return [32]byte{}, fmt.Errorf("unsupported primary type %s", t.PrimaryType)
+	}
+	if t.Domain.ChainID == nil {
+		return [32]byte{}, errors.New("chain ID missing")
+	}
+	if !common.IsHexAddress(t.Domain.VerifyingContract) {
+		return [32]byte{}, errors.New("verifying contract invalid")
+	}
+	domain := &Domain{
+		Name:              t.Domain.Name,
+		Version:           t.Domain.Version,
+		ChainID:           (*big.Int)(t.Domain.ChainID),
+		VerifyingContract: common.HexToAddress(t.Domain.VerifyingContract),
+	}
+
+	parentNodeStr, isString := t.Message["parentNode"].(string)
+	if !isString {
+		return [32]byte{}, errors.New("parent node missing")
+	}
+	parentNodeBytes, err := hexutil.Decode(parentNodeStr)
+	if err != nil || len(parentNodeBytes) != 32 {
+		return [32]byte{}, errors.New("parent node invalid")
+	}
+	var parentNode [32]byte
+	copy(parentNode[:], parentNodeBytes)
+	label, isString := t.Message["label"].(string)
+	if !isString {
+		return [32]byte{}, errors.New("label missing")
+	}
+	owner, isString := t.Message["owner"].(string)
+	if !isString || !common.IsHexAddress(owner) {
+		return [32]byte{}, errors.New("owner invalid")
+	}
+
+	return ClaimHash(domain, parentNode, label, common.HexToAddress(owner)), nil
+}
@@ -0,0 +1,186 @@
+// Copyright © 2021 Weald Technology Trading.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eip712_test
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+	"github.com/wealdtech/edcd/util/eip712"
+)
+
+func TestClaimHash(t *testing.T) {
+	domain := &eip712.Domain{
+		Name:              "ENS DNS claim",
+		Version:           "1",
+		ChainID:           big.NewInt(5),
+		VerifyingContract: common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314"),
+	}
+	parentNode := common.HexToHash("0x87e957ac2ae4a1e2e5d5dc2deb3b6a03e2aa62d5e3cc81da5bbd1b7b83823169")
+	owner := common.HexToAddress("0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5")
+
+	// Values generated by an independent EIP-712 implementation.
+	require.Equal(t, common.HexToHash("0x972cd833dfc087fbc500382ef70779f786edced2c77bb136f675fa86593aeec1"), common.Hash(domain.Separator()))
+	require.Equal(t, common.HexToHash("0x770b535835ba4a96d180aa959b51b876d904d43aa8945883181fdd02f0735a5c"), common.Hash(eip712.ClaimHash(domain, parentNode, "test", owner)))
+
+	// Changing any field of the claim changes the hash.
+	require.NotEqual(t, eip712.ClaimHash(domain, parentNode, "test", owner), eip712.ClaimHash(domain, parentNode, "test2", owner))
+	otherDomain := *domain
+	otherDomain.ChainID = big.NewInt(1)
+	require.NotEqual(t, eip712.ClaimHash(domain, parentNode, "test", owner), eip712.ClaimHash(&otherDomain, parentNode, "test", owner))
+}
+
+func TestClaimTypedData(t *testing.T) {
+	domain := &eip712.Domain{
+		Name:              "ENS DNS claim",
+		Version:           "1",
+		ChainID:           big.NewInt(5),
+		VerifyingContract: common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314"),
+	}
+	parentNode := common.HexToHash("0x87e957ac2ae4a1e2e5d5dc2deb3b6a03e2aa62d5e3cc81da5bbd1b7b83823169")
+	owner := common.HexToAddress("0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5")
+
+	typedData := eip712.ClaimTypedData(domain, parentNode, "test", owner)
+	data, err := json.Marshal(typedData)
+	require.NoError(t, err)
+	require.Equal(t, `{"types":{"Claim":[{"name":"parentNode","type":"bytes32"},{"name":"label","type":"string"},{"name":"owner","type":"address"}],"EIP712Domain":[{"name":"name","type":"string"},{"name":"version","type":"string"},{"name":"chainId","type":"uint256"},{"name":"verifyingContract","type":"address"}]},"primaryType":"Claim","domain":{"name":"ENS DNS claim","version":"1","chainId":"0x5","verifyingContract":"0x0102030405060708090a0B0c0d0e0f1011121314"},"message":{"label":"test","owner":"0x388Ea662EF2c223eC0B047D41Bf3c0f362142ad5","parentNode":"0x87e957ac2ae4a1e2e5d5dc2deb3b6a03e2aa62d5e3cc81da5bbd1b7b83823169"}}`, string(data))
+
+	// Ensure that the typed data survives a round trip and hashes as the claim.
+	decoded := &eip712.TypedData{}
+	require.NoError(t, json.Unmarshal(data, decoded))
+	hash, err := decoded.ClaimHash()
+	require.NoError(t, err)
+	require.Equal(t, eip712.ClaimHash(domain, parentNode, "test", owner), hash)
+
+	decoded.PrimaryType = "Mail"
+	_, err = decoded.ClaimHash()
+	require.EqualError(t, err, "unsupported primary type Mail")
+}
+
+func TestParseDomain(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    interface{}
+		expected *eip712.Domain
+		err      string
+	}{
+		{
+			name:  "Invalid",
+			input: "invalid",
+			err:   "invalid configuration",
+		},
+		{
+			name:  "NameMissing",
+			input: map[string]interface{}{},
+			err:   "name missing",
+		},
+		{
+			name: "VersionMissing",
+			input: map[string]interface{}{
+				"name": "ENS DNS claim",
+			},
+			err: "version missing",
+		},
+		{
+			name: "ChainIDMissing",
+			input: map[string]interface{}{
+				"name":    "ENS DNS claim",
+				"version": "1",
+			},
+			err: "chain-id missing",
+		},
+		{
+			name: "ChainIDInvalid",
+			input: map[string]interface{}{
+				"name":     "ENS DNS claim",
+				"version":  "1",
+				"chain-id": "invalid",
+			},
+			err: "chain-id invalid",
+		},
+		{
+			name: "ChainIDZero",
+			input: map[string]interface{}{
+				"name":     "ENS DNS claim",
+				"version":  "1",
+				"chain-id": 0,
+			},
+			err: "chain-id invalid",
+		},
+		{
+			name: "VerifyingContractMissing",
+			input: map[string]interface{}{
+				"name":     "ENS DNS claim",
+				"version":  "1",
+				"chain-id": 5,
+			},
+			err: "verifying-contract missing",
+		},
+		{
+			name: "VerifyingContractInvalid",
+			input: map[string]interface{}{
+				"name":               "ENS DNS claim",
+				"version":            "1",
+				"chain-id":           5,
+				"verifying-contract": "0x0102",
+			},
+			err: "verifying-contract invalid",
+		},
+		{
+			name: "Good",
+			input: map[string]interface{}{
+				"name":               "ENS DNS claim",
+				"version":            "1",
+				"chain-id":           5,
+				"verifying-contract": "0x0102030405060708090a0b0c0d0e0f1011121314",
+			},
+			expected: &eip712.Domain{
+				Name:              "ENS DNS claim",
+				Version:           "1",
+				ChainID:           big.NewInt(5),
+				VerifyingContract: common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314"),
+			},
+		},
+		{
+			name: "GoodHexChainID",
+			input: map[string]interface{}{
+				"name":               "ENS DNS claim",
+				"version":            "1",
+				"chain-id":           "0x5",
+				"verifying-contract": "0x0102030405060708090a0b0c0d0e0f1011121314",
+			},
+			expected: &eip712.Domain{
+				Name:              "ENS DNS claim",
+				Version:           "1",
+				ChainID:           big.NewInt(5),
+				VerifyingContract: common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314"),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := eip712.ParseDomain(test.input)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, test.expected, res)
+			}
+		})
+	}
+}